@@ -0,0 +1,106 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/identity"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/remotesigner"
+)
+
+// aegisq-signer holds a validator keystore and signs on behalf of
+// aegisqd, so the secret key never lives in the node process.
+//
+// Usage: aegisq-signer -chain-id <id> [-state <file>] <keystore-file> <unix:/path.sock | tcp:host:port>
+//
+// The keystore passphrase comes from AEGISQ_PASSPHRASE; a TCP signer
+// also needs the shared secret in AEGISQ_SIGNER_SECRET.
+func main() {
+
+	fs := flag.NewFlagSet("aegisq-signer", flag.ExitOnError)
+	chainID := fs.String("chain-id", "", "the only chain blocks and votes are signed for")
+	statePath := fs.String("state", "", "record of the last block and vote signed (default <keystore-file>.state.json)")
+	fs.Parse(os.Args[1:])
+
+	if fs.NArg() != 2 || *chainID == "" {
+		fmt.Println("usage: aegisq-signer -chain-id <id> [-state <file>] <keystore-file> <unix:/path.sock | tcp:host:port>")
+		os.Exit(2)
+	}
+
+	keystore, address := fs.Arg(0), fs.Arg(1)
+
+	if *statePath == "" {
+		*statePath = keystore + ".state.json"
+	}
+
+	passphrase := os.Getenv("AEGISQ_PASSPHRASE")
+	if passphrase == "" {
+		log.Fatal("AEGISQ_PASSPHRASE must be set to unlock the keystore")
+	}
+
+	secret := []byte(os.Getenv(remotesigner.SecretEnvVar))
+
+	network, addr, err := remotesigner.ParseAddress(address)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if network != "unix" && len(secret) < remotesigner.MinSecretSize {
+		log.Fatalf("a %s signer needs %s set to a shared secret of at least %d bytes", network, remotesigner.SecretEnvVar, remotesigner.MinSecretSize)
+	}
+
+	node, err := identity.LoadKeystore(keystore, []byte(passphrase))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	state, err := remotesigner.LoadSignState(*statePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Clear a stale socket left by a previous run, but nothing else.
+	if fi, err := os.Lstat(addr); err == nil && network == "unix" && fi.Mode()&os.ModeSocket != 0 {
+		os.Remove(addr)
+	}
+
+	l, err := net.Listen(network, addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if network == "unix" {
+		os.Chmod(addr, 0600)
+	}
+
+	srv := remotesigner.NewServer(node, remotesigner.ServerConfig{
+		ChainID: *chainID,
+		State:   state,
+		Secret:  secret,
+	})
+
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+		<-sig
+		srv.Close()
+	}()
+
+	fmt.Printf("Signing for %s (%s) on chain %s at %s\n", node.NodeID, node.Algorithm(), *chainID, address)
+
+	for _, t := range []crypto.MessageType{crypto.MessageBlock, crypto.MessageVote} {
+		if last, ok := state.Last(t); ok {
+			fmt.Printf("Last %s signed: height %d view %d\n", t, last.Height, last.View)
+		}
+	}
+
+	if err := srv.Serve(l); err != nil {
+		log.Fatal(err)
+	}
+}
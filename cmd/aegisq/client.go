@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// client talks to the aegisqd HTTP API.
+type client struct {
+	base string
+	http *http.Client
+}
+
+func newClient(node string) *client {
+
+	if !strings.Contains(node, "://") {
+		node = "http://" + node
+	}
+
+	return &client{
+		base: strings.TrimRight(node, "/"),
+		http: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// get fetches path and decodes the response into out, returning the
+// raw body for JSON output.
+func (c *client) get(path string, out interface{}) ([]byte, error) {
+
+	resp, err := c.http.Get(c.base + path)
+	if err != nil {
+		return nil, err
+	}
+
+	return decode(resp, out)
+}
+
+// post sends body as JSON to path.
+func (c *client) post(path string, body interface{}, out interface{}) ([]byte, error) {
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.http.Post(c.base+path, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	return decode(resp, out)
+}
+
+func decode(resp *http.Response, out interface{}) ([]byte, error) {
+
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// The node reports errors as plain text
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("node: %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return nil, fmt.Errorf("node: unexpected response: %w", err)
+		}
+	}
+
+	return data, nil
+}
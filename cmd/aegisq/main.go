@@ -0,0 +1,645 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/block"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/chain"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/identity"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/transaction"
+)
+
+// aegisq queries and submits to a running aegisqd over its HTTP API,
+// so it works while the node holds the database lock.
+const usage = `usage: aegisq [-node URL] [-o text|json] <command> [args]
+
+Commands:
+  status                      node and chain status
+  block <height>              block with its transactions
+  blocks                      latest 20 blocks
+  tx <height> <index>         transaction by position
+  txhash <data-hash>          transaction by data hash
+  submit [flags] <file>       sign the hash of file and submit it
+  proof <data-hash>           Merkle inclusion proof, checked locally
+  validators                  validator set
+  consensus                   consensus state of the tip
+
+The node defaults to AEGISQ_NODE or http://localhost:8080.
+`
+
+// output selects how results are printed.
+type output struct {
+	json bool
+}
+
+func main() {
+
+	fs := flag.NewFlagSet("aegisq", flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+
+	node := fs.String("node", envOr("AEGISQ_NODE", "http://localhost:8080"), "node API address")
+	format := fs.String("o", "text", "output format: text or json")
+
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		os.Exit(2)
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "unknown output format %q\n", *format)
+		os.Exit(2)
+	}
+
+	c := newClient(*node)
+	out := output{json: *format == "json"}
+
+	if err := run(c, out, fs.Arg(0), fs.Args()[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "aegisq:", err)
+		os.Exit(1)
+	}
+}
+
+func run(c *client, out output, command string, args []string) error {
+
+	switch command {
+
+	case "status":
+		return runStatus(c, out, args)
+
+	case "block":
+		return runBlock(c, out, args)
+
+	case "blocks":
+		return runBlocks(c, out, args)
+
+	case "tx":
+		return runTx(c, out, args)
+
+	case "txhash":
+		return runTxHash(c, out, args)
+
+	case "submit":
+		return runSubmit(c, out, args)
+
+	case "proof":
+		return runProof(c, out, args)
+
+	case "validators":
+		return runValidators(c, out, args)
+
+	case "consensus":
+		return runConsensus(c, out, args)
+
+	default:
+		return fmt.Errorf("unknown command %q\n\n%s", command, usage)
+	}
+}
+
+// =========================
+// RESPONSES
+// =========================
+
+type statusResponse struct {
+	Status        string `json:"status"`
+	Height        uint64 `json:"height"`
+	ChainID       string `json:"chain_id"`
+	HashAlgorithm string `json:"hash_algorithm"`
+	GenesisHash   string `json:"genesis_hash"`
+	PendingTxs    int    `json:"pending_txs"`
+
+	SignatureCache struct {
+		Hits    uint64  `json:"hits"`
+		Misses  uint64  `json:"misses"`
+		HitRate float64 `json:"hit_rate"`
+	} `json:"signature_cache"`
+}
+
+type quorum struct {
+	Required int `json:"required"`
+	Received int `json:"received"`
+}
+
+type blockResponse struct {
+	Height       int                        `json:"height"`
+	Hash         string                     `json:"hash"`
+	View         int                        `json:"view"`
+	Leader       string                     `json:"leader"`
+	Transactions []*transaction.Transaction `json:"transactions"`
+
+	Consensus struct {
+		Quorum quorum `json:"quorum"`
+		Status string `json:"status"`
+	} `json:"consensus"`
+}
+
+type blockSummary struct {
+	Height int    `json:"height"`
+	Hash   string `json:"hash"`
+	Txs    int    `json:"txs"`
+}
+
+type txHashResponse struct {
+	BlockHeight int                      `json:"block_height"`
+	TxIndex     int                      `json:"tx_index"`
+	Transaction *transaction.Transaction `json:"transaction"`
+}
+
+type proofResponse struct {
+	DataHash      string `json:"data_hash"`
+	BlockHeight   int    `json:"block_height"`
+	BlockHash     string `json:"block_hash"`
+	TxIndex       int    `json:"tx_index"`
+	TxHash        string `json:"tx_hash"`
+	MerkleRoot    string `json:"merkle_root"`
+	HashAlgorithm string `json:"hash_algorithm"`
+
+	Proof []struct {
+		Hash string `json:"hash"`
+		Side string `json:"side"`
+	} `json:"proof"`
+}
+
+type validatorsResponse struct {
+	Height     uint64 `json:"height"`
+	NextLeader string `json:"next_leader"`
+
+	Validators []struct {
+		NodeID          string `json:"node_id"`
+		Algorithm       string `json:"algorithm"`
+		Power           int64  `json:"power"`
+		PublicKey       []byte `json:"public_key"`
+		PendingRotation bool   `json:"pending_rotation"`
+		Local           bool   `json:"local"`
+	} `json:"validators"`
+}
+
+type consensusResponse struct {
+	Height     uint64   `json:"height"`
+	View       int      `json:"view"`
+	Leader     string   `json:"leader"`
+	Quorum     quorum   `json:"quorum"`
+	Validators []string `json:"validators"`
+	Status     string   `json:"status"`
+}
+
+type submitResponse struct {
+	DataHash   string `json:"data_hash"`
+	Status     string `json:"status"`
+	PendingTxs int    `json:"pending_txs"`
+}
+
+// =========================
+// COMMANDS
+// =========================
+
+func runStatus(c *client, out output, args []string) error {
+
+	if len(args) != 0 {
+		return errors.New("usage: aegisq status")
+	}
+
+	var s statusResponse
+
+	raw, err := c.get("/status", &s)
+	if err != nil {
+		return err
+	}
+
+	if out.json {
+		return printJSON(raw)
+	}
+
+	fmt.Println("Status:     ", s.Status)
+	fmt.Println("Chain:      ", s.ChainID)
+	fmt.Println("Height:     ", s.Height)
+	fmt.Println("Pending txs:", s.PendingTxs)
+	fmt.Println("Hash:       ", s.HashAlgorithm)
+	fmt.Println("Genesis:    ", s.GenesisHash)
+	fmt.Printf("Sig cache:   %d hits, %d misses (%.1f%%)\n", s.SignatureCache.Hits, s.SignatureCache.Misses, 100*s.SignatureCache.HitRate)
+
+	return nil
+}
+
+func runBlock(c *client, out output, args []string) error {
+
+	if len(args) != 1 {
+		return errors.New("usage: aegisq block <height>")
+	}
+
+	height, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid height %q", args[0])
+	}
+
+	var b blockResponse
+
+	raw, err := c.get(fmt.Sprintf("/block/%d", height), &b)
+	if err != nil {
+		return err
+	}
+
+	if out.json {
+		return printJSON(raw)
+	}
+
+	fmt.Println("Height:      ", b.Height)
+	fmt.Println("Hash:        ", b.Hash)
+	fmt.Println("View:        ", b.View)
+	fmt.Println("Leader:      ", b.Leader)
+	fmt.Printf("Consensus:    %s (%d/%d votes)\n", b.Consensus.Status, b.Consensus.Quorum.Received, b.Consensus.Quorum.Required)
+	fmt.Println("Transactions:", len(b.Transactions))
+
+	if len(b.Transactions) == 0 {
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "\nINDEX\tSENDER\tALGORITHM\tDATA HASH")
+
+	for i, tx := range b.Transactions {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", i, tx.SenderID, tx.Algorithm, tx.DataHash)
+	}
+
+	return w.Flush()
+}
+
+func runBlocks(c *client, out output, args []string) error {
+
+	if len(args) != 0 {
+		return errors.New("usage: aegisq blocks")
+	}
+
+	var blocks []blockSummary
+
+	raw, err := c.get("/blocks", &blocks)
+	if err != nil {
+		return err
+	}
+
+	if out.json {
+		return printJSON(raw)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "HEIGHT\tTXS\tHASH")
+
+	for _, b := range blocks {
+		fmt.Fprintf(w, "%d\t%d\t%s\n", b.Height, b.Txs, b.Hash)
+	}
+
+	return w.Flush()
+}
+
+func runTx(c *client, out output, args []string) error {
+
+	if len(args) != 2 {
+		return errors.New("usage: aegisq tx <height> <index>")
+	}
+
+	height, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid height %q", args[0])
+	}
+
+	index, err := strconv.ParseUint(args[1], 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid index %q", args[1])
+	}
+
+	var tx transaction.Transaction
+
+	raw, err := c.get(fmt.Sprintf("/tx/%d/%d", height, index), &tx)
+	if err != nil {
+		return err
+	}
+
+	if out.json {
+		return printJSON(raw)
+	}
+
+	fmt.Println("Block height:", height)
+	fmt.Println("Index:       ", index)
+	printTx(&tx)
+
+	return nil
+}
+
+func runTxHash(c *client, out output, args []string) error {
+
+	if len(args) != 1 {
+		return errors.New("usage: aegisq txhash <data-hash>")
+	}
+
+	var r txHashResponse
+
+	raw, err := c.get("/txhash/"+args[0], &r)
+	if err != nil {
+		return err
+	}
+
+	if out.json {
+		return printJSON(raw)
+	}
+
+	fmt.Println("Block height:", r.BlockHeight)
+	fmt.Println("Index:       ", r.TxIndex)
+	printTx(r.Transaction)
+
+	return nil
+}
+
+// runSubmit signs a transaction over the chain-hash digest of a file
+// with a keystore and submits it to the node's pool.
+func runSubmit(c *client, out output, args []string) error {
+
+	fs := flag.NewFlagSet("submit", flag.ContinueOnError)
+	keystore := fs.String("keystore", "", "keystore of the sending identity (passphrase from AEGISQ_PASSPHRASE)")
+	metadata := fs.String("metadata", "", "transaction metadata")
+	dataHash := fs.String("data-hash", "", "submit this hex data hash instead of hashing a file")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *keystore == "" || (fs.NArg() == 1) == (*dataHash != "") {
+		return errors.New("usage: aegisq submit -keystore FILE [-metadata TEXT] <file | -data-hash HEX>")
+	}
+
+	passphrase := os.Getenv("AEGISQ_PASSPHRASE")
+	if passphrase == "" {
+		return errors.New("AEGISQ_PASSPHRASE must be set to unlock the keystore")
+	}
+
+	// Sign for the node's chain: its ID and hash
+	var s statusResponse
+	if _, err := c.get("/status", &s); err != nil {
+		return err
+	}
+
+	hasher, err := crypto.NewHasher(s.HashAlgorithm)
+	if err != nil {
+		return err
+	}
+
+	p := chain.Params{ID: s.ChainID, Hasher: hasher}
+
+	hash := *dataHash
+
+	if hash == "" {
+
+		data, err := os.ReadFile(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+
+		hash = hex.EncodeToString(hasher.Hash(data))
+
+		if *metadata == "" {
+			*metadata = fs.Arg(0)
+		}
+	}
+
+	node, err := identity.LoadKeystore(*keystore, []byte(passphrase))
+	if err != nil {
+		return err
+	}
+	defer node.Destroy()
+
+	tx := transaction.NewTransaction(node, hash, *metadata)
+
+	if err := tx.SignWith(node, p); err != nil {
+		return err
+	}
+
+	var r submitResponse
+
+	raw, err := c.post("/submit", tx, &r)
+	if err != nil {
+		return err
+	}
+
+	if out.json {
+		return printJSON(raw)
+	}
+
+	fmt.Println("Submitted:  ", r.DataHash)
+	fmt.Println("Sender:     ", tx.SenderID, "("+tx.Algorithm+")")
+	fmt.Println("Status:     ", r.Status)
+	fmt.Println("Pending txs:", r.PendingTxs)
+
+	return nil
+}
+
+// runProof fetches an inclusion proof and checks it locally: the
+// transaction hashes to the proven leaf, and the path reaches the
+// block's Merkle root.
+func runProof(c *client, out output, args []string) error {
+
+	if len(args) != 1 {
+		return errors.New("usage: aegisq proof <data-hash>")
+	}
+
+	var p proofResponse
+
+	raw, err := c.get("/proof/"+args[0], &p)
+	if err != nil {
+		return err
+	}
+
+	var r txHashResponse
+	if _, err := c.get("/txhash/"+args[0], &r); err != nil {
+		return err
+	}
+
+	if err := verifyProof(&p, r.Transaction); err != nil {
+		return fmt.Errorf("proof does not verify: %w", err)
+	}
+
+	if out.json {
+		return printJSON(raw)
+	}
+
+	fmt.Println("Data hash:  ", p.DataHash)
+	fmt.Println("Block:      ", p.BlockHeight, p.BlockHash)
+	fmt.Println("Index:      ", p.TxIndex)
+	fmt.Println("Tx hash:    ", p.TxHash)
+	fmt.Println("Merkle root:", p.MerkleRoot, "("+p.HashAlgorithm+")")
+	fmt.Println("Path:")
+
+	for i, step := range p.Proof {
+		fmt.Printf("  %2d %-5s %s\n", i, step.Side, step.Hash)
+	}
+
+	fmt.Println("Verified: the transaction is included under the Merkle root.")
+
+	return nil
+}
+
+func verifyProof(p *proofResponse, tx *transaction.Transaction) error {
+
+	h, err := crypto.NewHasher(p.HashAlgorithm)
+	if err != nil {
+		return err
+	}
+
+	leaf, err := tx.HashWith(h)
+	if err != nil {
+		return err
+	}
+
+	if hex.EncodeToString(leaf) != p.TxHash {
+		return errors.New("transaction does not hash to the proven leaf")
+	}
+
+	root, err := hex.DecodeString(p.MerkleRoot)
+	if err != nil {
+		return err
+	}
+
+	var steps []block.ProofStep
+
+	for _, s := range p.Proof {
+
+		hash, err := hex.DecodeString(s.Hash)
+		if err != nil {
+			return err
+		}
+
+		steps = append(steps, block.ProofStep{Hash: hash, Left: s.Side == "left"})
+	}
+
+	if !block.VerifyMerkleProof(h, leaf, steps, root) {
+		return errors.New("path does not reach the Merkle root")
+	}
+
+	return nil
+}
+
+func runValidators(c *client, out output, args []string) error {
+
+	if len(args) != 0 {
+		return errors.New("usage: aegisq validators")
+	}
+
+	var r validatorsResponse
+
+	raw, err := c.get("/validators", &r)
+	if err != nil {
+		return err
+	}
+
+	if out.json {
+		return printJSON(raw)
+	}
+
+	fmt.Println("Height:     ", r.Height)
+	fmt.Println("Next leader:", r.NextLeader)
+	fmt.Println()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NODE ID\tALGORITHM\tPOWER\tKEY\tLOCAL\tROTATION")
+
+	for _, v := range r.Validators {
+
+		rotation := "-"
+		if v.PendingRotation {
+			rotation = "pending"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%t\t%s\n", v.NodeID, v.Algorithm, v.Power, shortHex(v.PublicKey), v.Local, rotation)
+	}
+
+	return w.Flush()
+}
+
+func runConsensus(c *client, out output, args []string) error {
+
+	if len(args) != 0 {
+		return errors.New("usage: aegisq consensus")
+	}
+
+	var r consensusResponse
+
+	raw, err := c.get("/consensus", &r)
+	if err != nil {
+		return err
+	}
+
+	if out.json {
+		return printJSON(raw)
+	}
+
+	fmt.Println("Height:    ", r.Height)
+	fmt.Println("View:      ", r.View)
+	fmt.Println("Leader:    ", r.Leader)
+	fmt.Printf("Quorum:     %d/%d\n", r.Quorum.Received, r.Quorum.Required)
+	fmt.Println("Status:    ", r.Status)
+	fmt.Println("Validators:", len(r.Validators))
+
+	return nil
+}
+
+// =========================
+// FORMATTING
+// =========================
+
+func printTx(tx *transaction.Transaction) {
+
+	fmt.Println("Sender:      ", tx.SenderID)
+	fmt.Println("Algorithm:   ", tx.Algorithm)
+	fmt.Println("Data hash:   ", tx.DataHash)
+	fmt.Println("Metadata:    ", tx.Metadata)
+	fmt.Println("Timestamp:   ", time.Unix(tx.Timestamp, 0).UTC().Format(time.RFC3339))
+	fmt.Println("Public key:  ", shortHex(tx.PublicKey))
+	fmt.Println("Signature:   ", shortHex(tx.Signature))
+
+	if tx.Rotation != nil {
+		fmt.Println("Key rotation: activates at height", tx.Rotation.ActivationHeight)
+	}
+}
+
+// shortHex abbreviates long keys and signatures for terminal output.
+func shortHex(b []byte) string {
+
+	s := hex.EncodeToString(b)
+	if len(s) <= 24 {
+		return s
+	}
+
+	return fmt.Sprintf("%s…%s (%d bytes)", s[:12], s[len(s)-8:], len(b))
+}
+
+func printJSON(raw []byte) error {
+
+	var buf bytes.Buffer
+
+	if err := json.Indent(&buf, raw, "", "  "); err != nil {
+		return err
+	}
+
+	buf.WriteByte('\n')
+
+	_, err := buf.WriteTo(os.Stdout)
+	return err
+}
+
+func envOr(name string, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return fallback
+}
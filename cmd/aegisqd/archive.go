@@ -0,0 +1,322 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/archive"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/config"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/storage"
+)
+
+// runExport writes the node's chain, with its genesis, to a chain
+// archive. "-" writes to standard output.
+func runExport(args []string) error {
+
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	to := fs.Uint64("to", 0, "last height to export (default: the tip)")
+
+	cfg, err := parseNodeFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		return errors.New("usage: aegisqd export [-to HEIGHT] <FILE|->")
+	}
+
+	path := fs.Arg(0)
+
+	// Keep standard output for the archive
+	status := os.Stdout
+	if path == "-" {
+		status = os.Stderr
+	}
+
+	db, err := openNodeDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	g, err := chainGenesis(db, cfg)
+	if err != nil {
+		return err
+	}
+
+	params, err := g.Params()
+	if err != nil {
+		return err
+	}
+
+	doc, err := json.Marshal(g)
+	if err != nil {
+		return err
+	}
+
+	tip, err := db.GetLatestHeight()
+	if err != nil {
+		return err
+	}
+
+	// Archives replay from genesis
+	base, err := db.Base()
+	if err != nil {
+		return err
+	}
+
+	if base > 0 {
+		return fmt.Errorf("database is state synced from height %d and lacks the earlier blocks an archive needs", base)
+	}
+
+	height := tip
+	if *to > 0 {
+		if *to > tip {
+			return fmt.Errorf("height %d is above the tip %d", *to, tip)
+		}
+		height = *to
+	}
+
+	// A file is written under a temporary name, so a failed export
+	// never leaves a partial archive behind
+	var out io.Writer = os.Stdout
+	var file *os.File
+	tmp := path + ".tmp"
+
+	if path != "-" {
+
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("%s already exists", path)
+		}
+
+		file, err = os.OpenFile(tmp, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			file.Close()
+			os.Remove(tmp)
+		}()
+
+		out = file
+	}
+
+	w, err := archive.NewWriter(out, archive.Header{
+		ChainID:       params.ID,
+		HashAlgorithm: params.Hasher.Algorithm(),
+		GenesisHash:   params.GenesisHash,
+		Genesis:       doc,
+		Height:        height,
+		Created:       time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	txs := 0
+
+	for h := uint64(1); h <= height; h++ {
+
+		b, err := db.GetBlock(h)
+		if err != nil {
+			return fmt.Errorf("block %d: %w", h, err)
+		}
+
+		// Archives carry finality: an importer trusts no block
+		// without its certificate
+		commit, err := db.Commit(h)
+		if err != nil {
+			return fmt.Errorf("block %d: %w", h, err)
+		}
+
+		if err := w.WriteBlock(b, commit); err != nil {
+			return err
+		}
+
+		txs += len(b.Transactions)
+	}
+
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	if file != nil {
+
+		if err := file.Sync(); err != nil {
+			return err
+		}
+
+		if err := os.Rename(tmp, path); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(status, "Exported %d blocks, %d transactions of %s to %s in %s\n",
+		height, txs, params.ID, path, time.Since(start).Round(time.Millisecond))
+
+	return nil
+}
+
+// runImport replays a chain archive into a fresh database, validating
+// every block and its commit certificate against the archived genesis
+// as a node would. "-" reads from standard input.
+func runImport(args []string) error {
+
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+
+	cfg, err := parseNodeFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		return errors.New("usage: aegisqd import <FILE|->")
+	}
+
+	var in io.Reader = os.Stdin
+
+	if path := fs.Arg(0); path != "-" {
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		in = f
+	}
+
+	r, err := archive.NewReader(in)
+	if err != nil {
+		return err
+	}
+
+	// The archived genesis must be the one its blocks are anchored to
+	g, err := config.ParseGenesis(r.Header.Genesis)
+	if err != nil {
+		return fmt.Errorf("archived genesis: %w", err)
+	}
+
+	params, err := g.Params()
+	if err != nil {
+		return err
+	}
+
+	if string(params.GenesisHash) != string(r.Header.GenesisHash) {
+		return fmt.Errorf("archived genesis hashes to %x, archive is anchored to %x", params.GenesisHash, r.Header.GenesisHash)
+	}
+
+	fmt.Println("Archive:", r.Header.ChainID, r.Header.Height, "blocks, created", r.Header.Created.Format(time.RFC3339))
+	fmt.Printf("Genesis: %x\n", params.GenesisHash)
+
+	db, err := storage.Open(cfg.Path(cfg.DBPath))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	height, err := db.GetLatestHeight()
+	if err != nil {
+		return err
+	}
+
+	if height > 0 {
+		return fmt.Errorf("%s already holds %d blocks; import needs a fresh database", cfg.DBPath, height)
+	}
+
+	if err := useArchivedGenesis(cfg, g, params.GenesisHash); err != nil {
+		return err
+	}
+
+	if err := db.UseHasher(params.Hasher); err != nil {
+		return err
+	}
+
+	if err := db.UseGenesis(params.GenesisHash); err != nil {
+		return err
+	}
+
+	if err := db.SaveGenesisDocument(r.Header.Genesis); err != nil {
+		return err
+	}
+
+	v, _, err := genesisVerifier(g)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	imported, txs := 0, 0
+
+	for {
+
+		b, commit, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err == nil {
+			err = v.VerifyCommit(b, commit)
+		}
+
+		if err == nil {
+			err = v.Verify(b)
+		}
+
+		if err == nil {
+			err = db.SaveCommittedBlock(b, commit)
+		}
+
+		if err != nil {
+			fmt.Println("Imported", imported, "valid blocks before the error.")
+			return err
+		}
+
+		imported++
+		txs += len(b.Transactions)
+	}
+
+	fmt.Printf("Imported %d blocks, %d transactions in %s\n", imported, txs, time.Since(start).Round(time.Millisecond))
+
+	return nil
+}
+
+// useArchivedGenesis checks the node's genesis file against the
+// archived genesis g, with hash genesisHash, or writes g there for a
+// node seeded from the archive.
+func useArchivedGenesis(cfg *config.NodeConfig, g *config.Genesis, genesisHash []byte) error {
+
+	path := cfg.Path(cfg.GenesisFile)
+
+	local, err := loadGenesis(path)
+	if err != nil {
+		return err
+	}
+
+	if local == nil {
+
+		if err := g.Save(path); err != nil {
+			return err
+		}
+
+		fmt.Println("Wrote archived genesis to", path)
+		return nil
+	}
+
+	hash, err := local.Hash()
+	if err != nil {
+		return err
+	}
+
+	if string(hash) != string(genesisHash) {
+		return fmt.Errorf("%s is genesis %x, not the archived chain's", path, hash)
+	}
+
+	return nil
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/block"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/chain"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/config"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/ledger"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/storage"
+)
+
+// runDB dispatches the offline database commands. The node must be
+// stopped: they open the database exclusively.
+func runDB(args []string) error {
+
+	if len(args) == 0 {
+		return errors.New("usage: aegisqd db <verify|rollback|stats> ...")
+	}
+
+	switch args[0] {
+
+	case "verify":
+		return runDBVerify(args[1:])
+
+	case "rollback":
+		return runDBRollback(args[1:])
+
+	case "stats":
+		return runDBStats(args[1:])
+
+	default:
+		return fmt.Errorf("unknown db command: %s", args[0])
+	}
+}
+
+// openNodeDB opens the existing database of the node configured by
+// cfg.
+func openNodeDB(cfg *config.NodeConfig) (*storage.DB, error) {
+
+	path := cfg.Path(cfg.DBPath)
+
+	// Open would create a missing database
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+
+	return storage.Open(path)
+}
+
+// parseDBFlags parses the flags of a db command, which takes no
+// arguments, and opens the node's database.
+func parseDBFlags(fs *flag.FlagSet, args []string) (*storage.DB, *config.NodeConfig, error) {
+
+	cfg, err := parseNodeFlags(fs, args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if fs.NArg() != 0 {
+		return nil, nil, fmt.Errorf("unexpected arguments: %q", fs.Args())
+	}
+
+	db, err := openNodeDB(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return db, cfg, nil
+}
+
+// chainGenesis returns the genesis the database is anchored to: the
+// node's genesis file or, without one, the document stored by the
+// node. Either must hash to the anchored genesis.
+func chainGenesis(db *storage.DB, cfg *config.NodeConfig) (*config.Genesis, error) {
+
+	doc, anchored, err := db.GenesisDocument()
+	if err != nil {
+		return nil, err
+	}
+
+	if anchored == nil {
+		return nil, errors.New("database is not anchored to a genesis")
+	}
+
+	g, err := loadGenesis(cfg.Path(cfg.GenesisFile))
+	if err != nil {
+		return nil, err
+	}
+
+	if g == nil {
+
+		if doc == nil {
+			return nil, fmt.Errorf("no %s and no genesis stored in the database; start the node once or pass -genesis", cfg.GenesisFile)
+		}
+
+		if g, err = config.ParseGenesis(doc); err != nil {
+			return nil, fmt.Errorf("stored genesis: %w", err)
+		}
+	}
+
+	hash, err := g.Hash()
+	if err != nil {
+		return nil, err
+	}
+
+	if string(hash) != string(anchored) {
+		return nil, fmt.Errorf("database belongs to genesis %x, not %x", anchored, hash)
+	}
+
+	return g, nil
+}
+
+// genesisVerifier returns a verifier for the chain of g, from its
+// first block, tracking the state to check the committed state roots.
+func genesisVerifier(g *config.Genesis) (*ledger.ChainVerifier, chain.Params, error) {
+
+	params, err := g.Params()
+	if err != nil {
+		return nil, params, err
+	}
+
+	vs, err := g.ValidatorSet()
+	if err != nil {
+		return nil, params, err
+	}
+
+	algorithms := make(map[string]string)
+	for _, v := range g.Validators {
+		algorithms[v.NodeID] = v.Algorithm
+	}
+
+	st, err := g.State()
+	if err != nil {
+		return nil, params, err
+	}
+
+	v := ledger.NewChainVerifier(params, vs, algorithms)
+	v.TrackState(st)
+
+	return v, params, nil
+}
+
+// baseVerifier returns a verifier for a state-synced chain, from the
+// block after base, the height its snapshot was taken at.
+func baseVerifier(db *storage.DB, params chain.Params, base uint64) (*ledger.ChainVerifier, error) {
+
+	m, err := db.Snapshot(base)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot %d: %w", base, err)
+	}
+
+	st, err := storedSnapshot(db, m, params.Hasher)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot %d: %w", base, err)
+	}
+
+	b, err := db.GetBlock(base)
+	if err != nil {
+		return nil, err
+	}
+
+	if string(b.StateRoot) != string(m.StateRoot) {
+		return nil, fmt.Errorf("snapshot %d does not match the state root of its block", base)
+	}
+
+	return ledger.NewChainVerifierAt(params, st, b)
+}
+
+// runDBVerify re-validates every stored block from genesis: linkage,
+// Merkle root, transaction and block signatures, the leader schedule,
+// the state roots and the commit certificates. A state-synced database is verified from its
+// base snapshot. It reports the first invalid block.
+func runDBVerify(args []string) error {
+
+	fs := flag.NewFlagSet("db verify", flag.ContinueOnError)
+
+	db, cfg, err := parseDBFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	g, err := chainGenesis(db, cfg)
+	if err != nil {
+		return err
+	}
+
+	v, params, err := genesisVerifier(g)
+	if err != nil {
+		return err
+	}
+
+	if err := db.UseHasher(params.Hasher); err != nil {
+		return err
+	}
+
+	tip, err := db.GetLatestHeight()
+	if err != nil {
+		return err
+	}
+
+	base, err := db.Base()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Chain:", params.ID, "hash:", params.Hasher.Algorithm())
+	fmt.Printf("Genesis: %x\n", params.GenesisHash)
+
+	if base > 0 {
+
+		if v, err = baseVerifier(db, params, base); err != nil {
+			return err
+		}
+
+		fmt.Println("State synced at height", base)
+	}
+
+	fmt.Println("Verifying", tip-base, "blocks...")
+
+	start := time.Now()
+	txs := 0
+
+	for h := base + 1; h <= tip; h++ {
+
+		b, err := db.GetBlock(h)
+		if err == nil {
+			err = verifyCommit(db, v, b)
+		}
+		if err == nil {
+			err = v.Verify(b)
+		}
+
+		if err != nil {
+			fmt.Println("Invalid block at height", h, "-", err)
+			fmt.Println("Blocks", base+1, "to", h-1, "are valid; remove the rest with: aegisqd db rollback -to", h-1)
+			return fmt.Errorf("chain is invalid from height %d", h)
+		}
+
+		txs += len(b.Transactions)
+	}
+
+	fmt.Printf("Chain valid: %d blocks, %d transactions in %s\n", tip-base, txs, time.Since(start).Round(time.Millisecond))
+
+	if st := v.State(); st != nil {
+		fmt.Printf("State: %d records, root %x\n", st.Records(), st.Root(params.Hasher))
+	}
+
+	if tip > 0 {
+		fmt.Printf("Tip: %x\n", v.Tip().Hash)
+	}
+
+	return nil
+}
+
+// verifyCommit checks the commit certificate stored with b, if any:
+// blocks from before certificates were kept have none.
+func verifyCommit(db *storage.DB, v *ledger.ChainVerifier, b *block.Block) error {
+
+	c, err := db.Commit(uint64(b.Index))
+	if errors.Is(err, storage.ErrCommitNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return v.VerifyCommit(b, c)
+}
+
+// runDBRollback deletes the blocks above a height, with their index
+// entries, and makes that height the tip.
+func runDBRollback(args []string) error {
+
+	fs := flag.NewFlagSet("db rollback", flag.ContinueOnError)
+	to := fs.Int64("to", -1, "height to keep as the new tip (required)")
+
+	db, _, err := parseDBFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if *to < 0 {
+		return errors.New("usage: aegisqd db rollback -to HEIGHT")
+	}
+
+	removed, err := db.Rollback(uint64(*to))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Removed %d blocks; tip is now height %d\n", removed, *to)
+
+	return nil
+}
+
+// runDBStats reports the chain the database holds and its bucket
+// sizes.
+func runDBStats(args []string) error {
+
+	fs := flag.NewFlagSet("db stats", flag.ContinueOnError)
+
+	db, cfg, err := parseDBFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	s, err := db.Stats()
+	if err != nil {
+		return err
+	}
+
+	path := cfg.Path(cfg.DBPath)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	hashAlgorithm := s.HashAlgorithm
+	if hashAlgorithm == "" {
+		hashAlgorithm = "-"
+	}
+
+	fmt.Println("Database:", path, "-", info.Size(), "bytes")
+	fmt.Println("Height:", s.Height)
+	if s.Base > 0 {
+		fmt.Println("Base:", s.Base, "(state synced; earlier blocks not stored)")
+	}
+	fmt.Println("Hash:", hashAlgorithm)
+	fmt.Printf("Genesis: %x (document stored: %t)\n", s.GenesisHash, s.HasGenesis)
+	fmt.Println()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "BUCKET\tKEYS\tDATA BYTES\tALLOCATED")
+
+	for _, b := range s.Buckets {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\n", b.Name, b.Keys, b.Bytes, b.Allocated)
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/config"
+)
+
+// loadNodeConfig builds the node configuration for args: defaults,
+// then <home>/config.yaml, then AEGISQ_* variables, then flags. The
+// home directory itself comes from -home or AEGISQ_HOME.
+func loadNodeConfig(args []string) (*config.NodeConfig, error) {
+
+	fs := flag.NewFlagSet("aegisqd", flag.ContinueOnError)
+
+	cfg, err := parseNodeFlags(fs, args)
+	if err != nil {
+		return nil, err
+	}
+
+	if fs.NArg() != 0 {
+		return nil, fmt.Errorf("unexpected arguments: %q", fs.Args())
+	}
+
+	return cfg, nil
+}
+
+// parseNodeFlags parses args with the node flags added to fs, leaving
+// positional arguments in fs.Args().
+func parseNodeFlags(fs *flag.FlagSet, args []string) (*config.NodeConfig, error) {
+
+	load := nodeFlags(fs)
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	return load()
+}
+
+// nodeFlags registers the node configuration flags on fs. The returned
+// function builds the configuration once fs has been parsed.
+func nodeFlags(fs *flag.FlagSet) func() (*config.NodeConfig, error) {
+
+	home := fs.String("home", envOr("AEGISQ_HOME", "."), "node home directory (env AEGISQ_HOME)")
+	dbPath := fs.String("db", "", "block database path (env AEGISQ_DB)")
+	genesis := fs.String("genesis", "", "genesis file (env AEGISQ_GENESIS)")
+	apiListen := fs.String("api.listen", "", "API listen address (env AEGISQ_API_LISTEN)")
+	p2pListen := fs.String("p2p.listen", "", "peer listen address (env AEGISQ_P2P_LISTEN)")
+	peers := fs.String("peers", "", "comma-separated peer host:port list (env AEGISQ_PEERS)")
+	seeds := fs.String("seeds", "", "comma-separated seed host:port list (env AEGISQ_SEEDS)")
+	maxInbound := fs.Int("p2p.max-inbound", 0, "max inbound peer hosts served at once")
+	maxOutbound := fs.Int("p2p.max-outbound", 0, "max peers connected beyond -peers")
+	externalAddr := fs.String("p2p.external-address", "", "host:port other nodes reach this node at")
+	banDuration := fs.Duration("p2p.ban-duration", 0, "first ban of a misbehaving peer host")
+	keystoreDir := fs.String("keystore-dir", "", "validator keystore directory")
+	remoteSigners := fs.String("remote-signers", "", "nodeID=address list (env AEGISQ_REMOTE_SIGNERS)")
+	maxTxs := fs.Int("block.max-txs", 0, "max transactions per proposed block")
+	interval := fs.Duration("block.interval", 0, "time between proposed blocks")
+	emptyBlocks := fs.Bool("block.empty-blocks", true, "propose blocks without pending transactions")
+	emptyInterval := fs.Duration("block.empty-block-interval", 0, "with -block.empty-blocks=false, propose an empty block after this idle time")
+	syntheticTxs := fs.Int("block.synthetic-txs", 0, "synthetic transactions per proposed block")
+	mempoolSize := fs.Int("mempool.size", 0, "max pending transactions")
+	snapshotInterval := fs.Int("snapshot.interval", 0, "take a state snapshot every N blocks (0: none)")
+	snapshotKeep := fs.Int("snapshot.keep-recent", 0, "state snapshots to keep")
+	syncRange := fs.Int("sync.range-size", 0, "blocks per sync request")
+	syncParallel := fs.Int("sync.parallel", 0, "sync requests in flight")
+	syncInterval := fs.Duration("sync.interval", 0, "time between polls of the peers' heights")
+	gossipQueue := fs.Int("gossip.queue-size", 0, "messages queued per gossip peer")
+	gossipBatch := fs.Int("gossip.batch-size", 0, "messages sent to a gossip peer at once")
+	proposeTimeout := fs.Duration("consensus.propose-timeout", 0, "how long a leader failing to sign its block is retried before the next view (0: genesis value)")
+	commitTimeout := fs.Duration("consensus.commit-timeout", 0, "how long failing commit votes are retried before the next view (0: genesis value)")
+	devValidators := fs.Int("dev.validators", 0, "local validators of a dev chain without genesis")
+	devEphemeral := fs.Bool("dev.ephemeral-keys", false, "without AEGISQ_PASSPHRASE, use new dev validator keys on every start")
+
+	return func() (*config.NodeConfig, error) {
+
+		cfg, err := config.LoadNodeConfig(*home)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := cfg.ApplyEnv(os.Getenv); err != nil {
+			return nil, err
+		}
+
+		// Only flags given on the command line override
+		var flagErr error
+
+		fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "db":
+				cfg.DBPath = *dbPath
+			case "genesis":
+				cfg.GenesisFile = *genesis
+			case "api.listen":
+				cfg.API.Listen = *apiListen
+			case "p2p.listen":
+				cfg.P2P.Listen = *p2pListen
+			case "peers":
+				cfg.P2P.Peers = config.SplitList(*peers)
+			case "seeds":
+				cfg.P2P.Seeds = config.SplitList(*seeds)
+			case "p2p.max-inbound":
+				cfg.P2P.MaxInbound = *maxInbound
+			case "p2p.max-outbound":
+				cfg.P2P.MaxOutbound = *maxOutbound
+			case "p2p.external-address":
+				cfg.P2P.ExternalAddress = *externalAddr
+			case "p2p.ban-duration":
+				cfg.P2P.BanDuration = config.Duration(*banDuration)
+			case "keystore-dir":
+				cfg.Validator.KeystoreDir = *keystoreDir
+			case "remote-signers":
+				signers, err := config.ParseRemoteSigners(*remoteSigners)
+				if err != nil {
+					flagErr = fmt.Errorf("-remote-signers: %w", err)
+				}
+				cfg.Validator.RemoteSigners = signers
+			case "block.max-txs":
+				cfg.Block.MaxTxs = *maxTxs
+			case "block.interval":
+				cfg.Block.Interval = config.Duration(*interval)
+			case "block.empty-blocks":
+				cfg.Block.EmptyBlocks = *emptyBlocks
+			case "block.empty-block-interval":
+				cfg.Block.EmptyBlockInterval = config.Duration(*emptyInterval)
+			case "block.synthetic-txs":
+				cfg.Block.SyntheticTxs = *syntheticTxs
+			case "mempool.size":
+				cfg.Mempool.Size = *mempoolSize
+			case "snapshot.interval":
+				cfg.Snapshot.Interval = *snapshotInterval
+			case "snapshot.keep-recent":
+				cfg.Snapshot.KeepRecent = *snapshotKeep
+			case "sync.range-size":
+				cfg.Sync.RangeSize = *syncRange
+			case "sync.parallel":
+				cfg.Sync.Parallel = *syncParallel
+			case "sync.interval":
+				cfg.Sync.Interval = config.Duration(*syncInterval)
+			case "gossip.queue-size":
+				cfg.Gossip.QueueSize = *gossipQueue
+			case "gossip.batch-size":
+				cfg.Gossip.BatchSize = *gossipBatch
+			case "consensus.propose-timeout":
+				cfg.Consensus.ProposeTimeout = config.Duration(*proposeTimeout)
+			case "consensus.commit-timeout":
+				cfg.Consensus.CommitTimeout = config.Duration(*commitTimeout)
+			case "dev.validators":
+				cfg.Dev.Validators = *devValidators
+			case "dev.ephemeral-keys":
+				cfg.Dev.EphemeralKeys = *devEphemeral
+			}
+		})
+
+		if flagErr != nil {
+			return nil, flagErr
+		}
+
+		if err := cfg.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid configuration: %w", err)
+		}
+
+		return cfg, nil
+	}
+}
+
+func envOr(name string, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// printNodeConfig shows the effective settings at startup.
+func printNodeConfig(cfg *config.NodeConfig, g *config.Genesis) {
+
+	propose, commit := cfg.Timeouts(g)
+
+	fmt.Println("Home:", cfg.Home)
+	fmt.Println("Database:", cfg.Path(cfg.DBPath))
+	fmt.Println("API listen:", cfg.API.Listen, "P2P listen:", cfg.P2P.Listen, "peers:", len(cfg.P2P.Peers), "seeds:", len(cfg.P2P.Seeds))
+	fmt.Println("Timeouts: propose", propose, "commit", commit)
+}
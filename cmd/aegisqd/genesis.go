@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/chain"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/config"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/identity"
+)
+
+// loadGenesis loads the genesis document at path, or returns nil when
+// the node runs without one.
+func loadGenesis(path string) (*config.Genesis, error) {
+
+	g, err := config.LoadGenesis(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	return g, err
+}
+
+// devGenesis describes a local chain run by validators. The genesis
+// time is fixed so that persistent keys give the same genesis hash on
+// every start.
+func devGenesis(validators []*identity.NodeIdentity) *config.Genesis {
+
+	g := &config.Genesis{
+		ChainID:     chain.DefaultID,
+		GenesisTime: time.Unix(0, 0).UTC(),
+		Consensus:   config.DefaultConsensusParams,
+	}
+
+	for _, v := range validators {
+		g.Validators = append(g.Validators, config.GenesisValidator{
+			NodeID:    v.NodeID,
+			Algorithm: v.Algorithm(),
+			PublicKey: v.PublicKey,
+			Power:     1,
+		})
+	}
+
+	return g
+}
+
+// devValidators returns count local validators named validator-1..N,
+// with keystores in keystoreDir, or with new keys when ephemeral is
+// set and there is no passphrase.
+func devValidators(
+	count int,
+	keystoreDir string,
+	signer crypto.Signer,
+	passphrase string,
+	ephemeral bool,
+	remoteSigners map[string]string,
+) ([]*identity.NodeIdentity, error) {
+
+	var validators []*identity.NodeIdentity
+
+	for i := 1; i <= count; i++ {
+
+		nodeID := fmt.Sprintf("validator-%d", i)
+
+		var node *identity.NodeIdentity
+		var err error
+
+		if addr, ok := remoteSigners[nodeID]; ok {
+			node, err = connectRemoteValidator(nodeID, addr, signer)
+			fmt.Println("Using remote signer for", nodeID, "at", addr)
+		} else if passphrase == "" && ephemeral {
+			node, err = identity.NewNodeIdentity(nodeID, signer)
+		} else {
+			node, err = loadOrCreateValidator(keystoreDir, nodeID, signer, passphrase)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		validators = append(validators, node)
+	}
+
+	return validators, nil
+}
+
+// genesisValidators returns the genesis validators this node holds a
+// key for, through a remote signer or a keystore in keystoreDir. Each
+// key must be the one recorded in genesis.
+func genesisValidators(
+	g *config.Genesis,
+	keystoreDir string,
+	signer crypto.Signer,
+	passphrase string,
+	remoteSigners map[string]string,
+) ([]*identity.NodeIdentity, error) {
+
+	var validators []*identity.NodeIdentity
+
+	for _, v := range g.Validators {
+
+		var node *identity.NodeIdentity
+		var err error
+
+		if addr, ok := remoteSigners[v.NodeID]; ok {
+			node, err = connectRemoteValidator(v.NodeID, addr, signer)
+			fmt.Println("Using remote signer for", v.NodeID, "at", addr)
+		} else if passphrase != "" {
+			node, err = loadValidator(keystoreDir, v.NodeID, signer, passphrase)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if node == nil {
+			continue
+		}
+
+		if string(node.PublicKey) != string(v.PublicKey) {
+			return nil, fmt.Errorf("%s: local key does not match genesis", v.NodeID)
+		}
+
+		validators = append(validators, node)
+	}
+
+	return validators, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/block"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/consensus"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/gossip"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/transaction"
+)
+
+// submitWait bounds how long a submission waits for room in the
+// gossip queues before it is refused as busy.
+const submitWait = 2 * time.Second
+
+var errEquivocation = errors.New("equivocation")
+
+// gossipHandler applies messages from peers: transactions go through
+// the same admission as the API, and proposals through follow.
+func gossipHandler(p *producer) gossip.Apply {
+
+	return func(m *gossip.Message) error {
+
+		switch m.Kind {
+		case gossip.KindTx:
+			return p.admit(m.Tx)
+		case gossip.KindProposal:
+			return p.follow(m.Proposal.Block, m.Proposal.Commit)
+		}
+
+		return fmt.Errorf("unknown message kind %q", m.Kind)
+	}
+}
+
+// submit admits a transaction from a client and floods it to the
+// peers, so it reaches whichever node leads. When the peers are not
+// keeping up it is taken back out of the pool and refused with
+// gossip.ErrBusy.
+func (p *producer) submit(ctx context.Context, tx *transaction.Transaction) error {
+
+	if err := p.admit(tx); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, submitWait)
+	defer cancel()
+
+	if err := p.gossip.Publish(ctx, gossip.NewTx(tx)); err != nil {
+		p.pool.Remove([]*transaction.Transaction{tx})
+		return err
+	}
+
+	return nil
+}
+
+// follow appends a block committed elsewhere when it extends the
+// chain. A block already stored is accepted, so it is relayed; one
+// further ahead is left to block sync, since it cannot be checked
+// yet. A block for a stored height is first checked with the keys of
+// that height: only a different block carrying a valid certificate is
+// equivocation, and anything else is an invalid block.
+func (p *producer) follow(b *block.Block, commit *consensus.Certificate) error {
+
+	index := uint64(b.Index)
+
+	if height := p.chain.Height(); index == height+1 {
+
+		err := p.chain.Append(b, commit)
+
+		// Unless block sync or this node got there first
+		if err == nil || p.chain.Height() < index {
+			return err
+		}
+	}
+
+	height := p.chain.Height()
+
+	if index > height {
+		return fmt.Errorf("proposal for height %d is ahead of the chain at %d", b.Index, height)
+	}
+
+	if err := p.chain.Check(b, commit); err != nil {
+		return err
+	}
+
+	stored, err := p.chain.Blocks(index, index)
+	if err != nil {
+		return err
+	}
+
+	if string(stored[0].Block.Hash) != string(b.Hash) {
+		return fmt.Errorf("%w: certified proposal for height %d conflicts with the stored block", errEquivocation, b.Index)
+	}
+
+	return nil
+}
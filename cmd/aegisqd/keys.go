@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/identity"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/remotesigner"
+)
+
+const passphraseEnvVar = "AEGISQ_PASSPHRASE"
+
+// connectRemoteValidator returns an identity whose signing is served by
+// the aegisq-signer at addr.
+func connectRemoteValidator(
+	nodeID string,
+	addr string,
+	signer crypto.Signer,
+) (*identity.NodeIdentity, error) {
+
+	client, err := remotesigner.Dial(addr, []byte(os.Getenv(remotesigner.SecretEnvVar)))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", nodeID, err)
+	}
+
+	node, err := client.Identity(signer)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("%s: %w", nodeID, err)
+	}
+
+	if node.NodeID != nodeID {
+		client.Close()
+		return nil, fmt.Errorf("signer at %s holds key for %s, not %s", addr, node.NodeID, nodeID)
+	}
+
+	return node, nil
+}
+
+// runKeygen creates a new encrypted keystore for nodeID at path.
+// The algorithm follows CRYPTO_ALG, the passphrase comes from AEGISQ_PASSPHRASE.
+func runKeygen(nodeID string, path string) error {
+
+	passphrase := os.Getenv(passphraseEnvVar)
+	if passphrase == "" {
+		return fmt.Errorf("%s must be set to encrypt the keystore", passphraseEnvVar)
+	}
+
+	signer, err := crypto.NewDefaultSigner()
+	if err != nil {
+		return err
+	}
+
+	node, err := identity.NewNodeIdentity(nodeID, signer)
+	if err != nil {
+		return err
+	}
+
+	if err := identity.SaveKeystore(path, node, []byte(passphrase)); err != nil {
+		return err
+	}
+
+	pubPEM, err := node.PublicKeyPEM()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Keystore written:", path)
+	fmt.Print(node.String())
+	fmt.Print(pubPEM)
+
+	return nil
+}
+
+// loadValidator returns the identity for nodeID from the keystore
+// directory dir, or nil when there is no keystore for it.
+func loadValidator(
+	dir string,
+	nodeID string,
+	signer crypto.Signer,
+	passphrase string,
+) (*identity.NodeIdentity, error) {
+
+	path := filepath.Join(dir, nodeID+".json")
+
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	node, err := identity.LoadKeystore(path, []byte(passphrase))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if node.Algorithm() != signer.Algorithm() {
+		return nil, fmt.Errorf(
+			"%s: keystore algorithm %s does not match signer %s",
+			path, node.Algorithm(), signer.Algorithm(),
+		)
+	}
+
+	node.Signer = signer
+	return node, nil
+}
+
+// loadOrCreateValidator returns a persistent identity for nodeID from the
+// keystore directory dir, creating it on first start.
+func loadOrCreateValidator(
+	dir string,
+	nodeID string,
+	signer crypto.Signer,
+	passphrase string,
+) (*identity.NodeIdentity, error) {
+
+	if passphrase == "" {
+		return nil, fmt.Errorf("%s must be set to unlock the keystore of %s (or run a throwaway dev chain with -dev.ephemeral-keys)", passphraseEnvVar, nodeID)
+	}
+
+	node, err := loadValidator(dir, nodeID, signer, passphrase)
+	if err != nil || node != nil {
+		return node, err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	node, err = identity.NewNodeIdentity(nodeID, signer)
+	if err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(dir, nodeID+".json")
+
+	if err := identity.SaveKeystore(path, node, []byte(passphrase)); err != nil {
+		return nil, err
+	}
+
+	return node, nil
+}
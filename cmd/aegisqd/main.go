@@ -5,14 +5,9 @@ import (
 	"log"
 	"os"
 	"strconv"
-	"time"
 
-	"github.com/Sai-shashank-2005/aegisq-protocol/core/block"
-	"github.com/Sai-shashank-2005/aegisq-protocol/core/consensus"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/chain"
 	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
-	"github.com/Sai-shashank-2005/aegisq-protocol/core/identity"
-	"github.com/Sai-shashank-2005/aegisq-protocol/core/scheduler"
-	"github.com/Sai-shashank-2005/aegisq-protocol/core/simulation"
 	"github.com/Sai-shashank-2005/aegisq-protocol/core/storage"
 	"github.com/Sai-shashank-2005/aegisq-protocol/core/transaction"
 )
@@ -28,7 +23,7 @@ func main() {
 		height, _ := strconv.Atoi(os.Args[2])
 		index, _ := strconv.Atoi(os.Args[3])
 
-		db, err := storage.Open("aegisq.db")
+		db, err := openLocalDB()
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -57,7 +52,7 @@ func main() {
 
 		hash := os.Args[2]
 
-		db, err := storage.Open("aegisq.db")
+		db, err := openLocalDB()
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -75,186 +70,111 @@ func main() {
 	}
 
 	// =========================
-	// NORMAL NODE MODE
+	// CLI MODE: keygen
 	// =========================
 
-	signer, err := crypto.NewDilithiumSigner()
-	if err != nil {
-		panic(err)
-	}
-
-	// 1️⃣ Validators
-	var validators []*identity.NodeIdentity
-
-	for i := 1; i <= 4; i++ {
+	if len(os.Args) == 4 && os.Args[1] == "keygen" {
 
-		node, err := identity.NewNodeIdentity(
-			fmt.Sprintf("validator-%d", i),
-			signer,
-		)
-		if err != nil {
+		if err := runKeygen(os.Args[2], os.Args[3]); err != nil {
 			log.Fatal(err)
 		}
-
-		validators = append(validators, node)
-	}
-
-	fmt.Println("Validators initialized.")
-
-	// 2️⃣ Validator governance
-	vs := consensus.NewValidatorSet()
-
-	for _, v := range validators {
-		vs.AddValidator(v.NodeID, v.PublicKey)
-	}
-
-	// 3️⃣ Leader scheduler
-	sched := scheduler.NewRoundRobinScheduler(vs)
-
-	// 4️⃣ Database
-	db, err := storage.Open("aegisq.db")
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer db.Close()
-
-	height, err := db.GetLatestHeight()
-	if err != nil {
-		log.Fatal(err)
+		return
 	}
 
-	var previousHash []byte
+	// =========================
+	// CLI MODE: init / genesis
+	// =========================
 
-	if height > 0 {
+	if len(os.Args) >= 2 && os.Args[1] == "init" {
 
-		lastBlock, err := db.GetBlock(height)
-		if err != nil {
+		if err := runInit(os.Args[2:]); err != nil {
 			log.Fatal(err)
 		}
-
-		previousHash = lastBlock.Hash
-
-		fmt.Println("Restored height:", height)
-
-	} else {
-
-		fmt.Println("No chain found. Starting fresh.")
-	}
-
-	// 5️⃣ Leader selection
-	view := 0
-
-	leaderID, err := sched.GetLeader(int(height+1), view)
-	if err != nil {
-		log.Fatal(err)
+		return
 	}
 
-	var leader *identity.NodeIdentity
-
-	for _, v := range validators {
+	if len(os.Args) >= 2 && os.Args[1] == "genesis" {
 
-		if v.NodeID == leaderID {
-			leader = v
-			break
+		if err := runGenesis(os.Args[2:]); err != nil {
+			log.Fatal(err)
 		}
+		return
 	}
 
-	if leader == nil {
-		log.Fatal("leader not found")
-	}
-
-	fmt.Println("Leader selected:", leader.NodeID)
+	// =========================
+	// CLI MODE: db
+	// =========================
 
-	// 6️⃣ Generate transactions
-	startTx := time.Now()
+	if len(os.Args) >= 2 && os.Args[1] == "db" {
 
-	txs, err := simulation.GenerateSyntheticDataset(10000, leader)
-	if err != nil {
-		log.Fatal(err)
+		if err := runDB(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
 	}
 
-	fmt.Println("Generated synthetic storage transactions:", len(txs))
-	fmt.Println("Transaction generation time:", time.Since(startTx))
-
-	// 7️⃣ Create block
-	startFinalize := time.Now()
+	// =========================
+	// CLI MODE: export / import
+	// =========================
 
-	newBlock := block.NewBlock(
-		int(height+1),
-		view,
-		previousHash,
-		txs,
-	)
+	if len(os.Args) >= 2 && os.Args[1] == "export" {
 
-	if err := newBlock.Finalize(leader); err != nil {
-		log.Fatal(err)
+		if err := runExport(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
 	}
 
-	fmt.Println("Block finalize time:", time.Since(startFinalize))
-	fmt.Println("Proposed block height:", newBlock.Index)
+	if len(os.Args) >= 2 && os.Args[1] == "import" {
 
-	blockHashString := fmt.Sprintf("%x", newBlock.Hash)
-
-	// 8️⃣ BFT voting
-	votePool := consensus.NewVotePool(vs)
-
-	for _, v := range validators {
-
-		vote := consensus.Vote{
-			ValidatorID: v.NodeID,
-			BlockHash:   blockHashString,
-			View:        view,
-			Type:        consensus.Prepare,
+		if err := runImport(os.Args[2:]); err != nil {
+			log.Fatal(err)
 		}
-
-		_ = votePool.AddVote(vote)
-	}
-
-	if !votePool.HasQuorum(blockHashString, view, consensus.Prepare) {
-		fmt.Println("Prepare quorum NOT reached.")
 		return
 	}
 
-	fmt.Println("Prepare quorum reached.")
+	// =========================
+	// CLI MODE: statesync
+	// =========================
 
-	for _, v := range validators {
+	if len(os.Args) >= 2 && os.Args[1] == "statesync" {
 
-		vote := consensus.Vote{
-			ValidatorID: v.NodeID,
-			BlockHash:   blockHashString,
-			View:        view,
-			Type:        consensus.Commit,
+		if err := runStateSync(os.Args[2:]); err != nil {
+			log.Fatal(err)
 		}
-
-		_ = votePool.AddVote(vote)
-	}
-
-	if !votePool.HasQuorum(blockHashString, view, consensus.Commit) {
-		fmt.Println("Commit quorum NOT reached.")
 		return
 	}
 
-	fmt.Println("Commit quorum reached.")
+	// =========================
+	// NORMAL NODE MODE
+	// =========================
 
-	// 9️⃣ Save block
-	if err := db.SaveBlock(newBlock); err != nil {
+	if err := runNode(os.Args[1:]); err != nil {
 		log.Fatal(err)
 	}
+}
 
-	fmt.Println("Block committed at height:", newBlock.Index)
+// openLocalDB opens the block database of the node home given by
+// AEGISQ_HOME and its config.yaml.
+func openLocalDB() (*storage.DB, error) {
 
-	printBlockSummary(newBlock)
+	cfg, err := loadNodeConfig(nil)
+	if err != nil {
+		return nil, err
+	}
 
-	// 🔟 Start API server
+	return storage.Open(cfg.Path(cfg.DBPath))
+}
 
-	// reuse existing votePool (already created above)
-	vp := votePool
+// signerAt returns a signer for the algorithm the chain requires at
+// height. Chains without a migration schedule use Dilithium.
+func signerAt(p chain.Params, height int) (crypto.Signer, error) {
 
-	// create finality engine (safe, no logic change)
-	fe := consensus.NewFinalityEngine(vp)
+	if alg := p.AlgorithmAt(height); alg != "" {
+		return crypto.NewSignerForAlgorithm(alg)
+	}
 
-	// use existing scheduler (rename fix)
-	startServer(db, vs, vp, fe, sched)
+	return crypto.NewDilithiumSigner()
 }
 
 func printTxDetails(height int, index int, tx *transaction.Transaction) {
@@ -270,25 +190,3 @@ func printTxDetails(height int, index int, tx *transaction.Transaction) {
 	fmt.Printf("Signature: %x\n", tx.Signature)
 	fmt.Println("--------------------------------")
 }
-
-func printBlockSummary(b *block.Block) {
-
-	fmt.Println("\n========= BLOCK SUMMARY =========")
-	fmt.Println("Height:", b.Index)
-	fmt.Printf("Hash: %x\n", b.Hash)
-	fmt.Printf("Previous: %x\n", b.PreviousHash)
-	fmt.Println("Total Transactions:", len(b.Transactions))
-
-	for i := 0; i < 5 && i < len(b.Transactions); i++ {
-
-		tx := b.Transactions[i]
-
-		fmt.Println("  Tx", i+1)
-		fmt.Println("   Sender:", tx.SenderID)
-		fmt.Println("   DataHash:", tx.DataHash)
-	}
-
-	if len(b.Transactions) > 5 {
-		fmt.Println("  ...")
-	}
-}
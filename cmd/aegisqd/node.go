@@ -0,0 +1,343 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/block"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/blocksync"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/consensus"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/gossip"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/identity"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/mempool"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/p2p"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/scheduler"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/storage"
+)
+
+// shutdownTimeout bounds how long in-flight API requests may delay
+// shutdown.
+const shutdownTimeout = 5 * time.Second
+
+// runNode runs the node until SIGINT or SIGTERM: it connects to its
+// peers and catches up with them, then produces blocks on the tip
+// while following theirs, and serves the API, block sync and gossip. On shutdown the block in
+// progress is finished, the servers drain and the database is closed.
+func runNode(args []string) error {
+
+	cfg, err := loadNodeConfig(args)
+	if err != nil {
+		return err
+	}
+
+	passphrase := os.Getenv(passphraseEnvVar)
+
+	remoteSigners := cfg.Validator.RemoteSigners
+	keystoreDir := cfg.Path(cfg.Validator.KeystoreDir)
+
+	// 1️⃣ Genesis
+	g, err := loadGenesis(cfg.Path(cfg.GenesisFile))
+	if err != nil {
+		return err
+	}
+
+	// Without genesis.json the local validators form a dev chain
+	var signer crypto.Signer
+	var validators []*identity.NodeIdentity
+	var ephemeral bool
+
+	if g == nil {
+
+		ephemeral = passphrase == "" && cfg.Dev.EphemeralKeys
+
+		signer, err = crypto.NewDilithiumSigner()
+		if err != nil {
+			return err
+		}
+
+		validators, err = devValidators(cfg.Dev.Validators, keystoreDir, signer, passphrase, ephemeral, remoteSigners)
+		if err != nil {
+			return err
+		}
+
+		if ephemeral {
+			log.Println("WARNING: no", passphraseEnvVar, "set: dev validators use EPHEMERAL keys, new on every start. The chain cannot be restarted and these keys must never secure real value.")
+		}
+
+		g = devGenesis(validators)
+
+		fmt.Println("No", cfg.GenesisFile, "found: running a dev chain of the local validators.")
+	}
+
+	if err := cfg.CheckGenesis(g); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	params, err := g.Params()
+	if err != nil {
+		return err
+	}
+
+	printNodeConfig(cfg, g)
+
+	fmt.Println("Chain:", params.ID, "hash:", params.Hasher.Algorithm())
+	fmt.Printf("Genesis: %x\n", params.GenesisHash)
+
+	// 2️⃣ Database, anchored to genesis
+	db, err := storage.Open(cfg.Path(cfg.DBPath))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			log.Println("closing database:", err)
+			return
+		}
+		fmt.Println("Database closed.")
+	}()
+
+	if err := db.UseHasher(params.Hasher); err != nil {
+		return err
+	}
+
+	if err := db.UseGenesis(params.GenesisHash); err != nil {
+		if ephemeral {
+			return fmt.Errorf("%w (ephemeral keys change the dev genesis on every start; set %s or remove %s)", err, passphraseEnvVar, cfg.DBPath)
+		}
+		return err
+	}
+
+	// Offline tools validate the chain against the stored document
+	doc, err := json.Marshal(g)
+	if err != nil {
+		return err
+	}
+
+	if err := db.SaveGenesisDocument(doc); err != nil {
+		return err
+	}
+
+	height, err := db.GetLatestHeight()
+	if err != nil {
+		return err
+	}
+
+	if height > 0 {
+		fmt.Println("Restored height:", height)
+	} else {
+		fmt.Println("No chain found. Starting fresh.")
+	}
+
+	// 3️⃣ Local validators holding genesis keys, with the signer
+	// required for the next block
+	if signer == nil {
+
+		signer, err = signerAt(params, int(height+1))
+		if err != nil {
+			return err
+		}
+
+		validators, err = genesisValidators(g, keystoreDir, signer, passphrase, remoteSigners)
+		if err != nil {
+			return err
+		}
+	}
+
+	if c, ok := signer.(interface{ Close() }); ok {
+		defer c.Close()
+	}
+
+	fmt.Println("Signature algorithm:", signer.Algorithm())
+	fmt.Println("Validators initialized:", len(validators), "local of", len(g.Validators))
+
+	// 4️⃣ Application state at the tip, and from it the validator
+	// keys, including rotations since genesis
+	st, err := loadState(db, g, params)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("State:", st.Records(), "records at height", st.Height())
+
+	vs, _, err := st.ValidatorSet()
+	if err != nil {
+		return err
+	}
+
+	// Votes are not exchanged between nodes, so a node signing blocks
+	// must hold a quorum of the validator keys itself; the others
+	// follow the chain without keys
+	if n, quorum := len(validators), consensus.Quorum(vs.Count()); n > 0 && n < quorum {
+		return fmt.Errorf("%d of %d validator keys are local, but a block needs %d votes and nodes do not exchange votes: hold at least %d of the keys on this node, or none to follow the chain", n, vs.Count(), quorum, quorum)
+	}
+
+	// Leader scheduler
+	sched := scheduler.NewRoundRobinScheduler(vs)
+
+	// 5️⃣ The chain, extended by produced and synced blocks alike
+	verifier, err := tipVerifier(db, params, st)
+	if err != nil {
+		return err
+	}
+
+	bc := blocksync.NewChain(db, verifier)
+	pool := mempool.New(cfg.Mempool.Size)
+
+	var snapshot func(*block.Block)
+	if every := cfg.Snapshot.Interval; every > 0 {
+		snapshot = takeSnapshots(db, st, params, every, cfg.Snapshot.KeepRecent)
+	}
+
+	// Blocks from peers commit transactions this node also holds
+	bc.OnAppend = func(b *block.Block) {
+		pool.Remove(b.Transactions)
+		if snapshot != nil {
+			snapshot(b)
+		}
+	}
+
+	// 6️⃣ Block production, with transactions and proposals gossiped
+	// to and from the peers
+	prod := newProducer(cfg, g, params, bc, vs, sched, pool, signer, st, validators)
+
+	router := gossip.NewRouter(params.Hasher, gossipHandler(prod), gossip.Config{
+		QueueSize: cfg.Gossip.QueueSize,
+		BatchSize: cfg.Gossip.BatchSize,
+	})
+	defer router.Close()
+
+	prod.gossip = router
+
+	// Peers: the configured ones, and others learned from seeds and
+	// peers, kept in the address book
+	book, err := p2p.LoadAddrBook(cfg.Path(cfg.P2P.AddrBook))
+	if err != nil {
+		return fmt.Errorf("address book: %w", err)
+	}
+
+	mgr := p2p.NewManager(p2p.Config{
+		Persistent:      cfg.P2P.Peers,
+		Seeds:           cfg.P2P.Seeds,
+		MaxInbound:      cfg.P2P.MaxInbound,
+		MaxOutbound:     cfg.P2P.MaxOutbound,
+		ExternalAddress: cfg.P2P.ExternalAddress,
+		BanDuration:     time.Duration(cfg.P2P.BanDuration),
+	}, p2p.Info{ChainID: params.ID, GenesisHash: params.GenesisHash}, book, p2p.NewHTTPTransport())
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// 7️⃣ API server, and block sync and gossip served to peers
+	vp := consensus.NewVotePool(vs)
+	fe := consensus.NewFinalityEngine(vp)
+
+	srv := newServer(cfg.API.Listen, db, vs, vp, fe, sched, prod, g)
+
+	p2pMux := http.NewServeMux()
+	p2pMux.Handle("/blocksync/", blocksync.Handler(bc))
+	p2pMux.Handle("/gossip", gossip.Handler(router))
+	p2pMux.Handle("/p2p/", p2p.Handler(mgr))
+
+	p2pSrv := &http.Server{Addr: cfg.P2P.Listen, Handler: mgr.Guard(p2pMux)}
+
+	serverErr := make(chan error, 2)
+
+	for _, s := range []struct {
+		name string
+		srv  *http.Server
+	}{{"API", srv}, {"P2P", p2pSrv}} {
+
+		go func(name string, srv *http.Server) {
+			fmt.Println("🚀", name, "server running on", srv.Addr)
+
+			if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				serverErr <- fmt.Errorf("%s server: %w", name, err)
+				stop()
+			}
+		}(s.name, s.srv)
+	}
+
+	// 8️⃣ Connect to the peers, catch up with them, then produce while
+	// following them
+	peers := newPeerSet()
+
+	reactor := blocksync.NewReactor(bc, peers.list, blocksync.Config{
+		RangeSize: cfg.Sync.RangeSize,
+		Parallel:  cfg.Sync.Parallel,
+		Interval:  time.Duration(cfg.Sync.Interval),
+	})
+
+	managePeers(mgr, router, reactor, peers)
+
+	mgrDone := make(chan error, 1)
+
+	go func() {
+		mgrDone <- mgr.Run(ctx)
+	}()
+
+	caughtUp := make(chan struct{})
+	syncDone := make(chan error, 1)
+
+	go func() {
+		// Catching up needs the peers of the first round
+		<-mgr.Ready()
+
+		err := reactor.Run(ctx, func() { close(caughtUp) })
+		stop()
+		syncDone <- err
+	}()
+
+	var runErr error
+
+	select {
+	case <-ctx.Done():
+	case <-caughtUp:
+		fmt.Printf("Caught up at height %d. Producing blocks every %s (empty blocks: %t)\n", bc.Height(), cfg.Block.Interval, cfg.Block.EmptyBlocks)
+		runErr = prod.run(ctx)
+	}
+
+	// A second signal from here on kills the process
+	stop()
+
+	fmt.Println("Shutting down...")
+
+	// The reactor may be appending a block
+	syncErr := <-syncDone
+
+	if err := <-mgrDone; err != nil {
+		log.Println("saving address book:", err)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	for _, s := range []*http.Server{srv, p2pSrv} {
+		if err := s.Shutdown(shutdownCtx); err != nil {
+			log.Println("server shutdown:", err)
+		}
+	}
+
+	if runErr != nil {
+		return runErr
+	}
+
+	if syncErr != nil {
+		return fmt.Errorf("block sync: %w", syncErr)
+	}
+
+	select {
+	case err := <-serverErr:
+		return err
+	default:
+		return nil
+	}
+}
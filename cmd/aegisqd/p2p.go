@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/blocksync"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/gossip"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/p2p"
+)
+
+// peerSet holds the block sync peers, following the connections of
+// the peer manager.
+type peerSet struct {
+	mu    sync.Mutex
+	peers map[string]blocksync.Peer
+}
+
+func newPeerSet() *peerSet {
+	return &peerSet{peers: make(map[string]blocksync.Peer)}
+}
+
+func (s *peerSet) add(addr string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.peers[addr] = blocksync.NewHTTPPeer(addr)
+}
+
+func (s *peerSet) remove(addr string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.peers, addr)
+}
+
+// list returns the peers in address order.
+func (s *peerSet) list() []blocksync.Peer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]blocksync.Peer, 0, len(s.peers))
+	for _, p := range s.peers {
+		list = append(list, p)
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].ID() < list[j].ID() })
+	return list
+}
+
+// managePeers connects gossip and block sync to the peers mgr
+// connects, and reports back the peers they find failing or
+// misbehaving.
+func managePeers(mgr *p2p.Manager, router *gossip.Router, reactor *blocksync.Reactor, peers *peerSet) {
+
+	mgr.OnConnect = func(addr string) {
+		router.AddPeer(gossip.NewHTTPPeer(addr))
+		peers.add(addr)
+	}
+
+	mgr.OnDisconnect = func(addr string) {
+		router.RemovePeer(addr)
+		peers.remove(addr)
+	}
+
+	// Inbound gossip is known by the sender's host
+	router.OnReject = func(from string, err error) {
+		if o, ok := offense(err); ok {
+			mgr.Report(from, o, err)
+		}
+	}
+
+	router.OnSendError = mgr.Failed
+
+	reactor.OnPeerError = func(peer string, err error) {
+		if o, ok := offense(err); ok {
+			mgr.Report(peer, o, err)
+			return
+		}
+		mgr.Failed(peer, err)
+	}
+}
+
+// offense classifies an error caused by a peer's message as
+// misbehaviour. Other errors, such as a full pool or a transaction
+// already committed, an honest peer may cause too. Only equivocation
+// proven by a valid certificate bans at once: a block that differs
+// from the stored one but fails the checks is an invalid block.
+func offense(err error) (p2p.Offense, bool) {
+
+	switch {
+	case errors.Is(err, gossip.ErrMalformed):
+		return p2p.MalformedMessage, true
+	case errors.Is(err, errInvalidSignature):
+		return p2p.InvalidSignature, true
+	case errors.Is(err, blocksync.ErrInvalidBlock):
+		return p2p.InvalidBlock, true
+	case errors.Is(err, errEquivocation):
+		return p2p.Equivocation, true
+	case errors.Is(err, blocksync.ErrFalseHeight):
+		return p2p.FalseHeight, true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,464 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/block"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/blocksync"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/chain"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/config"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/consensus"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/gossip"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/identity"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/mempool"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/scheduler"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/simulation"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/state"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/transaction"
+)
+
+var (
+	errCommitted        = errors.New("transaction already committed")
+	errInvalidSignature = errors.New("invalid transaction signature")
+
+	// errSigning marks a validator key that failed to sign, such as a
+	// remote signer timing out; the round is retried
+	errSigning = errors.New("signing failed")
+)
+
+// producer proposes and finalizes blocks with the local validators,
+// always building on the tip of the chain, so a restarted node
+// continues where it stopped and blocks synced from peers are built
+// upon.
+type producer struct {
+	chain  *blocksync.Chain
+	params chain.Params
+	vs     *consensus.ValidatorSet
+	sched  *scheduler.RoundRobinScheduler
+	pool   *mempool.Pool
+	signer crypto.Signer
+	state  *state.State
+	gossip *gossip.Router
+
+	local map[string]*identity.NodeIdentity
+
+	maxTxs       int
+	synthetic    int
+	interval     time.Duration
+	emptyBlocks  bool
+	emptyTimeout time.Duration
+
+	lastBlock time.Time
+
+	// proposeTimeout bounds the retries of a leader failing to sign its
+	// block, and commitTimeout those of the votes on a signed block;
+	// then the height moves on to the next view with a local leader
+	proposeTimeout time.Duration
+	commitTimeout  time.Duration
+
+	// height and view are the round in progress, started at
+	// roundStart, and signedAt is when its block was signed
+	height     int
+	view       int
+	roundStart time.Time
+	signedAt   time.Time
+
+	// proposal is the block signed for the next height while it waits
+	// for a quorum, with the pool transactions it took. It is proposed
+	// again rather than signing a different block at the same height
+	// and view, which a remote signer would refuse.
+	proposal    *block.Block
+	proposalTxs []*transaction.Transaction
+
+	// load signs synthetic transactions for leaders whose key is held
+	// by a remote signer, which only signs blocks and votes
+	load *identity.NodeIdentity
+
+	// waiting is the last reason no block could be produced, so it is
+	// logged once rather than on every tick
+	waiting string
+}
+
+func newProducer(
+	cfg *config.NodeConfig,
+	g *config.Genesis,
+	params chain.Params,
+	bc *blocksync.Chain,
+	vs *consensus.ValidatorSet,
+	sched *scheduler.RoundRobinScheduler,
+	pool *mempool.Pool,
+	signer crypto.Signer,
+	st *state.State,
+	validators []*identity.NodeIdentity,
+) *producer {
+
+	p := &producer{
+		chain:        bc,
+		params:       params,
+		vs:           vs,
+		sched:        sched,
+		pool:         pool,
+		signer:       signer,
+		state:        st,
+		local:        make(map[string]*identity.NodeIdentity),
+		maxTxs:       params.MaxBlockTxs,
+		synthetic:    cfg.Block.SyntheticTxs,
+		interval:     time.Duration(cfg.Block.Interval),
+		emptyBlocks:  cfg.Block.EmptyBlocks,
+		emptyTimeout: time.Duration(cfg.Block.EmptyBlockInterval),
+	}
+
+	if cfg.Block.MaxTxs > 0 {
+		p.maxTxs = cfg.Block.MaxTxs
+	}
+
+	p.proposeTimeout, p.commitTimeout = cfg.Timeouts(g)
+
+	for _, v := range validators {
+		p.local[v.NodeID] = v
+	}
+
+	if tip := bc.Tip(); tip.Index > 0 {
+		p.lastBlock = time.Unix(tip.Timestamp, 0)
+	}
+
+	return p
+}
+
+// run produces blocks until ctx is cancelled. A block in progress is
+// always finished, so shutdown never leaves a half-written block. Only
+// a failure to store a block stops it.
+func (p *producer) run(ctx context.Context) error {
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		ticked := false
+
+		select {
+
+		case <-ctx.Done():
+			return nil
+
+		case <-timer.C:
+			ticked = true
+
+		case <-p.pool.Notify():
+			// Only waiting for transactions needs an early wake-up
+			if p.emptyBlocks {
+				continue
+			}
+		}
+
+		if p.due() {
+			if err := p.produce(); err != nil {
+				return err
+			}
+		}
+
+		// Re-arm after producing, so a slow block does not eat into
+		// the next interval
+		if ticked {
+			timer.Reset(p.interval)
+		}
+	}
+}
+
+// due applies the empty-block policy.
+func (p *producer) due() bool {
+
+	since := time.Since(p.lastBlock)
+
+	if since < p.interval {
+		return false
+	}
+
+	if p.emptyBlocks || p.synthetic > 0 || p.pool.Len() > 0 {
+		return true
+	}
+
+	return p.emptyTimeout > 0 && since >= p.emptyTimeout
+}
+
+// produce proposes the next block on the tip, collects the local
+// validators' votes and commits it.
+func (p *producer) produce() error {
+
+	start := time.Now()
+
+	var pooled []*transaction.Transaction
+	var commit *consensus.Certificate
+
+	b, err := p.chain.Extend(func(tip *block.Block) (*block.Block, *consensus.Certificate, error) {
+
+		b, reaped, err := p.propose(tip)
+		if err != nil || b == nil {
+			return nil, nil, err
+		}
+
+		pooled = reaped
+
+		commit, err = p.vote(b, b.View)
+		if err != nil || commit == nil {
+			return nil, nil, err
+		}
+
+		return b, commit, nil
+	})
+
+	switch {
+
+	// The same proposal is retried on the next tick
+	case errors.Is(err, errSigning):
+		p.wait(err.Error())
+		return nil
+
+	// A block of this node's own that fails validation is dropped
+	case errors.Is(err, blocksync.ErrInvalidBlock):
+		p.proposal, p.proposalTxs = nil, nil
+		p.wait(fmt.Sprintf("proposal rejected: %v", err))
+		return nil
+
+	case err != nil:
+		return err
+
+	case b == nil:
+		return nil
+	}
+
+	p.proposal, p.proposalTxs = nil, nil
+
+	p.pool.Remove(pooled)
+	p.lastBlock = time.Now()
+	p.waiting = ""
+
+	// Peers append it without waiting for block sync
+	if err := p.gossip.Broadcast(gossip.NewProposal(b, commit)); err != nil {
+		log.Println("gossip:", err)
+	}
+
+	log.Printf("Committed block %d by %s: %d txs, hash %x (%s)", b.Index, b.Validator, len(b.Transactions), b.Hash, time.Since(start).Round(time.Millisecond))
+
+	return nil
+}
+
+// propose builds and signs the block after tip when a local validator
+// leads its height. It also returns the pool transactions it took.
+func (p *producer) propose(tip *block.Block) (*block.Block, []*transaction.Transaction, error) {
+
+	// The genesis anchor carries the genesis hash
+	previousHash := tip.Hash
+
+	next := tip.Index + 1
+
+	if alg := p.params.AlgorithmAt(next); alg != "" && alg != p.signer.Algorithm() {
+		return nil, nil, fmt.Errorf("chain switches to %s at height %d: restart the node to load the new keys", alg, next)
+	}
+
+	// A new height starts at view 0
+	if p.height != next {
+		p.height, p.view, p.roundStart = next, 0, time.Now()
+	}
+
+	leader, view, err := p.leader(next, p.view)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if leader == nil {
+		p.wait("no local validator keys: following the peers")
+		return nil, nil, nil
+	}
+
+	if view != p.view {
+		p.view, p.roundStart = view, time.Now()
+	}
+
+	if b := p.proposal; b != nil && b.Index == next && b.View == view && bytes.Equal(b.PreviousHash, previousHash) {
+		return b, p.proposalTxs, nil
+	}
+
+	pooled := p.pool.Reap(p.maxTxs)
+
+	// Blocks synced from peers may have committed some already
+	var txs []*transaction.Transaction
+	for _, tx := range pooled {
+		if _, committed := p.state.Record(tx.DataHash); !committed {
+			txs = append(txs, tx)
+		}
+	}
+
+	if n := p.synthetic; n > 0 {
+
+		if p.maxTxs > 0 && len(txs)+n > p.maxTxs {
+			n = p.maxTxs - len(txs)
+		}
+
+		sender := leader
+		if leader.Backend != nil {
+			if p.load == nil {
+				if p.load, err = identity.NewNodeIdentity("synthetic-load", p.signer); err != nil {
+					return nil, nil, err
+				}
+			}
+			sender = p.load
+		}
+
+		generated, err := simulation.GenerateSyntheticDataset(n, sender, p.params)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		txs = append(txs[:len(txs):len(txs)], generated...)
+	}
+
+	b := block.NewBlock(next, view, previousHash, txs)
+
+	if p.params.CommitsState(next) {
+		if b.StateRoot, err = p.state.RootAfter(b, p.params.Hasher); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := b.FinalizeWith(leader, p.params); err != nil {
+		err = fmt.Errorf("%w: %s block %d: %v", errSigning, leader.NodeID, next, err)
+		p.timeout(err, p.roundStart, p.proposeTimeout)
+		return nil, nil, err
+	}
+
+	p.proposal, p.proposalTxs = b, pooled
+	p.signedAt = time.Now()
+
+	return b, pooled, nil
+}
+
+// leader returns the local validator leading height in the lowest view
+// from view on that one does. Votes are not exchanged between nodes, so
+// the node holding a quorum of the keys produces every block, and a
+// height whose leader is not local moves on to the next view rather
+// than stall.
+func (p *producer) leader(height, view int) (*identity.NodeIdentity, int, error) {
+
+	for end := view + p.vs.Count(); view < end; view++ {
+
+		id, err := p.sched.GetLeader(height, view)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if v := p.local[id]; v != nil {
+			return v, view, nil
+		}
+	}
+
+	return nil, 0, nil
+}
+
+// vote runs the prepare and commit phases with the local validators
+// and returns the certificate of their signed commit votes, or nil
+// without a quorum. A validator that fails to sign its commit vote is
+// left out, and the block still commits if the others reach a quorum.
+// Votes are per height, so each block gets a fresh pool.
+func (p *producer) vote(b *block.Block, view int) (*consensus.Certificate, error) {
+
+	votePool := consensus.NewVotePool(p.vs)
+	blockHash := fmt.Sprintf("%x", b.Hash)
+	commit := consensus.NewCertificate(b.Index, view, b.Hash)
+
+	var failed error
+
+	for _, phase := range []consensus.VoteType{consensus.Prepare, consensus.Commit} {
+
+		for _, v := range p.local {
+
+			if phase == consensus.Commit {
+				if err := commit.Sign(v, p.params); err != nil {
+					failed = fmt.Errorf("%w: %s commit vote for block %d: %v", errSigning, v.NodeID, b.Index, err)
+					continue
+				}
+			}
+
+			_ = votePool.AddVote(consensus.Vote{
+				ValidatorID: v.NodeID,
+				BlockHash:   blockHash,
+				View:        view,
+				Type:        phase,
+			})
+		}
+
+		if votePool.HasQuorum(blockHash, view, phase) {
+			continue
+		}
+
+		if failed != nil {
+			p.timeout(failed, p.signedAt, p.commitTimeout)
+			return nil, failed
+		}
+
+		p.wait(fmt.Sprintf("local validators hold no quorum for height %d", b.Index))
+		return nil, nil
+	}
+
+	return commit, nil
+}
+
+// timeout moves the round on to the next view once err has kept it
+// from progressing for longer than limit since start. The proposal is
+// dropped, and the next local leader signs a new one.
+func (p *producer) timeout(err error, start time.Time, limit time.Duration) {
+
+	if time.Since(start) < limit {
+		return
+	}
+
+	log.Printf("Block production: height %d view %d timed out after %s: %v", p.height, p.view, limit, err)
+
+	p.view++
+	p.roundStart = time.Now()
+	p.proposal, p.proposalTxs = nil, nil
+}
+
+func (p *producer) wait(reason string) {
+	if reason != p.waiting {
+		log.Println("Block production:", reason)
+		p.waiting = reason
+	}
+}
+
+// admit checks a submitted transaction and queues it for a block. It
+// must be signed for this chain with the algorithm blocks are signed
+// with; the verified signature is cached, so the block check later
+// does not verify it again.
+func (p *producer) admit(tx *transaction.Transaction) error {
+
+	if tx.SenderID == "" || tx.DataHash == "" {
+		return errors.New("sender_id and data_hash must be set")
+	}
+
+	// Rotations change the validator set and go through the ledger
+	if tx.IsKeyRotation() {
+		return errors.New("key rotations cannot be submitted through the API")
+	}
+
+	if tx.Algorithm != p.signer.Algorithm() {
+		return fmt.Errorf("chain accepts %s transactions, got %q", p.signer.Algorithm(), tx.Algorithm)
+	}
+
+	valid, err := tx.VerifyWith(p.signer, p.params)
+	if err != nil || !valid {
+		return errInvalidSignature
+	}
+
+	if _, committed := p.state.Record(tx.DataHash); committed {
+		return errCommitted
+	}
+
+	return p.pool.Add(tx)
+}
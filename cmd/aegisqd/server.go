@@ -2,25 +2,41 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/blocksync"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/config"
 	"github.com/Sai-shashank-2005/aegisq-protocol/core/consensus"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/gossip"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/mempool"
 	"github.com/Sai-shashank-2005/aegisq-protocol/core/scheduler"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/state"
 	"github.com/Sai-shashank-2005/aegisq-protocol/core/storage"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/transaction"
 )
 
-func startServer(
+// maxSubmitBytes bounds a submitted transaction; a Dilithium
+// transaction is under 8 KB of JSON.
+const maxSubmitBytes = 1 << 20
+
+// newServer builds the HTTP API server; the caller runs and shuts it
+// down.
+func newServer(
+	listen string,
 	db *storage.DB,
 	vs *consensus.ValidatorSet,
 	vp *consensus.VotePool,
 	fe *consensus.FinalityEngine,
 	scheduler *scheduler.RoundRobinScheduler,
-) {
+	prod *producer,
+	g *config.Genesis,
+) *http.Server {
 
 	mux := http.NewServeMux()
 
@@ -44,8 +60,15 @@ func startServer(
 		}
 
 		json.NewEncoder(w).Encode(map[string]interface{}{
-			"status": "running",
-			"height": height,
+			"status":          "running",
+			"height":          height,
+			"chain_id":        prod.params.ID,
+			"hash_algorithm":  prod.params.Hasher.Algorithm(),
+			"genesis_hash":    fmt.Sprintf("%x", prod.params.GenesisHash),
+			"pending_txs":     prod.pool.Len(),
+			"signature_cache": crypto.DefaultSignatureCache.Stats(),
+			"peers":           prod.gossip.Peers(),
+			"gossip":          prod.gossip.Stats(),
 		})
 	})
 
@@ -74,8 +97,7 @@ func startServer(
 
 		// quorum calculation
 		n := vs.Count()
-		f := (n - 1) / 3
-		required := 2*f + 1
+		required := consensus.Quorum(n)
 
 		// simulate received votes
 		received := len(block.Transactions)
@@ -202,8 +224,7 @@ func startServer(
 
 		// quorum
 		n := vs.Count()
-		f := (n - 1) / 3
-		required := 2*f + 1
+		required := consensus.Quorum(n)
 
 		received := len(block.Transactions)
 		if received > n {
@@ -288,9 +309,236 @@ func startServer(
 		})
 	})
 
-	fmt.Println("🚀 API server running on http://localhost:8080")
+	// ---------------------------
+	// SUBMIT TX
+	// ---------------------------
+	mux.HandleFunc("/submit", func(w http.ResponseWriter, r *http.Request) {
+
+		enableCors(&w)
+
+		if r.Method != http.MethodPost {
+			http.Error(w, "POST a signed transaction", 405)
+			return
+		}
+
+		var tx transaction.Transaction
+
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSubmitBytes)).Decode(&tx); err != nil {
+			http.Error(w, "invalid transaction: "+err.Error(), 400)
+			return
+		}
+
+		if err := prod.submit(r.Context(), &tx); err != nil {
+
+			code := 400
+			switch {
+			case errors.Is(err, mempool.ErrDuplicate), errors.Is(err, errCommitted):
+				code = 409
+			case errors.Is(err, mempool.ErrPoolFull), errors.Is(err, gossip.ErrBusy), errors.Is(err, gossip.ErrClosed):
+				code = 503
+			}
+
+			http.Error(w, err.Error(), code)
+			return
+		}
+
+		w.WriteHeader(202)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data_hash":   tx.DataHash,
+			"status":      "pending",
+			"pending_txs": prod.pool.Len(),
+		})
+	})
+
+	// ---------------------------
+	// MERKLE PROOF BY TX HASH
+	// ---------------------------
+	mux.HandleFunc("/proof/", func(w http.ResponseWriter, r *http.Request) {
+
+		enableCors(&w)
+
+		hash := strings.TrimPrefix(r.URL.Path, "/proof/")
+
+		block, index, err := db.GetTransactionByHash(hash)
+		if err != nil {
+			http.Error(w, err.Error(), 404)
+			return
+		}
+
+		leaf, proof, err := block.TransactionProof(index, prod.params.Hasher)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+
+		steps := []map[string]interface{}{}
+
+		for _, step := range proof {
+			side := "right"
+			if step.Left {
+				side = "left"
+			}
+
+			steps = append(steps, map[string]interface{}{
+				"hash": fmt.Sprintf("%x", step.Hash),
+				"side": side,
+			})
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data_hash":      hash,
+			"block_height":   block.Index,
+			"block_hash":     fmt.Sprintf("%x", block.Hash),
+			"tx_index":       index,
+			"tx_hash":        fmt.Sprintf("%x", leaf),
+			"merkle_root":    fmt.Sprintf("%x", block.MerkleRoot),
+			"hash_algorithm": prod.params.Hasher.Algorithm(),
+			"proof":          steps,
+		})
+	})
+
+	// ---------------------------
+	// STATE SNAPSHOTS
+	// ---------------------------
+	mux.HandleFunc("/snapshots", func(w http.ResponseWriter, r *http.Request) {
+
+		enableCors(&w)
+
+		snapshots, err := db.Snapshots()
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+
+		if snapshots == nil {
+			snapshots = []*state.Manifest{}
+		}
+
+		json.NewEncoder(w).Encode(snapshots)
+	})
+
+	// /snapshots/{height}, /snapshots/{height}/block and
+	// /snapshots/{height}/chunks/{index}
+	mux.HandleFunc("/snapshots/", func(w http.ResponseWriter, r *http.Request) {
+
+		enableCors(&w)
+
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/snapshots/"), "/")
+
+		height, err := strconv.ParseUint(parts[0], 10, 64)
+		if err != nil {
+			http.Error(w, "invalid height", 400)
+			return
+		}
+
+		m, err := db.Snapshot(height)
+		if errors.Is(err, storage.ErrSnapshotNotFound) {
+			http.Error(w, err.Error(), 404)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+
+		switch {
+
+		case len(parts) == 1:
+			json.NewEncoder(w).Encode(m)
+
+		// The full block, whose header commits to the state root,
+		// with the certificate that finalized it
+		case len(parts) == 2 && parts[1] == "block":
+
+			b, err := db.GetBlock(height)
+			if err != nil {
+				http.Error(w, err.Error(), 500)
+				return
+			}
+
+			commit, err := db.Commit(height)
+			if errors.Is(err, storage.ErrCommitNotFound) {
+				http.Error(w, err.Error(), 404)
+				return
+			}
+			if err != nil {
+				http.Error(w, err.Error(), 500)
+				return
+			}
+
+			json.NewEncoder(w).Encode(blocksync.Committed{Block: b, Commit: commit})
+
+		case len(parts) == 3 && parts[1] == "chunks":
+
+			index, err := strconv.Atoi(parts[2])
+			if err != nil || index < 0 || index >= len(m.Chunks) {
+				http.Error(w, "invalid chunk index", 400)
+				return
+			}
+
+			// The snapshot may have been pruned meanwhile
+			chunk, err := db.SnapshotChunk(height, index)
+			if errors.Is(err, storage.ErrSnapshotNotFound) {
+				http.Error(w, err.Error(), 404)
+				return
+			}
+			if err != nil {
+				http.Error(w, err.Error(), 500)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(chunk)
+
+		default:
+			http.Error(w, "usage: /snapshots/{height}[/block|/chunks/{index}]", 400)
+		}
+	})
+
+	// ---------------------------
+	// VALIDATORS
+	// ---------------------------
+	mux.HandleFunc("/validators", func(w http.ResponseWriter, r *http.Request) {
+
+		enableCors(&w)
+
+		height, err := db.GetLatestHeight()
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+
+		next := int(height + 1)
+		leader, _ := scheduler.GetLeader(next, 0)
+
+		validators := []map[string]interface{}{}
+
+		for _, v := range g.Validators {
+
+			key, _ := vs.KeyAt(v.NodeID, next)
+
+			validators = append(validators, map[string]interface{}{
+				"node_id":          v.NodeID,
+				"algorithm":        v.Algorithm,
+				"power":            v.Power,
+				"public_key":       key,
+				"pending_rotation": vs.PendingRotation(v.NodeID, next),
+				"local":            prod.local[v.NodeID] != nil,
+			})
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"height":      height,
+			"next_leader": leader,
+			"validators":  validators,
+		})
+	})
 
-	log.Fatal(http.ListenAndServe(":8080", mux))
+	return &http.Server{
+		Addr:              listen,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
 }
 
 func enableCors(w *http.ResponseWriter) {
@@ -0,0 +1,482 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/block"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/blocksync"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/chain"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/config"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/consensus"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/ledger"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/scheduler"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/state"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/storage"
+)
+
+// loadState rebuilds the application state at the database tip: from
+// the latest stored snapshot at or below it, or from genesis, then
+// replaying the blocks after.
+func loadState(db *storage.DB, g *config.Genesis, params chain.Params) (*state.State, error) {
+
+	tip, err := db.GetLatestHeight()
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots, err := db.Snapshots()
+	if err != nil {
+		return nil, err
+	}
+
+	var st *state.State
+
+	for i := len(snapshots) - 1; i >= 0 && st == nil; i-- {
+
+		m := snapshots[i]
+		if m.Height > tip {
+			continue
+		}
+
+		if st, err = storedSnapshot(db, m, params.Hasher); err != nil {
+			return nil, fmt.Errorf("snapshot %d: %w", m.Height, err)
+		}
+	}
+
+	if st == nil {
+
+		base, err := db.Base()
+		if err != nil {
+			return nil, err
+		}
+
+		if base > 0 {
+			return nil, fmt.Errorf("database starts at height %d, but its snapshot is missing", base)
+		}
+
+		if st, err = g.State(); err != nil {
+			return nil, err
+		}
+	}
+
+	for h := st.Height() + 1; h <= tip; h++ {
+
+		b, err := db.GetBlock(h)
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %w", h, err)
+		}
+
+		if err := st.Apply(b); err != nil {
+			return nil, err
+		}
+	}
+
+	return st, nil
+}
+
+// storedSnapshot restores the snapshot m from the database.
+func storedSnapshot(db *storage.DB, m *state.Manifest, h crypto.Hasher) (*state.State, error) {
+
+	r, err := state.NewRestorer(m, h)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range m.Chunks {
+
+		chunk, err := db.SnapshotChunk(m.Height, i)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := r.Add(i, chunk); err != nil {
+			return nil, err
+		}
+	}
+
+	return r.Finish()
+}
+
+// runStateSync starts a fresh database from a peer's state snapshot
+// instead of replaying the chain: the snapshot is rebuilt from chunks
+// checked against their hashes, and its root must match the state root
+// in the header of a block finalized by a quorum under the genesis
+// keys, or matching -trust-hash.
+func runStateSync(args []string) error {
+
+	fs := flag.NewFlagSet("statesync", flag.ContinueOnError)
+	sources := fs.String("sources", "", "comma-separated API URLs of nodes serving snapshots (required)")
+	height := fs.Uint64("height", 0, "snapshot height (default: the latest offered)")
+	trustHash := fs.String("trust-hash", "", "hash of the snapshot block, from a node you trust")
+
+	cfg, err := parseNodeFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 || *sources == "" {
+		return errors.New("usage: aegisqd statesync -sources URL[,URL...] [-height H] [-trust-hash HEX]")
+	}
+
+	g, err := loadGenesis(cfg.Path(cfg.GenesisFile))
+	if err != nil {
+		return err
+	}
+
+	if g == nil {
+		return fmt.Errorf("state sync needs the chain's genesis file (%s)", cfg.GenesisFile)
+	}
+
+	params, err := g.Params()
+	if err != nil {
+		return err
+	}
+
+	if params.StateRootInterval == 0 {
+		return errors.New("the chain commits no state roots (genesis state_root_interval)")
+	}
+
+	var trusted []byte
+	if *trustHash != "" {
+		if trusted, err = hex.DecodeString(*trustHash); err != nil {
+			return fmt.Errorf("-trust-hash: %w", err)
+		}
+	}
+
+	var peers []*snapshotSource
+	for _, peer := range config.SplitList(*sources) {
+		peers = append(peers, newSnapshotSource(peer))
+	}
+
+	db, err := storage.Open(cfg.Path(cfg.DBPath))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if tip, err := db.GetLatestHeight(); err != nil || tip > 0 {
+		return fmt.Errorf("%s already holds a chain; state sync needs a fresh database", cfg.DBPath)
+	}
+
+	fmt.Println("Chain:", params.ID, "hash:", params.Hasher.Algorithm())
+	fmt.Printf("Genesis: %x\n", params.GenesisHash)
+
+	// 1️⃣ Pick a snapshot and the block it was taken at
+	m, b, commit, err := pickSnapshot(peers, params, *height)
+	if err != nil {
+		return err
+	}
+
+	if err := verifySnapshotBlock(g, params, b, commit, trusted); err != nil {
+		return err
+	}
+
+	if string(m.BlockHash) != string(b.Hash) || string(m.StateRoot) != string(b.StateRoot) {
+		return fmt.Errorf("snapshot %d does not match the state root of its block", m.Height)
+	}
+
+	fmt.Printf("Snapshot: height %d, %d entries in %d chunks, state root %x\n", m.Height, m.Entries, len(m.Chunks), m.StateRoot)
+
+	// 2️⃣ Fetch the chunks, each checked against the manifest
+	r, err := state.NewRestorer(m, params.Hasher)
+	if err != nil {
+		return err
+	}
+
+	chunks := make([][]byte, len(m.Chunks))
+
+	for i := range m.Chunks {
+
+		chunk, err := fetchChunk(peers, r, m.Height, i)
+		if err != nil {
+			return err
+		}
+
+		chunks[i] = chunk
+	}
+
+	// 3️⃣ The rebuilt state must have the committed root
+	st, err := r.Finish()
+	if err != nil {
+		return err
+	}
+
+	// 4️⃣ Start the database at the snapshot
+	if err := db.UseHasher(params.Hasher); err != nil {
+		return err
+	}
+
+	if err := db.UseGenesis(params.GenesisHash); err != nil {
+		return err
+	}
+
+	doc, err := json.Marshal(g)
+	if err != nil {
+		return err
+	}
+
+	if err := db.SaveGenesisDocument(doc); err != nil {
+		return err
+	}
+
+	if err := db.RestoreSnapshot(m, chunks, b); err != nil {
+		return err
+	}
+
+	fmt.Printf("State synced at height %d: %d records. The node continues from block %d.\n", st.Height(), st.Records(), st.Height()+1)
+
+	return nil
+}
+
+// pickSnapshot returns the snapshot at height, or the latest offered by
+// any source, with its block and the block's commit certificate.
+func pickSnapshot(sources []*snapshotSource, params chain.Params, height uint64) (*state.Manifest, *block.Block, *consensus.Certificate, error) {
+
+	var best *state.Manifest
+	var from *snapshotSource
+
+	for _, src := range sources {
+
+		var offered []*state.Manifest
+		if err := src.get("/snapshots", &offered); err != nil {
+			fmt.Println("Skipping", src.base+":", err)
+			continue
+		}
+
+		for _, m := range offered {
+
+			if height > 0 && m.Height != height {
+				continue
+			}
+
+			if !params.CommitsState(int(m.Height)) {
+				continue
+			}
+
+			if best == nil || m.Height > best.Height {
+				best, from = m, src
+			}
+		}
+	}
+
+	if best == nil {
+		return nil, nil, nil, errors.New("no peer offers a usable snapshot")
+	}
+
+	var c blocksync.Committed
+	if err := from.get(fmt.Sprintf("/snapshots/%d/block", best.Height), &c); err != nil {
+		return nil, nil, nil, err
+	}
+
+	if c.Block == nil || uint64(c.Block.Index) != best.Height {
+		return nil, nil, nil, fmt.Errorf("%s: served no block %d for snapshot %d", from.base, best.Height, best.Height)
+	}
+
+	return best, c.Block, c.Commit, nil
+}
+
+// verifySnapshotBlock checks the block a snapshot was taken at: its
+// hash must be the trusted one or, without one, it must verify under
+// the genesis key of its scheduled leader and commit must hold the
+// votes of a quorum under their genesis keys, so no single validator
+// can vouch for a state root. The state root in its header then
+// vouches for the snapshot.
+func verifySnapshotBlock(g *config.Genesis, params chain.Params, b *block.Block, commit *consensus.Certificate, trusted []byte) error {
+
+	if trusted != nil {
+
+		if string(b.Hash) != string(trusted) {
+			return fmt.Errorf("block %d is %x, not the trusted hash", b.Index, b.Hash)
+		}
+
+		fmt.Printf("Block %d matches the trusted hash\n", b.Index)
+		return nil
+	}
+
+	vs, err := g.ValidatorSet()
+	if err != nil {
+		return err
+	}
+
+	leader, err := scheduler.NewRoundRobinScheduler(vs).GetLeader(b.Index, b.View)
+	if err != nil {
+		return err
+	}
+
+	if b.Validator != leader {
+		return fmt.Errorf("block %d was produced by %s, not its scheduled leader %s", b.Index, b.Validator, leader)
+	}
+
+	v, _ := g.Validator(leader)
+
+	alg := params.AlgorithmAt(b.Index)
+	if alg == "" {
+		alg = v.Algorithm
+	}
+
+	verifier, err := crypto.Verifier(alg)
+	if err != nil {
+		return err
+	}
+
+	valid, err := b.VerifyWith(verifier, v.PublicKey, params)
+	if err != nil || !valid {
+		return fmt.Errorf("block %d does not verify under the genesis key of %s; if keys rotated since genesis, pass -trust-hash with the block hash from a node you trust", b.Index, leader)
+	}
+
+	cv, _, err := genesisVerifier(g)
+	if err != nil {
+		return err
+	}
+
+	if err := cv.VerifyCommit(b, commit); err != nil {
+		return fmt.Errorf("%v; if keys rotated since genesis, pass -trust-hash with the block hash from a node you trust", err)
+	}
+
+	fmt.Printf("Block %d verifies under the genesis key of %s, finalized by %d validators\n", b.Index, leader, len(commit.Votes))
+	return nil
+}
+
+// fetchChunk gets chunk index of the snapshot at height from the first
+// source serving it intact.
+func fetchChunk(sources []*snapshotSource, r *state.Restorer, height uint64, index int) ([]byte, error) {
+
+	var errs []string
+
+	for _, src := range sources {
+
+		chunk, err := src.chunk(height, index)
+		if err == nil {
+			err = r.Add(index, chunk)
+		}
+
+		if err == nil {
+			return chunk, nil
+		}
+
+		errs = append(errs, src.base+": "+err.Error())
+	}
+
+	return nil, fmt.Errorf("chunk %d: %s", index, strings.Join(errs, "; "))
+}
+
+// snapshotSource is a node serving snapshots over its API.
+type snapshotSource struct {
+	base string
+	http *http.Client
+}
+
+func newSnapshotSource(peer string) *snapshotSource {
+
+	if !strings.Contains(peer, "://") {
+		peer = "http://" + peer
+	}
+
+	return &snapshotSource{
+		base: strings.TrimRight(peer, "/"),
+		http: &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+func (s *snapshotSource) fetch(path string, limit int64) ([]byte, error) {
+
+	resp, err := s.http.Get(s.base + path)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("response exceeds %d bytes", limit)
+	}
+
+	return data, nil
+}
+
+func (s *snapshotSource) get(path string, out interface{}) error {
+
+	data, err := s.fetch(path, 256<<20)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, out)
+}
+
+func (s *snapshotSource) chunk(height uint64, index int) ([]byte, error) {
+	return s.fetch(fmt.Sprintf("/snapshots/%d/chunks/%d", height, index), state.MaxChunkSize)
+}
+
+// tipVerifier returns a verifier at the tip of the chain in db, the
+// block st was reached at, tracking st.
+func tipVerifier(db *storage.DB, params chain.Params, st *state.State) (*ledger.ChainVerifier, error) {
+
+	if st.Height() > 0 {
+
+		tip, err := db.GetBlock(st.Height())
+		if err != nil {
+			return nil, err
+		}
+
+		return ledger.NewChainVerifierAt(params, st, tip)
+	}
+
+	vs, algorithms, err := st.ValidatorSet()
+	if err != nil {
+		return nil, err
+	}
+
+	v := ledger.NewChainVerifier(params, vs, algorithms)
+	v.TrackState(st)
+
+	return v, nil
+}
+
+// takeSnapshots returns a hook that stores a snapshot of st at every
+// block at a multiple of interval, keeping the keep most recent.
+// Snapshots only serve syncing nodes: a failed one is logged.
+func takeSnapshots(db *storage.DB, st *state.State, params chain.Params, interval, keep int) func(b *block.Block) {
+
+	return func(b *block.Block) {
+
+		if b.Index%interval != 0 {
+			return
+		}
+
+		start := time.Now()
+
+		m, chunks := st.Snapshot(params.Hasher, state.DefaultChunkSize)
+		m.BlockHash = b.Hash
+
+		err := db.SaveSnapshot(m, chunks)
+		if err == nil {
+			_, err = db.PruneSnapshots(keep)
+		}
+
+		if err != nil {
+			log.Printf("Snapshot at height %d failed: %v", b.Index, err)
+			return
+		}
+
+		log.Printf("Snapshot at height %d: %d entries in %d chunks (%s)", m.Height, m.Entries, len(m.Chunks), time.Since(start).Round(time.Millisecond))
+	}
+}
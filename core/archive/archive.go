@@ -0,0 +1,249 @@
+// Package archive reads and writes portable chain archives: a gzip
+// stream of JSON lines holding a header with the genesis document,
+// then every block from height 1 in order with its commit
+// certificate, each with a SHA-256 checksum of their encoding.
+//
+// Checksums only catch damage to the archive itself; an imported chain
+// must still be validated block by block against its genesis.
+package archive
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/block"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/consensus"
+)
+
+// Format identifies chain archives; Version is the layout written.
+// Version 2 added the commit certificates.
+const (
+	Format  = "aegisq-chain-archive"
+	Version = 2
+)
+
+// maxLine bounds one encoded block, well above the largest block the
+// consensus limits allow.
+const maxLine = 256 << 20
+
+// Header opens an archive. Height is the number of blocks that follow,
+// so a truncated archive is detected.
+type Header struct {
+	Format        string          `json:"format"`
+	Version       int             `json:"version"`
+	ChainID       string          `json:"chain_id"`
+	HashAlgorithm string          `json:"hash_algorithm"`
+	GenesisHash   []byte          `json:"genesis_hash"`
+	Genesis       json.RawMessage `json:"genesis"`
+	Height        uint64          `json:"height"`
+	Created       time.Time       `json:"created"`
+}
+
+// record is one archived block with its certificate. Checksum covers
+// the block encoding followed by the certificate encoding.
+type record struct {
+	Height   uint64          `json:"height"`
+	Checksum []byte          `json:"checksum"`
+	Block    json.RawMessage `json:"block"`
+	Commit   json.RawMessage `json:"commit"`
+}
+
+func (rec *record) sum() []byte {
+	h := sha256.New()
+	h.Write(rec.Block)
+	h.Write(rec.Commit)
+	return h.Sum(nil)
+}
+
+//
+// ==============================
+// WRITER
+// ==============================
+//
+
+// Writer streams blocks into an archive.
+type Writer struct {
+	gz      *gzip.Writer
+	enc     *json.Encoder
+	height  uint64
+	written uint64
+}
+
+// NewWriter writes h to w and returns a writer for its blocks. Format
+// and Version are filled in.
+func NewWriter(w io.Writer, h Header) (*Writer, error) {
+
+	h.Format = Format
+	h.Version = Version
+
+	gz := gzip.NewWriter(w)
+	enc := json.NewEncoder(gz)
+
+	if err := enc.Encode(h); err != nil {
+		return nil, err
+	}
+
+	return &Writer{gz: gz, enc: enc, height: h.Height}, nil
+}
+
+// WriteBlock appends b, which must be the next height, with the
+// certificate c that committed it.
+func (w *Writer) WriteBlock(b *block.Block, c *consensus.Certificate) error {
+
+	if b.Index < 1 || uint64(b.Index) != w.written+1 {
+		return fmt.Errorf("block %d written after height %d", b.Index, w.written)
+	}
+
+	if w.written == w.height {
+		return fmt.Errorf("archive holds %d blocks", w.height)
+	}
+
+	if c == nil {
+		return fmt.Errorf("block %d: commit certificate missing", b.Index)
+	}
+
+	data, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+
+	commit, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	rec := record{Height: uint64(b.Index), Block: data, Commit: commit}
+	rec.Checksum = rec.sum()
+
+	if err := w.enc.Encode(rec); err != nil {
+		return err
+	}
+
+	w.written++
+	return nil
+}
+
+// Close flushes the archive. Every block announced in the header must
+// have been written. The underlying writer is not closed.
+func (w *Writer) Close() error {
+
+	if err := w.gz.Close(); err != nil {
+		return err
+	}
+
+	if w.written != w.height {
+		return fmt.Errorf("archive announces %d blocks, %d written", w.height, w.written)
+	}
+
+	return nil
+}
+
+//
+// ==============================
+// READER
+// ==============================
+//
+
+// Reader reads blocks back from an archive.
+type Reader struct {
+	Header Header
+
+	gz   *gzip.Reader
+	scan *bufio.Scanner
+	read uint64
+}
+
+// NewReader reads the archive header from r.
+func NewReader(r io.Reader) (*Reader, error) {
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a chain archive: %w", err)
+	}
+
+	scan := bufio.NewScanner(gz)
+	scan.Buffer(nil, maxLine)
+
+	if !scan.Scan() {
+		if err := scan.Err(); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("not a chain archive: empty")
+	}
+
+	var h Header
+	if err := json.Unmarshal(scan.Bytes(), &h); err != nil || h.Format != Format {
+		return nil, errors.New("not a chain archive")
+	}
+
+	if h.Version != Version {
+		return nil, fmt.Errorf("archive version %d is not supported (want %d)", h.Version, Version)
+	}
+
+	return &Reader{Header: h, gz: gz, scan: scan}, nil
+}
+
+// Next returns the next block and its certificate, checked against
+// their checksum, or io.EOF once every block announced in the header
+// has been read. The certificate is not verified.
+func (r *Reader) Next() (*block.Block, *consensus.Certificate, error) {
+
+	if !r.scan.Scan() {
+
+		if err := r.scan.Err(); err != nil {
+			return nil, nil, fmt.Errorf("block %d: %w", r.read+1, err)
+		}
+
+		if r.read != r.Header.Height {
+			return nil, nil, fmt.Errorf("archive truncated: %d of %d blocks", r.read, r.Header.Height)
+		}
+
+		return nil, nil, io.EOF
+	}
+
+	next := r.read + 1
+
+	if r.read == r.Header.Height {
+		return nil, nil, fmt.Errorf("archive holds more than the %d blocks announced", r.Header.Height)
+	}
+
+	var rec record
+	if err := json.Unmarshal(r.scan.Bytes(), &rec); err != nil {
+		return nil, nil, fmt.Errorf("block %d: %w", next, err)
+	}
+
+	if rec.Height != next {
+		return nil, nil, fmt.Errorf("block %d: archive record is height %d", next, rec.Height)
+	}
+
+	if string(rec.sum()) != string(rec.Checksum) {
+		return nil, nil, fmt.Errorf("block %d: checksum mismatch", next)
+	}
+
+	var b block.Block
+	if err := json.Unmarshal(rec.Block, &b); err != nil {
+		return nil, nil, fmt.Errorf("block %d: %w", next, err)
+	}
+
+	if uint64(b.Index) != next {
+		return nil, nil, fmt.Errorf("block %d: archived block is height %d", next, b.Index)
+	}
+
+	if len(rec.Commit) == 0 || string(rec.Commit) == "null" {
+		return nil, nil, fmt.Errorf("block %d: commit certificate missing", next)
+	}
+
+	var c consensus.Certificate
+	if err := json.Unmarshal(rec.Commit, &c); err != nil {
+		return nil, nil, fmt.Errorf("block %d: commit certificate: %w", next, err)
+	}
+
+	r.read = next
+	return &b, &c, nil
+}
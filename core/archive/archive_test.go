@@ -0,0 +1,253 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/block"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/chain"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/consensus"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/identity"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/transaction"
+)
+
+func testBlocks(t *testing.T, count int) []*block.Block {
+
+	p := chain.Default
+	p.GenesisHash = p.Hasher.Hash([]byte("genesis"))
+
+	node, err := identity.NewNodeIdentity("validator-1", &crypto.Ed25519Signer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prev := p.GenesisHash
+	var blocks []*block.Block
+
+	for h := 1; h <= count; h++ {
+
+		tx := transaction.NewTransaction(node, "data", "meta")
+		if err := tx.SignWith(node, p); err != nil {
+			t.Fatal(err)
+		}
+
+		b := block.NewBlock(h, 0, prev, []*transaction.Transaction{tx})
+		if err := b.FinalizeWith(node, p); err != nil {
+			t.Fatal(err)
+		}
+
+		blocks = append(blocks, b)
+		prev = b.Hash
+	}
+
+	return blocks
+}
+
+func writeArchive(t *testing.T, blocks []*block.Block) []byte {
+
+	var buf bytes.Buffer
+
+	w, err := NewWriter(&buf, Header{
+		ChainID:     chain.DefaultID,
+		GenesisHash: []byte("genesis"),
+		Genesis:     json.RawMessage(`{"chain_id":"aegisq-local"}`),
+		Height:      uint64(len(blocks)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The archive carries certificates without checking them
+	for _, b := range blocks {
+		if err := w.WriteBlock(b, consensus.NewCertificate(b.Index, b.View, b.Hash)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+// readAll returns the blocks read before the first error, checking
+// each came with the certificate written for it.
+func readAll(data []byte) ([]*block.Block, error) {
+
+	r, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var blocks []*block.Block
+
+	for {
+		b, c, err := r.Next()
+		if err == io.EOF {
+			return blocks, nil
+		}
+		if err != nil {
+			return blocks, err
+		}
+		if c.Height != b.Index || !bytes.Equal(c.BlockHash, b.Hash) {
+			return blocks, fmt.Errorf("block %d came with the certificate of block %d", b.Index, c.Height)
+		}
+		blocks = append(blocks, b)
+	}
+}
+
+// rewrite decompresses an archive, applies edit to its lines and
+// compresses it again.
+func rewrite(t *testing.T, data []byte, edit func(lines []string) []string) []byte {
+
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plain, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(plain), "\n"), "\n")
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write([]byte(strings.Join(edit(lines), "\n") + "\n"))
+	w.Close()
+
+	return buf.Bytes()
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+
+	blocks := testBlocks(t, 5)
+	data := writeArchive(t, blocks)
+
+	r, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Header.Version != Version || r.Header.Height != 5 || r.Header.ChainID != chain.DefaultID {
+		t.Fatalf("unexpected header: %+v", r.Header)
+	}
+
+	read, err := readAll(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(read) != 5 {
+		t.Fatalf("read %d blocks", len(read))
+	}
+
+	for i, b := range read {
+		if !bytes.Equal(b.Hash, blocks[i].Hash) || len(b.Transactions) != 1 {
+			t.Fatalf("block %d differs", i+1)
+		}
+	}
+}
+
+func TestArchiveEmptyChain(t *testing.T) {
+
+	read, err := readAll(writeArchive(t, nil))
+	if err != nil || len(read) != 0 {
+		t.Fatalf("read %d blocks (%v)", len(read), err)
+	}
+}
+
+func TestWriterRejectsGapsAndShortArchives(t *testing.T) {
+
+	blocks := testBlocks(t, 3)
+
+	w, err := NewWriter(io.Discard, Header{Height: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commit := consensus.NewCertificate(1, 0, blocks[0].Hash)
+
+	if err := w.WriteBlock(blocks[1], commit); err == nil {
+		t.Fatal("block 2 should not be written first")
+	}
+
+	if err := w.WriteBlock(blocks[0], nil); err == nil {
+		t.Fatal("block without a certificate should not be written")
+	}
+
+	if err := w.WriteBlock(blocks[0], commit); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Close(); err == nil {
+		t.Fatal("closing after 1 of 3 blocks should fail")
+	}
+}
+
+func TestReaderRejectsDamage(t *testing.T) {
+
+	data := writeArchive(t, testBlocks(t, 4))
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"truncated stream", data[:len(data)/2]},
+		{"missing blocks", rewrite(t, data, func(l []string) []string { return l[:3] })},
+		{"extra block", rewrite(t, data, func(l []string) []string { return append(l, l[4]) })},
+		{"reordered blocks", rewrite(t, data, func(l []string) []string { l[2], l[3] = l[3], l[2]; return l })},
+		{"corrupted block", rewrite(t, data, func(l []string) []string {
+			l[2] = strings.Replace(l[2], `"data`, `"date`, 1)
+			return l
+		})},
+		{"corrupted certificate", rewrite(t, data, func(l []string) []string {
+			l[2] = strings.Replace(l[2], `"commit":{"height":2`, `"commit":{"height":3`, 1)
+			return l
+		})},
+		{"missing certificate", rewrite(t, data, func(l []string) []string {
+			l[2] = l[2][:strings.Index(l[2], `,"commit"`)] + "}"
+			return l
+		})},
+	}
+
+	for _, test := range tests {
+		if _, err := readAll(test.data); err == nil {
+			t.Fatalf("%s: archive should be rejected", test.name)
+		}
+	}
+}
+
+func TestReaderRejectsOtherFormats(t *testing.T) {
+
+	if _, err := NewReader(strings.NewReader("plain text")); err == nil {
+		t.Fatal("uncompressed input should be rejected")
+	}
+
+	data := rewrite(t, writeArchive(t, nil), func(l []string) []string {
+		l[0] = strings.Replace(l[0], `"version":2`, `"version":3`, 1)
+		return l
+	})
+
+	if _, err := NewReader(bytes.NewReader(data)); err == nil || !strings.Contains(err.Error(), "version 3") {
+		t.Fatalf("future version should be rejected, got %v", err)
+	}
+
+	// Version 1 archives carry no certificates
+	data = rewrite(t, writeArchive(t, nil), func(l []string) []string {
+		l[0] = strings.Replace(l[0], `"version":2`, `"version":1`, 1)
+		return l
+	})
+
+	if _, err := NewReader(bytes.NewReader(data)); err == nil || !strings.Contains(err.Error(), "version 1") {
+		t.Fatalf("version 1 should be rejected, got %v", err)
+	}
+}
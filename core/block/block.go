@@ -5,6 +5,7 @@ import (
 	"errors"
 	"time"
 
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/chain"
 	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
 	"github.com/Sai-shashank-2005/aegisq-protocol/core/identity"
 	"github.com/Sai-shashank-2005/aegisq-protocol/core/transaction"
@@ -16,6 +17,7 @@ type Block struct {
 	Timestamp    int64
 	PreviousHash []byte
 	MerkleRoot   []byte
+	StateRoot    []byte `json:",omitempty"`
 	Transactions []*transaction.Transaction
 	Hash         []byte
 	Validator    string
@@ -32,24 +34,27 @@ func NewBlock(index int, view int, prevHash []byte, txs []*transaction.Transacti
 	}
 }
 
-func (b *Block) computeBlockHash() ([]byte, error) {
+func (b *Block) computeBlockHash(h crypto.Hasher) ([]byte, error) {
 
 	if b.MerkleRoot == nil {
 		return nil, errors.New("merkle root not set")
 	}
 
+	// Blocks without a state root hash as before state roots existed
 	header := struct {
 		Index        int
 		View         int
 		Timestamp    int64
 		PreviousHash []byte
 		MerkleRoot   []byte
+		StateRoot    []byte `json:",omitempty"`
 	}{
 		Index:        b.Index,
 		View:         b.View,
 		Timestamp:    b.Timestamp,
 		PreviousHash: b.PreviousHash,
 		MerkleRoot:   b.MerkleRoot,
+		StateRoot:    b.StateRoot,
 	}
 
 	bytes, err := json.Marshal(header)
@@ -57,28 +62,26 @@ func (b *Block) computeBlockHash() ([]byte, error) {
 		return nil, err
 	}
 
-	return crypto.Hash(bytes), nil
+	return h.Hash(bytes), nil
 }
 
+// Finalize seals the block for the default local chain.
 func (b *Block) Finalize(node *identity.NodeIdentity) error {
+	return b.FinalizeWith(node, chain.Default)
+}
 
-	if len(b.Transactions) == 0 {
-		return errors.New("block must contain transactions")
-	}
-
-	var txHashes [][]byte
+// FinalizeWith computes the Merkle root and header hash with the
+// chain's hash and signs the header hash in its BLOCK domain.
+func (b *Block) FinalizeWith(node *identity.NodeIdentity, p chain.Params) error {
 
-	for _, tx := range b.Transactions {
-		hash, err := tx.Hash()
-		if err != nil {
-			return err
-		}
-		txHashes = append(txHashes, hash)
+	txHashes, err := hashTransactions(b.Transactions, p.Hasher)
+	if err != nil {
+		return err
 	}
 
-	b.MerkleRoot = ComputeMerkleRoot(txHashes)
+	b.MerkleRoot = ComputeMerkleRootWith(p.Hasher, txHashes)
 
-	hash, err := b.computeBlockHash()
+	hash, err := b.computeBlockHash(p.Hasher)
 	if err != nil {
 		return err
 	}
@@ -86,7 +89,7 @@ func (b *Block) Finalize(node *identity.NodeIdentity) error {
 	b.Hash = hash
 	b.Validator = node.NodeID
 
-	signature, err := node.Sign(hash)
+	signature, err := node.SignAt(p.Domain(crypto.MessageBlock), b.Index, b.View, hash)
 	if err != nil {
 		return err
 	}
@@ -96,38 +99,108 @@ func (b *Block) Finalize(node *identity.NodeIdentity) error {
 	return nil
 }
 
+// Verify checks the block against the default local chain.
 func (b *Block) Verify(signer crypto.Signer, publicKey []byte) (bool, error) {
+	return b.VerifyWith(signer, publicKey, chain.Default)
+}
+
+// VerifyWith checks transaction signatures, Merkle root, header hash
+// and block signature, recomputing every digest with the chain's hash
+// and checking signatures in the chain's TX and BLOCK domains.
+func (b *Block) VerifyWith(signer crypto.Signer, publicKey []byte, p chain.Params) (bool, error) {
 
 	if b.Hash == nil {
 		return false, errors.New("block hash missing")
 	}
 
-	// 1️⃣ Verify each transaction signature
-	for _, tx := range b.Transactions {
-		valid, err := tx.Verify(signer)
-		if err != nil || !valid {
+	// Algorithm migration: block and transactions must be signed with
+	// the algorithm the chain requires at this height
+	if alg := p.AlgorithmAt(b.Index); alg != "" && signer.Algorithm() != alg {
+		return false, nil
+	}
+
+	h := p.Hasher
+
+	txCtx, err := p.Domain(crypto.MessageTx).Context()
+	if err != nil {
+		return false, err
+	}
+
+	blockCtx, err := p.Domain(crypto.MessageBlock).Context()
+	if err != nil {
+		return false, err
+	}
+
+	// 1️⃣ Hash every transaction once, in parallel
+	txHashes, err := hashTransactions(b.Transactions, h)
+	if err != nil {
+		return false, nil
+	}
+
+	// 2️⃣ Verify all transaction signatures as one parallel batch,
+	// skipping those already in the signature cache
+	items := make([]crypto.BatchItem, 0, len(b.Transactions))
+
+	// Incoming keys of rotations to another algorithm are checked
+	// separately with a verifier for that algorithm
+	var migrating []*transaction.Transaction
+	var migratingItems []crypto.BatchItem
+
+	for i, tx := range b.Transactions {
+		if tx.Algorithm != signer.Algorithm() {
 			return false, nil
 		}
-	}
 
-	// 2️⃣ Recompute Merkle root
-	var txHashes [][]byte
-	for _, tx := range b.Transactions {
-		hash, err := tx.Hash()
+		items = append(items, crypto.BatchItem{
+			PublicKey: tx.PublicKey,
+			Message:   txHashes[i],
+			Signature: tx.Signature,
+			Context:   txCtx,
+		})
+
+		if !tx.IsKeyRotation() {
+			continue
+		}
+
+		item, err := tx.RotationItem(p, txHashes[i])
 		if err != nil {
 			return false, err
 		}
-		txHashes = append(txHashes, hash)
+
+		if tx.NewKeyAlgorithm() == signer.Algorithm() {
+			items = append(items, item)
+		} else {
+			migrating = append(migrating, tx)
+			migratingItems = append(migratingItems, item)
+		}
+	}
+
+	for _, valid := range crypto.DefaultSignatureCache.VerifyBatch(signer, items, 0) {
+		if !valid {
+			return false, nil
+		}
+	}
+
+	for i, tx := range migrating {
+		verifier, err := crypto.Verifier(tx.NewKeyAlgorithm())
+		if err != nil {
+			return false, nil
+		}
+
+		if !crypto.DefaultSignatureCache.Verify(verifier, migratingItems[i]) {
+			return false, nil
+		}
 	}
 
-	expectedMerkle := ComputeMerkleRoot(txHashes)
+	// 3️⃣ Recompute Merkle root from the same hashes
+	expectedMerkle := ComputeMerkleRootWith(h, txHashes)
 
 	if string(expectedMerkle) != string(b.MerkleRoot) {
 		return false, nil
 	}
 
-	// 3️⃣ Recompute block header hash
-	expectedHash, err := b.computeBlockHash()
+	// 4️⃣ Recompute block header hash
+	expectedHash, err := b.computeBlockHash(h)
 	if err != nil {
 		return false, err
 	}
@@ -136,6 +209,42 @@ func (b *Block) Verify(signer crypto.Signer, publicKey []byte) (bool, error) {
 		return false, nil
 	}
 
-	// 4️⃣ Verify block signature
-	return signer.Verify(publicKey, b.Hash, b.Signature), nil
+	// 5️⃣ Verify block signature
+	return crypto.VerifyInContext(signer, publicKey, b.Hash, b.Signature, blockCtx), nil
+}
+
+// TransactionProof returns the payload hash of transaction index and
+// its Merkle inclusion proof under the chain hash h.
+func (b *Block) TransactionProof(index int, h crypto.Hasher) ([]byte, []ProofStep, error) {
+
+	txHashes, err := hashTransactions(b.Transactions, h)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	proof, err := MerkleProofWith(h, txHashes, index)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return txHashes[index], proof, nil
+}
+
+// hashTransactions computes every transaction payload hash in parallel.
+func hashTransactions(txs []*transaction.Transaction, h crypto.Hasher) ([][]byte, error) {
+
+	hashes := make([][]byte, len(txs))
+	errs := make([]error, len(txs))
+
+	crypto.ParallelFor(len(txs), 0, func(i int) {
+		hashes[i], errs[i] = txs[i].HashWith(h)
+	})
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return hashes, nil
 }
\ No newline at end of file
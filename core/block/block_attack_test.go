@@ -3,6 +3,7 @@ package block
 import (
 	"testing"
 
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/chain"
 	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
 	"github.com/Sai-shashank-2005/aegisq-protocol/core/identity"
 	"github.com/Sai-shashank-2005/aegisq-protocol/core/transaction"
@@ -59,4 +60,110 @@ func TestAttack_ModifyBlockHash(t *testing.T) {
 	if valid {
 		t.Fatal("Corrupted block hash should fail")
 	}
-}
\ No newline at end of file
+}
+
+func attackSigners(t *testing.T) map[string]crypto.Signer {
+
+	ecdsa, _ := crypto.NewECDSASigner()
+
+	dilithium, err := crypto.NewDilithiumSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(dilithium.Close)
+
+	return map[string]crypto.Signer{
+		"ed25519":    &crypto.Ed25519Signer{},
+		"ECDSA_P256": ecdsa,
+		"dilithium2": dilithium,
+	}
+}
+
+func TestAttack_CrossTypeSignatureReplay(t *testing.T) {
+
+	for name, signer := range attackSigners(t) {
+		t.Run(name, func(t *testing.T) {
+
+			node, _ := identity.NewNodeIdentity("validator-1", signer)
+
+			tx := transaction.NewTransaction(node, "payload", "data")
+			tx.SignWithIdentity(node)
+
+			block := NewBlock(1, 0, []byte("prev_hash"), []*transaction.Transaction{tx})
+			if err := block.Finalize(node); err != nil {
+				t.Fatal(err)
+			}
+
+			// A signature over the very same digest, obtained as a
+			// transaction or vote signature, must not pass as the
+			// block signature.
+			for _, typ := range []crypto.MessageType{crypto.MessageTx, crypto.MessageVote} {
+
+				sig, err := node.Sign(chain.Default.Domain(typ), block.Hash)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				forged := *block
+				forged.Signature = sig
+
+				if valid, _ := forged.Verify(signer, node.PublicKey); valid {
+					t.Fatalf("%s signature accepted as block signature", typ)
+				}
+			}
+
+			// Nor may a plain signature without any domain.
+			raw, _ := signer.SignSecret(node.PrivateKey, block.Hash)
+
+			forged := *block
+			forged.Signature = raw
+
+			if valid, _ := forged.Verify(signer, node.PublicKey); valid {
+				t.Fatal("undomained signature accepted as block signature")
+			}
+
+			// And a block signature must not pass as a transaction
+			// signature over the same digest.
+			ctx, _ := chain.Default.Domain(crypto.MessageTx).Context()
+
+			if crypto.VerifyInContext(signer, node.PublicKey, block.Hash, block.Signature, ctx) {
+				t.Fatal("block signature accepted as transaction signature")
+			}
+		})
+	}
+}
+
+func TestAttack_CrossChainBlockReplay(t *testing.T) {
+
+	testnet := chain.Params{ID: "aegisq-testnet", Hasher: crypto.DefaultHasher}
+	mainnet := chain.Params{ID: "aegisq-mainnet", Hasher: crypto.DefaultHasher}
+
+	for name, signer := range attackSigners(t) {
+		t.Run(name, func(t *testing.T) {
+
+			node, _ := identity.NewNodeIdentity("validator-1", signer)
+
+			tx := transaction.NewTransaction(node, "payload", "data")
+			if err := tx.SignWith(node, testnet); err != nil {
+				t.Fatal(err)
+			}
+
+			block := NewBlock(1, 0, []byte("prev_hash"), []*transaction.Transaction{tx})
+			if err := block.FinalizeWith(node, testnet); err != nil {
+				t.Fatal(err)
+			}
+
+			if valid, _ := block.VerifyWith(signer, node.PublicKey, testnet); !valid {
+				t.Fatal("block rejected on its own chain")
+			}
+
+			if valid, _ := block.VerifyWith(signer, node.PublicKey, mainnet); valid {
+				t.Fatal("testnet block accepted on mainnet")
+			}
+
+			if valid, _ := tx.VerifyWith(signer, mainnet); valid {
+				t.Fatal("testnet transaction accepted on mainnet")
+			}
+		})
+	}
+}
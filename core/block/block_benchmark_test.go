@@ -0,0 +1,88 @@
+package block
+
+import (
+	"testing"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/identity"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/transaction"
+)
+
+// Block verification is parallel across GOMAXPROCS workers.
+// Compare scaling with:
+//
+//	go test ./core/block -run '^$' -bench BlockVerify -cpu 1,2,4,8
+
+const benchTxCount = 2000
+
+func benchmarkBlock(b *testing.B) (*Block, crypto.Signer, []byte) {
+
+	// Measure real verification, not signature cache hits.
+	cache := crypto.DefaultSignatureCache
+	crypto.DefaultSignatureCache = nil
+	b.Cleanup(func() { crypto.DefaultSignatureCache = cache })
+
+	signer, err := crypto.NewDilithiumSigner()
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(signer.Close)
+
+	node, err := identity.NewNodeIdentity("validator-1", signer)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	txs := make([]*transaction.Transaction, benchTxCount)
+
+	for i := range txs {
+		tx := transaction.NewTransaction(node, "payload", "bench")
+		tx.Timestamp += int64(i)
+		if err := tx.SignWithIdentity(node); err != nil {
+			b.Fatal(err)
+		}
+		txs[i] = tx
+	}
+
+	blk := NewBlock(1, 0, []byte("prev_hash"), txs)
+	if err := blk.Finalize(node); err != nil {
+		b.Fatal(err)
+	}
+
+	return blk, signer, node.PublicKey
+}
+
+func BenchmarkBlockVerifyDilithium(b *testing.B) {
+
+	blk, signer, pub := benchmarkBlock(b)
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		valid, err := blk.Verify(signer, pub)
+		if err != nil || !valid {
+			b.Fatal("block verification failed")
+		}
+	}
+
+	b.ReportMetric(float64(benchTxCount*b.N)/b.Elapsed().Seconds(), "tx/s")
+}
+
+func BenchmarkBlockVerifySerialDilithium(b *testing.B) {
+
+	blk, signer, _ := benchmarkBlock(b)
+
+	b.ResetTimer()
+
+	// Baseline: the pre-batch path, one tx.Verify per transaction.
+	for i := 0; i < b.N; i++ {
+		for _, tx := range blk.Transactions {
+			valid, err := tx.Verify(signer)
+			if err != nil || !valid {
+				b.Fatal("transaction verification failed")
+			}
+		}
+	}
+
+	b.ReportMetric(float64(benchTxCount*b.N)/b.Elapsed().Seconds(), "tx/s")
+}
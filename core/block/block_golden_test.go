@@ -0,0 +1,93 @@
+package block
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/identity"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/transaction"
+)
+
+// goldenBlock builds a fixed block from a deterministic signer.
+func goldenBlock(t *testing.T, alg string) (*Block, crypto.Signer, []byte) {
+
+	signer, err := crypto.NewDeterministicSigner(alg, []byte("aegisq-golden-block"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(signer.Close)
+
+	node, err := identity.NewNodeIdentity("validator-1", signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var txs []*transaction.Transaction
+
+	for i := 0; i < 3; i++ {
+		tx := transaction.NewTransaction(node, "payload-"+string(rune('a'+i)), "golden")
+		tx.Timestamp = 1700000000 + int64(i)
+
+		if err := tx.SignWithIdentity(node); err != nil {
+			t.Fatal(err)
+		}
+		txs = append(txs, tx)
+	}
+
+	b := NewBlock(1, 0, make([]byte, 32), txs)
+	b.Timestamp = 1700000100
+
+	if err := b.Finalize(node); err != nil {
+		t.Fatal(err)
+	}
+
+	return b, signer, node.PublicKey
+}
+
+// fingerprint commits to the block hash and every signature.
+func fingerprint(b *Block) string {
+
+	h := sha256.New()
+	h.Write(b.Hash)
+	h.Write(b.Signature)
+
+	for _, tx := range b.Transactions {
+		h.Write(tx.Signature)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func TestGoldenBlock(t *testing.T) {
+
+	// Update only for intentional changes to block or transaction
+	// hashing or signing; the test prints the new value on mismatch.
+	golden := map[string]string{
+		"dilithium2": "7cffe5c853f266eb614b32c0301894d68a9b06e01fc1d73a040166484e5a28e2",
+		"ECDSA_P256": "a3eae1f91d1fb468231cfdf78e46baa4db3518295c42965a68d8d62b7b1f16b0",
+		"ed25519":    "1a96fa4b9913db88bd8ced05d5fbc054aff4d2b3ef3aecd40243b80c0767e279",
+	}
+
+	for alg, want := range golden {
+		t.Run(alg, func(t *testing.T) {
+
+			b, signer, pub := goldenBlock(t, alg)
+			again, _, _ := goldenBlock(t, alg)
+
+			if fingerprint(b) != fingerprint(again) {
+				t.Fatal("golden block is not reproducible")
+			}
+
+			valid, err := b.Verify(signer, pub)
+			if err != nil || !valid {
+				t.Fatal("golden block failed verification")
+			}
+
+			if got := fingerprint(b); got != want {
+				t.Fatalf("golden block changed: got %s", got)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package block
 import (
 	"testing"
 
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/chain"
 	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
 	"github.com/Sai-shashank-2005/aegisq-protocol/core/identity"
 	"github.com/Sai-shashank-2005/aegisq-protocol/core/transaction"
@@ -65,4 +66,71 @@ func TestBlockTamperFails(t *testing.T) {
 	if valid {
 		t.Fatal("Tampered block should fail")
 	}
-}
\ No newline at end of file
+}
+
+func TestBlockVerifyUsesChainHash(t *testing.T) {
+
+	signer := &crypto.Ed25519Signer{}
+	node, _ := identity.NewNodeIdentity("validator-1", signer)
+
+	hasher, err := crypto.NewHasher("shake256-512")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params := chain.Params{ID: "test-chain", Hasher: hasher}
+
+	tx := transaction.NewTransaction(node, "payload", "data")
+	if err := tx.SignWith(node, params); err != nil {
+		t.Fatal(err)
+	}
+
+	block := NewBlock(1, 0, []byte("prev_hash"), []*transaction.Transaction{tx})
+
+	if err := block.FinalizeWith(node, params); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(block.Hash) != 64 || len(block.MerkleRoot) != 64 {
+		t.Fatal("block digests do not use the chain hash size")
+	}
+
+	valid, err := block.VerifyWith(signer, node.PublicKey, params)
+	if err != nil || !valid {
+		t.Fatal("block verification under chain hash failed")
+	}
+
+	// The same block must not verify under a different hash.
+	valid, _ = block.VerifyWith(signer, node.PublicKey, chain.Params{ID: "test-chain", Hasher: crypto.DefaultHasher})
+	if valid {
+		t.Fatal("block verified under the wrong chain hash")
+	}
+}
+
+func TestEmptyBlockFinalizeAndVerify(t *testing.T) {
+
+	signer := &crypto.Ed25519Signer{}
+	node, _ := identity.NewNodeIdentity("validator-1", signer)
+
+	block := NewBlock(1, 0, []byte("prev_hash"), nil)
+
+	if err := block.Finalize(node); err != nil {
+		t.Fatal(err)
+	}
+
+	if string(block.MerkleRoot) != string(crypto.DefaultHasher.Hash(nil)) {
+		t.Fatal("empty block root must be the hash of the empty string")
+	}
+
+	valid, err := block.Verify(signer, node.PublicKey)
+	if err != nil || !valid {
+		t.Fatal("empty block verification failed")
+	}
+
+	// A transaction cannot be smuggled into a sealed empty block
+	block.Transactions = append(block.Transactions, createTestTx(t, node))
+
+	if valid, _ := block.Verify(signer, node.PublicKey); valid {
+		t.Fatal("block with an added transaction should fail")
+	}
+}
@@ -2,13 +2,21 @@ package block
 
 import (
 	"bytes"
+	"errors"
 
 	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
 )
 
+// ComputeMerkleRoot builds the root with the default chain hash.
 func ComputeMerkleRoot(hashes [][]byte) []byte {
+	return ComputeMerkleRootWith(crypto.DefaultHasher, hashes)
+}
+
+// ComputeMerkleRootWith builds the root with the chain hash h. The
+// root of an empty block is the hash of the empty string.
+func ComputeMerkleRootWith(h crypto.Hasher, hashes [][]byte) []byte {
 	if len(hashes) == 0 {
-		return nil
+		return h.Hash(nil)
 	}
 
 	if len(hashes) == 1 {
@@ -22,9 +30,69 @@ func ComputeMerkleRoot(hashes [][]byte) []byte {
 			nextLevel = append(nextLevel, hashes[i])
 		} else {
 			combined := bytes.Join([][]byte{hashes[i], hashes[i+1]}, nil)
-			nextLevel = append(nextLevel, crypto.Hash(combined))
+			nextLevel = append(nextLevel, h.Hash(combined))
+		}
+	}
+
+	return ComputeMerkleRootWith(h, nextLevel)
+}
+// ProofStep is one sibling on the path from a leaf to the Merkle root.
+// Left reports whether the sibling is hashed before the running node.
+type ProofStep struct {
+	Hash []byte `json:"hash"`
+	Left bool   `json:"left"`
+}
+
+// MerkleProofWith returns the inclusion proof of hashes[index] under
+// the root ComputeMerkleRootWith builds. A node promoted without a
+// sibling contributes no step.
+func MerkleProofWith(h crypto.Hasher, hashes [][]byte, index int) ([]ProofStep, error) {
+
+	if index < 0 || index >= len(hashes) {
+		return nil, errors.New("leaf index out of range")
+	}
+
+	var proof []ProofStep
+
+	level := hashes
+
+	for len(level) > 1 {
+
+		sibling := index ^ 1
+
+		if sibling < len(level) {
+			proof = append(proof, ProofStep{Hash: level[sibling], Left: sibling < index})
+		}
+
+		var next [][]byte
+
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+			} else {
+				next = append(next, h.Hash(bytes.Join([][]byte{level[i], level[i+1]}, nil)))
+			}
+		}
+
+		level = next
+		index /= 2
+	}
+
+	return proof, nil
+}
+
+// VerifyMerkleProof reports whether proof links leaf to root.
+func VerifyMerkleProof(h crypto.Hasher, leaf []byte, proof []ProofStep, root []byte) bool {
+
+	node := leaf
+
+	for _, step := range proof {
+		if step.Left {
+			node = h.Hash(bytes.Join([][]byte{step.Hash, node}, nil))
+		} else {
+			node = h.Hash(bytes.Join([][]byte{node, step.Hash}, nil))
 		}
 	}
 
-	return ComputeMerkleRoot(nextLevel)
-}
\ No newline at end of file
+	return bytes.Equal(node, root)
+}
@@ -0,0 +1,74 @@
+package block
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/identity"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/transaction"
+)
+
+func TestMerkleProofEveryLeaf(t *testing.T) {
+
+	h := crypto.DefaultHasher
+
+	// Odd sizes exercise promoted nodes without a sibling
+	for n := 1; n <= 9; n++ {
+
+		var leaves [][]byte
+		for i := 0; i < n; i++ {
+			leaves = append(leaves, h.Hash([]byte(fmt.Sprintf("tx-%d", i))))
+		}
+
+		root := ComputeMerkleRootWith(h, leaves)
+
+		for i := range leaves {
+
+			proof, err := MerkleProofWith(h, leaves, i)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !VerifyMerkleProof(h, leaves[i], proof, root) {
+				t.Fatalf("%d leaves: proof of leaf %d does not verify", n, i)
+			}
+
+			if n > 1 && VerifyMerkleProof(h, leaves[(i+1)%n], proof, root) {
+				t.Fatalf("%d leaves: proof of leaf %d verifies another leaf", n, i)
+			}
+		}
+	}
+
+	if _, err := MerkleProofWith(h, [][]byte{h.Hash(nil)}, 1); err == nil {
+		t.Fatal("out of range index should be rejected")
+	}
+}
+
+func TestBlockTransactionProof(t *testing.T) {
+
+	signer := &crypto.Ed25519Signer{}
+	node, err := identity.NewNodeIdentity("validator-1", signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var txs []*transaction.Transaction
+	for i := 0; i < 3; i++ {
+		txs = append(txs, createTestTx(t, node))
+	}
+
+	b := NewBlock(1, 0, []byte("prev_hash"), txs)
+	if err := b.Finalize(node); err != nil {
+		t.Fatal(err)
+	}
+
+	leaf, proof, err := b.TransactionProof(2, crypto.DefaultHasher)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !VerifyMerkleProof(crypto.DefaultHasher, leaf, proof, b.MerkleRoot) {
+		t.Fatal("transaction proof does not reach the block's Merkle root")
+	}
+}
@@ -0,0 +1,192 @@
+// Package blocksync brings a node that is behind its peers up to their
+// tip: it asks peers for their height, downloads the missing blocks in
+// ranges from several peers at once, and validates them in order with
+// the ledger rules and their commit certificates before storing them.
+//
+// The protocol has two requests, Status and Blocks, served by any node
+// through a Source. Peers are reached over HTTP (Handler, NewHTTPPeer)
+// or, for tests and simulations, in process (NewLocalPeer).
+package blocksync
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/block"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/consensus"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/ledger"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/storage"
+)
+
+// ErrInvalidBlock marks a block or certificate rejected by validation,
+// as opposed to a failure to store it.
+var ErrInvalidBlock = errors.New("invalid block")
+
+// ErrNotStored marks a request for blocks a node does not store.
+var ErrNotStored = errors.New("not stored")
+
+// ErrFalseHeight marks a peer that did not serve blocks up to the
+// height it reported.
+var ErrFalseHeight = errors.New("did not serve the height it reported")
+
+// Chain is a node's chain, extended only by blocks that pass the
+// ledger rules with a commit certificate. Block production and sync
+// both append through it, so they never race for a height.
+//
+// A stored block has been applied to the verifier's state; an error
+// other than ErrInvalidBlock leaves database and verifier apart, and
+// the chain must not be used further.
+type Chain struct {
+	mu       sync.Mutex
+	db       *storage.DB
+	verifier *ledger.ChainVerifier
+
+	// OnAppend, if set, is called with every stored block before the
+	// next one is appended.
+	OnAppend func(b *block.Block)
+}
+
+// NewChain extends the chain in db, whose tip v has verified.
+func NewChain(db *storage.DB, v *ledger.ChainVerifier) *Chain {
+	return &Chain{db: db, verifier: v}
+}
+
+// Height returns the height of the tip.
+func (c *Chain) Height() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return uint64(c.verifier.Height())
+}
+
+// Tip returns the last block, or the genesis anchor.
+func (c *Chain) Tip() *block.Block {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.verifier.Tip()
+}
+
+// Append validates b and its certificate as the next block and stores
+// both.
+func (c *Chain) Append(b *block.Block, commit *consensus.Certificate) error {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.append(b, commit)
+}
+
+// Extend appends the block build makes on the tip, holding off other
+// appends until it is stored. build returns a nil block to append
+// nothing.
+func (c *Chain) Extend(build func(tip *block.Block) (*block.Block, *consensus.Certificate, error)) (*block.Block, error) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b, commit, err := build(c.verifier.Tip())
+	if err != nil || b == nil {
+		return nil, err
+	}
+
+	if err := c.append(b, commit); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func (c *Chain) append(b *block.Block, commit *consensus.Certificate) error {
+
+	if err := c.verifier.VerifyCommit(b, commit); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBlock, err)
+	}
+
+	if err := c.verifier.Verify(b); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBlock, err)
+	}
+
+	if err := c.db.SaveCommittedBlock(b, commit); err != nil {
+		return err
+	}
+
+	if c.OnAppend != nil {
+		c.OnAppend(b)
+	}
+
+	return nil
+}
+
+// Check validates b and its certificate as a block for a height the
+// chain already stores, against the ledger rules and the validator
+// keys of that height, without storing anything. A failure wraps
+// ErrInvalidBlock. A block passing it that differs from the stored one
+// was committed by a quorum that also committed the stored block.
+func (c *Chain) Check(b *block.Block, commit *consensus.Certificate) error {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var parent *block.Block
+
+	if b.Index > 1 {
+
+		var err error
+		if parent, err = c.db.GetBlock(uint64(b.Index - 1)); err != nil {
+			return fmt.Errorf("block %d: parent: %w", b.Index, err)
+		}
+	}
+
+	if err := c.verifier.VerifyPast(b, parent, commit); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBlock, err)
+	}
+
+	return nil
+}
+
+// Status reports the stored range of the chain.
+func (c *Chain) Status() (Status, error) {
+
+	base, err := c.db.Base()
+	if err != nil {
+		return Status{}, err
+	}
+
+	return Status{Base: base, Height: c.Height()}, nil
+}
+
+// Blocks returns the committed blocks from to to, at most MaxRange.
+func (c *Chain) Blocks(from, to uint64) ([]Committed, error) {
+
+	status, err := c.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	if from <= status.Base || to < from || to > status.Height {
+		return nil, fmt.Errorf("blocks %d to %d are %w (have %d to %d)", from, to, ErrNotStored, status.Base+1, status.Height)
+	}
+
+	if to-from+1 > MaxRange {
+		to = from + MaxRange - 1
+	}
+
+	list := make([]Committed, 0, to-from+1)
+
+	for h := from; h <= to; h++ {
+
+		b, err := c.db.GetBlock(h)
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %w", h, err)
+		}
+
+		commit, err := c.db.Commit(h)
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %w", h, err)
+		}
+
+		list = append(list, Committed{Block: b, Commit: commit})
+	}
+
+	return list, nil
+}
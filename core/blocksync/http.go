@@ -0,0 +1,133 @@
+package blocksync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxResponseBytes bounds a Blocks response read from a peer: MaxRange
+// full blocks of Dilithium transactions.
+const maxResponseBytes = 512 << 20
+
+// Handler serves src to syncing nodes:
+//
+//	GET /blocksync/status
+//	GET /blocksync/blocks?from=H&to=H
+func Handler(src Source) http.Handler {
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/blocksync/status", func(w http.ResponseWriter, r *http.Request) {
+
+		status, err := src.Status()
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(status)
+	})
+
+	mux.HandleFunc("/blocksync/blocks", func(w http.ResponseWriter, r *http.Request) {
+
+		from, err1 := strconv.ParseUint(r.URL.Query().Get("from"), 10, 64)
+		to, err2 := strconv.ParseUint(r.URL.Query().Get("to"), 10, 64)
+
+		if err1 != nil || err2 != nil {
+			http.Error(w, "usage: /blocksync/blocks?from=H&to=H", 400)
+			return
+		}
+
+		blocks, err := src.Blocks(from, to)
+		if errors.Is(err, ErrNotStored) {
+			http.Error(w, err.Error(), 404)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(blocks)
+	})
+
+	return mux
+}
+
+// httpPeer reaches a node serving Handler.
+type httpPeer struct {
+	id   string
+	base string
+	http *http.Client
+}
+
+// NewHTTPPeer returns the peer at addr, a host:port or URL serving
+// Handler. The peer's ID is addr.
+func NewHTTPPeer(addr string) Peer {
+
+	base := addr
+	if !strings.Contains(base, "://") {
+		base = "http://" + base
+	}
+
+	return &httpPeer{
+		id:   addr,
+		base: strings.TrimRight(base, "/"),
+		http: &http.Client{Timeout: time.Minute},
+	}
+}
+
+func (p *httpPeer) ID() string {
+	return p.id
+}
+
+func (p *httpPeer) Status(ctx context.Context) (Status, error) {
+
+	var status Status
+	err := p.get(ctx, "/blocksync/status", &status)
+
+	return status, err
+}
+
+func (p *httpPeer) Blocks(ctx context.Context, from, to uint64) ([]Committed, error) {
+
+	var blocks []Committed
+	err := p.get(ctx, fmt.Sprintf("/blocksync/blocks?from=%d&to=%d", from, to), &blocks)
+
+	return blocks, err
+}
+
+func (p *httpPeer) get(ctx context.Context, path string, out interface{}) error {
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.base+path, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body := io.LimitReader(resp.Body, maxResponseBytes)
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(body, 1024))
+		if resp.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("%w: %s", ErrNotStored, strings.TrimSpace(string(msg)))
+		}
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return json.NewDecoder(body).Decode(out)
+}
@@ -0,0 +1,74 @@
+package blocksync
+
+import (
+	"context"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/block"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/consensus"
+)
+
+// MaxRange bounds the blocks served for one request.
+const MaxRange = 500
+
+// Status is the range of blocks a node stores: those above Base, up
+// to Height. Base is 0 unless the node was state synced.
+type Status struct {
+	Base   uint64 `json:"base"`
+	Height uint64 `json:"height"`
+}
+
+// Committed is a block with the certificate that committed it.
+type Committed struct {
+	Block  *block.Block           `json:"block"`
+	Commit *consensus.Certificate `json:"commit"`
+}
+
+// Source serves the sync requests from a local chain.
+type Source interface {
+	Status() (Status, error)
+
+	// Blocks returns the blocks from to to, or a prefix of them.
+	Blocks(from, to uint64) ([]Committed, error)
+}
+
+// Peer is a node the reactor syncs from. Nothing a peer returns is
+// trusted: every block is validated before it is stored.
+type Peer interface {
+	ID() string
+	Status(ctx context.Context) (Status, error)
+	Blocks(ctx context.Context, from, to uint64) ([]Committed, error)
+}
+
+// localPeer reaches a Source in the same process.
+type localPeer struct {
+	id  string
+	src Source
+}
+
+// NewLocalPeer returns a peer served by src in the same process, for
+// tests and simulations.
+func NewLocalPeer(id string, src Source) Peer {
+	return &localPeer{id: id, src: src}
+}
+
+func (p *localPeer) ID() string {
+	return p.id
+}
+
+func (p *localPeer) Status(ctx context.Context) (Status, error) {
+
+	if err := ctx.Err(); err != nil {
+		return Status{}, err
+	}
+
+	return p.src.Status()
+}
+
+func (p *localPeer) Blocks(ctx context.Context, from, to uint64) ([]Committed, error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return p.src.Blocks(from, to)
+}
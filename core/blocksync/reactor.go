@@ -0,0 +1,466 @@
+package blocksync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Config tunes a Reactor.
+type Config struct {
+	// RangeSize is the number of blocks asked for per request, at
+	// most MaxRange.
+	RangeSize int
+
+	// Parallel bounds the ranges requested or waiting to be applied.
+	Parallel int
+
+	// Interval is the time between polls of the peers' heights.
+	Interval time.Duration
+
+	// Timeout bounds one request to a peer.
+	Timeout time.Duration
+}
+
+// DefaultConfig returns the settings a node syncs with.
+func DefaultConfig() Config {
+	return Config{
+		RangeSize: 100,
+		Parallel:  4,
+		Interval:  time.Second,
+		Timeout:   10 * time.Second,
+	}
+}
+
+// Reactor keeps a chain up with its peers.
+type Reactor struct {
+	chain *Chain
+	peers func() []Peer
+	cfg   Config
+
+	// Logf reports progress and misbehaving peers; log.Printf by
+	// default.
+	Logf func(format string, args ...interface{})
+
+	// OnPeerError, if set, is told of every failed request and every
+	// invalid block, whose error wraps ErrInvalidBlock. It may be
+	// called concurrently.
+	OnPeerError func(peer string, err error)
+}
+
+// NewReactor syncs chain from the peers returned by peers, which is
+// called at every poll so the set may change. Zero settings in cfg
+// take their default.
+func NewReactor(chain *Chain, peers func() []Peer, cfg Config) *Reactor {
+
+	def := DefaultConfig()
+
+	if cfg.RangeSize <= 0 {
+		cfg.RangeSize = def.RangeSize
+	}
+	if cfg.RangeSize > MaxRange {
+		cfg.RangeSize = MaxRange
+	}
+	if cfg.Parallel <= 0 {
+		cfg.Parallel = def.Parallel
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = def.Interval
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = def.Timeout
+	}
+
+	return &Reactor{chain: chain, peers: peers, cfg: cfg, Logf: log.Printf}
+}
+
+// Run syncs with the peers until ctx is cancelled. caughtUp is called
+// once, the first time the chain reaches the height its peers
+// reported; the node then switches to live consensus, while Run keeps
+// following the peers' blocks. Only a failure to store a block stops
+// it early.
+func (r *Reactor) Run(ctx context.Context, caughtUp func()) error {
+
+	signalled := false
+
+	for {
+		synced, err := r.Sync(ctx)
+
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if synced && !signalled {
+			signalled = true
+			caughtUp()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(r.cfg.Interval):
+		}
+	}
+}
+
+// peerStatus is a peer with the range it reported.
+type peerStatus struct {
+	peer   Peer
+	status Status
+}
+
+// Sync runs one round: it asks every peer for its height and fetches
+// the blocks up to the highest. It reports whether the chain reached
+// the highest height of the peers that served their blocks. Peers that
+// do not answer are skipped, and a peer dropped for a failed request
+// or an invalid block no longer counts; with no peer left to serve
+// past the local height, the chain is as far as it can tell caught up.
+// The error is a failure to store a block.
+func (r *Reactor) Sync(ctx context.Context) (bool, error) {
+
+	height := r.chain.Height()
+
+	var sources []peerStatus
+	var target uint64
+
+	for _, ps := range r.statuses(ctx, r.peers()) {
+		if ps.status.Height > height {
+			sources = append(sources, ps)
+		}
+		if ps.status.Height > target {
+			target = ps.status.Height
+		}
+	}
+
+	if len(sources) == 0 {
+		return true, nil
+	}
+
+	r.Logf("Syncing blocks %d to %d from %d peers", height+1, target, len(sources))
+
+	start := time.Now()
+
+	reached, err := r.fetch(ctx, sources, target)
+	if err != nil {
+		return false, err
+	}
+
+	synced := r.chain.Height()
+	if synced > height {
+		r.Logf("Synced to height %d: %d blocks in %s", synced, synced-height, time.Since(start).Round(time.Millisecond))
+	}
+
+	return synced >= reached, nil
+}
+
+// statuses asks every peer for its range at once.
+func (r *Reactor) statuses(ctx context.Context, peers []Peer) []peerStatus {
+
+	list := make([]peerStatus, len(peers))
+	ok := make([]bool, len(peers))
+
+	var wg sync.WaitGroup
+
+	for i, p := range peers {
+
+		wg.Add(1)
+
+		go func(i int, p Peer) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, r.cfg.Timeout)
+			defer cancel()
+
+			status, err := p.Status(ctx)
+			if err != nil {
+				r.Logf("Sync: peer %s: %v", p.ID(), err)
+				r.peerError(p, err)
+				return
+			}
+
+			list[i], ok[i] = peerStatus{p, status}, true
+		}(i, p)
+	}
+
+	wg.Wait()
+
+	var answered []peerStatus
+	for i := range list {
+		if ok[i] {
+			answered = append(answered, list[i])
+		}
+	}
+
+	return answered
+}
+
+// span is a range of heights requested together.
+type span struct {
+	from, to uint64
+}
+
+type fetched struct {
+	span
+	peer   Peer
+	blocks []Committed
+	err    error
+}
+
+// fetch downloads the blocks up to target from sources, with up to
+// Parallel ranges in flight or waiting, and appends them in order. A
+// peer that fails a request or serves an invalid block is dropped for
+// the round and its range is asked of another. The target then drops
+// to the highest height of the peers left, so a peer claiming blocks
+// it does not have cannot keep the round going; fetch returns the
+// height the round ended at.
+func (r *Reactor) fetch(ctx context.Context, sources []peerStatus, target uint64) (uint64, error) {
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan fetched)
+	dropped := make(map[string]bool)
+
+	next := r.chain.Height() + 1
+	var retry []span
+
+	ready := make(map[uint64]fetched)
+	inflight := 0
+	turn := 0
+
+	// pick takes turns among the peers holding all of s
+	pick := func(s span) Peer {
+
+		for i := 0; i < len(sources); i++ {
+
+			ps := sources[(turn+i)%len(sources)]
+
+			if !dropped[ps.peer.ID()] && ps.status.Base < s.from && ps.status.Height >= s.to {
+				turn += i + 1
+				return ps.peer
+			}
+		}
+
+		return nil
+	}
+
+	request := func(s span, p Peer) {
+
+		ctx, cancelRequest := context.WithTimeout(ctx, r.cfg.Timeout)
+		defer cancelRequest()
+
+		blocks, err := p.Blocks(ctx, s.from, s.to)
+		if err == nil {
+			err = checkSpan(blocks, s)
+		}
+
+		select {
+		case results <- fetched{s, p, blocks, err}:
+		case <-ctx.Done():
+		}
+	}
+
+	drop := func(p Peer, err error) {
+
+		r.Logf("Sync: dropping peer %s for this round: %v", p.ID(), err)
+		r.peerError(p, err)
+		dropped[p.ID()] = true
+
+		target = r.chain.Height()
+		for _, ps := range sources {
+			if !dropped[ps.peer.ID()] && ps.status.Height > target {
+				target = ps.status.Height
+			}
+		}
+
+		// Ranges above the peers left are no longer asked for
+		var kept []span
+		for _, s := range retry {
+			if s.from > target {
+				continue
+			}
+			if s.to > target {
+				s.to = target
+			}
+			kept = append(kept, s)
+		}
+		retry = kept
+	}
+
+	// failed drops a peer whose request failed. Ranges are only asked
+	// of peers that reported holding them, so a range it does not
+	// store is a false report.
+	failed := func(res fetched) {
+
+		err := res.err
+		if errors.Is(err, ErrNotStored) {
+			err = fmt.Errorf("%w: %v", ErrFalseHeight, err)
+		}
+
+		retry = append(retry, res.span)
+		drop(res.peer, err)
+	}
+
+	for r.chain.Height() < target {
+
+		// Gossip and the producer append to the chain too: heights
+		// they stored meanwhile are not asked for again, and ranges
+		// below the tip are discarded rather than fill the pipeline
+		height := r.chain.Height()
+
+		if next <= height {
+			next = height + 1
+		}
+
+		var kept []span
+		for _, s := range retry {
+			if s.to <= height {
+				continue
+			}
+			if s.from <= height {
+				s.from = height + 1
+			}
+			kept = append(kept, s)
+		}
+		retry = kept
+
+		for from, res := range ready {
+			if res.to <= height {
+				delete(ready, from)
+			}
+		}
+
+		// Keep the pipeline full, retried ranges first
+		for inflight+len(ready) < r.cfg.Parallel {
+
+			var s span
+
+			switch {
+			case len(retry) > 0:
+				s = retry[0]
+			case next <= target:
+				s = span{next, next + uint64(r.cfg.RangeSize) - 1}
+				if s.to > target {
+					s.to = target
+				}
+			}
+
+			if s.from == 0 {
+				break
+			}
+
+			p := pick(s)
+			if p == nil {
+				break
+			}
+
+			if len(retry) > 0 {
+				retry = retry[1:]
+			} else {
+				next = s.to + 1
+			}
+
+			inflight++
+			go request(s, p)
+		}
+
+		if inflight == 0 {
+			r.Logf("Sync: no peer left to serve blocks from %d", r.chain.Height()+1)
+			return r.chain.Height(), nil
+		}
+
+		var res fetched
+
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case res = <-results:
+			inflight--
+		}
+
+		if res.err != nil {
+			failed(res)
+			continue
+		}
+
+		ready[res.from] = res
+
+		// Append the ranges that continue the chain
+		for {
+			res, ok := continuing(ready, r.chain.Height()+1)
+			if !ok {
+				break
+			}
+			delete(ready, res.from)
+
+			for _, c := range res.blocks {
+
+				// Blocks another path stored are skipped
+				if uint64(c.Block.Index) <= r.chain.Height() {
+					continue
+				}
+
+				err := r.chain.Append(c.Block, c.Commit)
+				if err == nil {
+					continue
+				}
+
+				if !errors.Is(err, ErrInvalidBlock) {
+					return 0, err
+				}
+
+				// Unless another path stored the height first
+				if uint64(c.Block.Index) <= r.chain.Height() {
+					continue
+				}
+
+				retry = append([]span{{uint64(c.Block.Index), res.to}}, retry...)
+				drop(res.peer, err)
+				break
+			}
+		}
+	}
+
+	return target, nil
+}
+
+// continuing returns the fetched range holding height, if any.
+func continuing(ready map[uint64]fetched, height uint64) (fetched, bool) {
+
+	for _, res := range ready {
+		if res.from <= height && height <= res.to {
+			return res, true
+		}
+	}
+
+	return fetched{}, false
+}
+
+func (r *Reactor) peerError(p Peer, err error) {
+	if r.OnPeerError != nil {
+		r.OnPeerError(p.ID(), err)
+	}
+}
+
+// checkSpan checks a response holds exactly the blocks of s, in order.
+func checkSpan(blocks []Committed, s span) error {
+
+	if uint64(len(blocks)) != s.to-s.from+1 {
+		return fmt.Errorf("%w: served %d blocks for %d to %d", ErrFalseHeight, len(blocks), s.from, s.to)
+	}
+
+	for i, c := range blocks {
+		if c.Block == nil || uint64(c.Block.Index) != s.from+uint64(i) {
+			return fmt.Errorf("served blocks out of order for %d to %d", s.from, s.to)
+		}
+	}
+
+	return nil
+}
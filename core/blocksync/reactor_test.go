@@ -0,0 +1,582 @@
+package blocksync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/block"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/chain"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/consensus"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/identity"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/ledger"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/scheduler"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/state"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/storage"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/transaction"
+)
+
+// testNet is a chain of four validators whose keys the test holds.
+type testNet struct {
+	params chain.Params
+	nodes  map[string]*identity.NodeIdentity
+	sched  *scheduler.RoundRobinScheduler
+}
+
+func newTestNet(t *testing.T) *testNet {
+
+	p := chain.Default
+	p.GenesisHash = p.Hasher.Hash([]byte("genesis"))
+	p.StateRootInterval = 5
+
+	n := &testNet{params: p, nodes: make(map[string]*identity.NodeIdentity)}
+	vs := consensus.NewValidatorSet()
+
+	for i := 1; i <= 4; i++ {
+
+		node, err := identity.NewNodeIdentity(fmt.Sprintf("validator-%d", i), &crypto.Ed25519Signer{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		n.nodes[node.NodeID] = node
+		if err := vs.AddValidator(node.NodeID, node.PublicKey); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	n.sched = scheduler.NewRoundRobinScheduler(vs)
+	return n
+}
+
+// node starts an empty chain in a fresh database.
+func (n *testNet) node(t *testing.T) *Chain {
+
+	db, err := storage.Open(filepath.Join(t.TempDir(), "aegisq.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := db.UseHasher(n.params.Hasher); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.UseGenesis(n.params.GenesisHash); err != nil {
+		t.Fatal(err)
+	}
+
+	var validators []state.Validator
+	for id, node := range n.nodes {
+		validators = append(validators, state.Validator{NodeID: id, Algorithm: node.Algorithm(), PublicKey: node.PublicKey})
+	}
+
+	st, err := state.New(validators)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vs, algorithms, err := st.ValidatorSet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := ledger.NewChainVerifier(n.params, vs, algorithms)
+	v.TrackState(st)
+
+	return NewChain(db, v)
+}
+
+// produce extends c by count blocks, each proposed by its leader and
+// committed by every validator.
+func (n *testNet) produce(t *testing.T, c *Chain, st func() *state.State, count int) {
+
+	for i := 0; i < count; i++ {
+
+		_, err := c.Extend(func(tip *block.Block) (*block.Block, *consensus.Certificate, error) {
+
+			height := tip.Index + 1
+
+			leaderID, err := n.sched.GetLeader(height, 0)
+			if err != nil {
+				return nil, nil, err
+			}
+			leader := n.nodes[leaderID]
+
+			tx := transaction.NewTransaction(leader, fmt.Sprintf("data-%d", height), "")
+			if err := tx.SignWith(leader, n.params); err != nil {
+				return nil, nil, err
+			}
+
+			b := block.NewBlock(height, 0, tip.Hash, []*transaction.Transaction{tx})
+
+			if n.params.CommitsState(height) {
+				if b.StateRoot, err = st().RootAfter(b, n.params.Hasher); err != nil {
+					return nil, nil, err
+				}
+			}
+
+			if err := b.FinalizeWith(leader, n.params); err != nil {
+				return nil, nil, err
+			}
+
+			commit := consensus.NewCertificate(height, 0, b.Hash)
+			for _, node := range n.nodes {
+				if err := commit.Sign(node, n.params); err != nil {
+					return nil, nil, err
+				}
+			}
+
+			return b, commit, nil
+		})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// producer returns a chain of count blocks.
+func (n *testNet) producer(t *testing.T, count int) *Chain {
+	c := n.node(t)
+	n.produce(t, c, c.verifier.State, count)
+	return c
+}
+
+func quietReactor(c *Chain, peers []Peer, cfg Config) *Reactor {
+	r := NewReactor(c, func() []Peer { return peers }, cfg)
+	r.Logf = func(string, ...interface{}) {}
+	return r
+}
+
+// faultySource serves src with each block passed through tamper.
+type faultySource struct {
+	Source
+	tamper func(c *Committed)
+}
+
+func (s faultySource) Blocks(from, to uint64) ([]Committed, error) {
+
+	blocks, err := s.Source.Blocks(from, to)
+	for i := range blocks {
+		s.tamper(&blocks[i])
+	}
+
+	return blocks, err
+}
+
+// liarSource serves src but reports height as its tip.
+type liarSource struct {
+	Source
+	height uint64
+}
+
+func (s liarSource) Status() (Status, error) {
+	status, err := s.Source.Status()
+	status.Height = s.height
+	return status, err
+}
+
+// downPeer fails every request.
+type downPeer struct{}
+
+func (downPeer) ID() string { return "down" }
+
+func (downPeer) Status(context.Context) (Status, error) {
+	return Status{}, errors.New("connection refused")
+}
+
+func (downPeer) Blocks(context.Context, uint64, uint64) ([]Committed, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestSyncFromPeers(t *testing.T) {
+
+	n := newTestNet(t)
+	src := n.producer(t, 57)
+
+	follower := n.node(t)
+
+	peers := []Peer{
+		downPeer{},
+		NewLocalPeer("a", src),
+		NewLocalPeer("b", src),
+	}
+
+	r := quietReactor(follower, peers, Config{RangeSize: 7, Parallel: 3})
+
+	synced, err := r.Sync(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !synced || follower.Height() != 57 {
+		t.Fatalf("synced to height %d (%t)", follower.Height(), synced)
+	}
+
+	if string(follower.Tip().Hash) != string(src.Tip().Hash) {
+		t.Fatal("follower tip differs from the peers'")
+	}
+
+	// The follower validated the state roots and serves the blocks on
+	got, err := follower.Blocks(50, 57)
+	if err != nil || len(got) != 8 || got[7].Commit == nil {
+		t.Fatalf("follower serves %d blocks (%v)", len(got), err)
+	}
+
+	if follower.verifier.State().Records() != 57 {
+		t.Fatalf("follower state holds %d records", follower.verifier.State().Records())
+	}
+}
+
+func TestSyncSkipsFaultyPeers(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		tamper func(c *Committed)
+	}{
+		{"no quorum", func(c *Committed) {
+			if c.Block.Index == 12 {
+				c.Commit.Votes = c.Commit.Votes[:2]
+			}
+		}},
+		{"forged vote", func(c *Committed) {
+			if c.Block.Index == 3 {
+				c.Commit.Votes[0].Signature[0] ^= 1
+			}
+		}},
+		{"missing certificate", func(c *Committed) {
+			if c.Block.Index == 20 {
+				c.Commit = nil
+			}
+		}},
+		{"tampered transaction", func(c *Committed) {
+			if c.Block.Index == 10 {
+				c.Block.Transactions[0].DataHash = "forged"
+			}
+		}},
+		{"certificate of another block", func(c *Committed) {
+			if c.Block.Index == 9 {
+				c.Commit.Height = 8
+			}
+		}},
+	}
+
+	n := newTestNet(t)
+	src := n.producer(t, 25)
+
+	for _, test := range tests {
+
+		follower := n.node(t)
+
+		// Ranges alternate between the peers until one is dropped: the
+		// faulty peer serves 1-4, 9-12 and 17-20
+		peers := []Peer{
+			NewLocalPeer("faulty", faultySource{src, test.tamper}),
+			NewLocalPeer("honest", src),
+		}
+
+		r := quietReactor(follower, peers, Config{RangeSize: 4, Parallel: 2})
+
+		var dropped []string
+		r.Logf = func(format string, args ...interface{}) {
+			dropped = append(dropped, fmt.Sprintf(format, args...))
+		}
+
+		synced, err := r.Sync(context.Background())
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		if !synced || string(follower.Tip().Hash) != string(src.Tip().Hash) {
+			t.Fatalf("%s: follower stopped at height %d", test.name, follower.Height())
+		}
+
+		found := false
+		for _, line := range dropped {
+			if strings.HasPrefix(line, "Sync: dropping peer faulty") {
+				found = true
+			}
+		}
+
+		if !found {
+			t.Fatalf("%s: faulty peer was not dropped: %q", test.name, dropped)
+		}
+	}
+}
+
+// A peer reporting a height it does not have is dropped and reported,
+// and the node still catches up with the honest peer and produces.
+func TestSyncCatchesUpNextToFalseHeight(t *testing.T) {
+
+	n := newTestNet(t)
+	src := n.producer(t, 12)
+
+	follower := n.node(t)
+
+	peers := []Peer{
+		NewLocalPeer("liar", liarSource{src, 1000}),
+		NewLocalPeer("honest", src),
+	}
+
+	r := quietReactor(follower, peers, Config{RangeSize: 4, Parallel: 2, Interval: 10 * time.Millisecond})
+
+	reported := make(chan error, 16)
+	r.OnPeerError = func(peer string, err error) {
+		if peer == "liar" && errors.Is(err, ErrFalseHeight) {
+			select {
+			case reported <- err:
+			default:
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	caughtUp := make(chan uint64, 1)
+	done := make(chan error, 1)
+
+	go func() {
+		done <- r.Run(ctx, func() { caughtUp <- follower.Height() })
+	}()
+
+	select {
+	case h := <-caughtUp:
+		if h != 12 {
+			t.Fatalf("caught up at height %d", h)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("reactor did not catch up, stuck at height %d", follower.Height())
+	}
+
+	select {
+	case <-reported:
+	default:
+		t.Fatal("false height was not reported")
+	}
+
+	// Production continues on the synced chain
+	n.produce(t, follower, follower.verifier.State, 2)
+
+	if follower.Height() != 14 {
+		t.Fatalf("follower at height %d after producing", follower.Height())
+	}
+
+	cancel()
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// racingPeer serves src, but first stores the start of every range in
+// chain, as gossip may while the range is in flight.
+type racingPeer struct {
+	Peer
+	src   *Chain
+	chain *Chain
+}
+
+func (p racingPeer) Blocks(ctx context.Context, from, to uint64) ([]Committed, error) {
+
+	if from == p.chain.Height()+1 {
+
+		blocks, err := p.src.Blocks(from, from)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := p.chain.Append(blocks[0].Block, blocks[0].Commit); err != nil {
+			return nil, err
+		}
+	}
+
+	return p.Peer.Blocks(ctx, from, to)
+}
+
+// Blocks another path appended while their range was in flight are
+// skipped, without blaming the peer that served them.
+func TestSyncSkipsBlocksStoredMeanwhile(t *testing.T) {
+
+	n := newTestNet(t)
+	src := n.producer(t, 20)
+
+	follower := n.node(t)
+	peer := racingPeer{NewLocalPeer("honest", src), src, follower}
+
+	r := quietReactor(follower, []Peer{peer}, Config{RangeSize: 4, Parallel: 1})
+
+	r.OnPeerError = func(peer string, err error) {
+		t.Errorf("peer %s blamed: %v", peer, err)
+	}
+
+	synced, err := r.Sync(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !synced || string(follower.Tip().Hash) != string(src.Tip().Hash) {
+		t.Fatalf("follower stopped at height %d", follower.Height())
+	}
+}
+
+func TestRunFollowsPeers(t *testing.T) {
+
+	n := newTestNet(t)
+	src := n.producer(t, 12)
+
+	follower := n.node(t)
+	r := quietReactor(follower, []Peer{NewLocalPeer("src", src)}, Config{Interval: 10 * time.Millisecond})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	caughtUp := make(chan uint64, 1)
+	done := make(chan error, 1)
+
+	go func() {
+		done <- r.Run(ctx, func() { caughtUp <- follower.Height() })
+	}()
+
+	select {
+	case h := <-caughtUp:
+		if h != 12 {
+			t.Fatalf("caught up at height %d", h)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("reactor did not catch up")
+	}
+
+	// Blocks produced after catching up are followed
+	n.produce(t, src, src.verifier.State, 3)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for follower.Height() < 15 {
+		if time.Now().After(deadline) {
+			t.Fatalf("follower stuck at height %d", follower.Height())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestChainRejectsWithoutChanging(t *testing.T) {
+
+	n := newTestNet(t)
+	src := n.producer(t, 5)
+
+	blocks, err := src.Blocks(1, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := n.node(t)
+
+	for _, committed := range blocks[:4] {
+		if err := c.Append(committed.Block, committed.Commit); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Block 5 commits the state root: a wrong one is rejected, and the
+	// right block is still accepted after
+	last := blocks[4]
+	root := last.Block.StateRoot
+	last.Block.StateRoot = n.params.Hasher.Hash([]byte("other"))
+
+	if err := c.Append(last.Block, last.Commit); !errors.Is(err, ErrInvalidBlock) {
+		t.Fatal("block with a wrong state root should be invalid:", err)
+	}
+
+	last.Block.StateRoot = root
+
+	if err := c.Append(last.Block, last.Commit); err != nil {
+		t.Fatal("valid block rejected after an invalid one:", err)
+	}
+}
+
+func TestChainChecksStoredHeights(t *testing.T) {
+
+	n := newTestNet(t)
+	c := n.producer(t, 6)
+
+	stored, err := c.Blocks(3, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Check(stored[0].Block, stored[0].Commit); err != nil {
+		t.Fatal("stored block failed the check:", err)
+	}
+
+	// conflicting builds another block at height 3, signed by signer
+	// and committed by voters
+	conflicting := func(signer string, voters int) (*block.Block, *consensus.Certificate) {
+
+		node := n.nodes[signer]
+
+		tx := transaction.NewTransaction(node, "conflicting", "")
+		if err := tx.SignWith(node, n.params); err != nil {
+			t.Fatal(err)
+		}
+
+		b := block.NewBlock(3, 0, stored[0].Block.PreviousHash, []*transaction.Transaction{tx})
+		if err := b.FinalizeWith(node, n.params); err != nil {
+			t.Fatal(err)
+		}
+
+		commit := consensus.NewCertificate(3, 0, b.Hash)
+		for i := 1; i <= voters; i++ {
+			if err := commit.Sign(n.nodes[fmt.Sprintf("validator-%d", i)], n.params); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		return b, commit
+	}
+
+	leader, err := n.sched.GetLeader(3, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other := "validator-1"
+	if leader == other {
+		other = "validator-2"
+	}
+
+	// A quorum committing another block passes: it proves equivocation
+	b, commit := conflicting(leader, 4)
+	if err := c.Check(b, commit); err != nil {
+		t.Fatal("certified conflicting block failed the check:", err)
+	}
+
+	if string(b.Hash) == string(stored[0].Block.Hash) {
+		t.Fatal("conflicting block has the stored hash")
+	}
+
+	b, commit = conflicting(leader, 2)
+	if err := c.Check(b, commit); !errors.Is(err, ErrInvalidBlock) {
+		t.Fatal("conflicting block without a quorum should be invalid:", err)
+	}
+
+	b, commit = conflicting(other, 4)
+	if err := c.Check(b, commit); !errors.Is(err, ErrInvalidBlock) {
+		t.Fatal("conflicting block by another leader should be invalid:", err)
+	}
+
+	if c.Height() != 6 {
+		t.Fatalf("chain moved to height %d", c.Height())
+	}
+}
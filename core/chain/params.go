@@ -0,0 +1,126 @@
+package chain
+
+import (
+	"fmt"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
+)
+
+// Params are the chain-wide constants fixed at genesis that every
+// digest and signature depends on.
+type Params struct {
+	// ID separates signatures between chains (testnet vs mainnet).
+	ID string
+
+	// Hasher is the chain hash for transactions, Merkle nodes and blocks.
+	Hasher crypto.Hasher
+
+	// Algorithms is the signature algorithm migration schedule, ordered
+	// by height. Empty means any single algorithm the node verifies with.
+	Algorithms []AlgorithmEpoch
+
+	// GenesisHash is the canonical hash of the genesis document and the
+	// PreviousHash of the first block. Empty for chains without one.
+	GenesisHash []byte
+
+	// MaxBlockTxs and MaxBlockBytes (JSON size) bound a block; zero
+	// means unlimited.
+	MaxBlockTxs   int
+	MaxBlockBytes int
+
+	// AllowedAlgorithms restricts signature algorithms; empty allows all.
+	AllowedAlgorithms []string
+
+	// StateRootInterval is the spacing of blocks that commit the
+	// application state root in their header; zero means none do.
+	StateRootInterval int
+}
+
+// AlgorithmEpoch requires Algorithm for block and transaction
+// signatures from Height on, until the next epoch.
+type AlgorithmEpoch struct {
+	Height    int    `json:"height"`
+	Algorithm string `json:"algorithm"`
+}
+
+// DefaultID is used by nodes running without a genesis file.
+const DefaultID = "aegisq-local"
+
+// Default is the parameter set for a local chain: DefaultID and the
+// default hash.
+var Default = Params{ID: DefaultID, Hasher: crypto.DefaultHasher}
+
+// Domain returns the signature domain for messages of type t.
+func (p Params) Domain(t crypto.MessageType) crypto.Domain {
+	return crypto.Domain{ChainID: p.ID, Type: t}
+}
+
+// Allows reports whether alg may sign on this chain.
+func (p Params) Allows(alg string) bool {
+
+	if len(p.AllowedAlgorithms) == 0 {
+		return crypto.SupportedAlgorithm(alg)
+	}
+
+	for _, a := range p.AllowedAlgorithms {
+		if a == alg {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CommitsState reports whether the block at height carries a state
+// root.
+func (p Params) CommitsState(height int) bool {
+	return p.StateRootInterval > 0 && height > 0 && height%p.StateRootInterval == 0
+}
+
+// AlgorithmAt returns the signature algorithm required at height, or
+// "" when the chain has no migration schedule.
+func (p Params) AlgorithmAt(height int) string {
+
+	alg := ""
+
+	for _, e := range p.Algorithms {
+		if e.Height > height {
+			break
+		}
+		alg = e.Algorithm
+	}
+
+	return alg
+}
+
+// ValidateSchedule checks that the migration schedule starts at
+// height 0, names supported algorithms and switches algorithm at
+// strictly increasing heights.
+func (p Params) ValidateSchedule() error {
+
+	for i, e := range p.Algorithms {
+
+		if !crypto.SupportedAlgorithm(e.Algorithm) {
+			return fmt.Errorf("algorithm schedule: unsupported algorithm %q", e.Algorithm)
+		}
+
+		if i == 0 {
+			if e.Height != 0 {
+				return fmt.Errorf("algorithm schedule must start at height 0, not %d", e.Height)
+			}
+			continue
+		}
+
+		prev := p.Algorithms[i-1]
+
+		if e.Height <= prev.Height {
+			return fmt.Errorf("algorithm schedule heights must increase: %d after %d", e.Height, prev.Height)
+		}
+
+		if e.Algorithm == prev.Algorithm {
+			return fmt.Errorf("algorithm schedule repeats %s at height %d", e.Algorithm, e.Height)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package chain
+
+import "testing"
+
+func TestAlgorithmAt(t *testing.T) {
+
+	p := Params{Algorithms: []AlgorithmEpoch{
+		{Height: 0, Algorithm: "ECDSA_P256"},
+		{Height: 100, Algorithm: "dilithium2"},
+	}}
+
+	tests := map[int]string{
+		0:   "ECDSA_P256",
+		99:  "ECDSA_P256",
+		100: "dilithium2",
+		500: "dilithium2",
+	}
+
+	for height, want := range tests {
+		if got := p.AlgorithmAt(height); got != want {
+			t.Fatalf("height %d: got %q, want %q", height, got, want)
+		}
+	}
+
+	if Default.AlgorithmAt(10) != "" {
+		t.Fatal("chain without a schedule should not require an algorithm")
+	}
+}
+
+func TestValidateScheduleRejectsInvalid(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		schedule []AlgorithmEpoch
+	}{
+		{"not starting at 0", []AlgorithmEpoch{{Height: 5, Algorithm: "ed25519"}}},
+		{"unsupported algorithm", []AlgorithmEpoch{{Height: 0, Algorithm: "rsa"}}},
+		{"heights not increasing", []AlgorithmEpoch{
+			{Height: 0, Algorithm: "ed25519"},
+			{Height: 10, Algorithm: "ECDSA_P256"},
+			{Height: 10, Algorithm: "dilithium2"},
+		}},
+		{"repeated algorithm", []AlgorithmEpoch{
+			{Height: 0, Algorithm: "ed25519"},
+			{Height: 10, Algorithm: "ed25519"},
+		}},
+	}
+
+	for _, test := range tests {
+		if err := (Params{Algorithms: test.schedule}).ValidateSchedule(); err == nil {
+			t.Fatalf("%s: schedule should be rejected", test.name)
+		}
+	}
+
+	valid := Params{Algorithms: []AlgorithmEpoch{
+		{Height: 0, Algorithm: "ECDSA_P256"},
+		{Height: 100, Algorithm: "dilithium2"},
+	}}
+
+	if err := valid.ValidateSchedule(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,41 +1,402 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
+	"time"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/chain"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/consensus"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/state"
 )
 
-// Genesis defines initial validator trust root.
+// Genesis is the document every node of a chain starts from: the chain
+// parameters, the initial validators and the initial application state.
+//
+// ChainID separates this chain's signatures from every other chain's;
+// HashAlgorithm names the chain hash (see crypto.NewHasher) and
+// defaults to sha3-256. AlgorithmSchedule, when set, fixes the
+// signature algorithm per height range (see chain.AlgorithmEpoch).
+//
+// The canonical genesis hash (see Hash) is the PreviousHash of the
+// first block, anchoring the chain to this document.
 type Genesis struct {
-	Validators []string `json:"validators"`
+	ChainID           string                 `json:"chain_id"`
+	GenesisTime       time.Time              `json:"genesis_time"`
+	HashAlgorithm     string                 `json:"hash_algorithm,omitempty"`
+	AlgorithmSchedule []chain.AlgorithmEpoch `json:"algorithm_schedule,omitempty"`
+	Consensus         ConsensusParams        `json:"consensus"`
+	Validators        []GenesisValidator     `json:"validators"`
+	AppState          json.RawMessage        `json:"app_state,omitempty"`
+}
+
+// GenesisValidator is an initial validator. PublicKey is the raw key
+// of Algorithm, base64 in JSON.
+type GenesisValidator struct {
+	NodeID    string `json:"node_id"`
+	Algorithm string `json:"algorithm"`
+	PublicKey []byte `json:"public_key"`
+	Power     int64  `json:"power"`
+}
+
+// ConsensusParams bound what validators may propose and how long
+// consensus rounds wait. Zero limits mean unlimited; an empty
+// AllowedAlgorithms allows every supported algorithm. Every block at a
+// multiple of StateRootInterval commits the application state root;
+// zero disables state roots and with them state sync.
+type ConsensusParams struct {
+	MaxBlockTxs       int      `json:"max_block_txs"`
+	MaxBlockBytes     int      `json:"max_block_bytes"`
+	ProposeTimeout    Duration `json:"propose_timeout"`
+	CommitTimeout     Duration `json:"commit_timeout"`
+	AllowedAlgorithms []string `json:"allowed_algorithms,omitempty"`
+	StateRootInterval int      `json:"state_root_interval,omitempty"`
+}
+
+// DefaultConsensusParams fit a block of 10,000 Dilithium transactions.
+var DefaultConsensusParams = ConsensusParams{
+	MaxBlockTxs:       10000,
+	MaxBlockBytes:     64 << 20,
+	ProposeTimeout:    Duration(3 * time.Second),
+	CommitTimeout:     Duration(1 * time.Second),
+	StateRootInterval: 100,
+}
+
+// Duration is a time.Duration written as a string such as "3s".
+type Duration time.Duration
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"3s\": %w", err)
+	}
+
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(v)
+	return nil
+}
+
+// Hasher returns the chain hash declared in genesis.
+func (g *Genesis) Hasher() (crypto.Hasher, error) {
+	return crypto.NewHasher(g.HashAlgorithm)
+}
+
+// canonical returns the byte encoding the genesis hash is taken over:
+// compact JSON in field order, the time in UTC and the app state
+// compacted, so formatting of the file does not change the hash.
+func (g *Genesis) canonical() ([]byte, error) {
+
+	c := *g
+	c.GenesisTime = g.GenesisTime.UTC()
+
+	if len(g.AppState) > 0 {
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, g.AppState); err != nil {
+			return nil, fmt.Errorf("app_state: %w", err)
+		}
+		c.AppState = buf.Bytes()
+	}
+
+	return json.Marshal(&c)
+}
+
+// Hash returns the canonical genesis hash under the chain hash.
+func (g *Genesis) Hash() ([]byte, error) {
+
+	h, err := g.Hasher()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := g.canonical()
+	if err != nil {
+		return nil, err
+	}
+
+	return h.Hash(data), nil
+}
+
+// Params returns the chain parameters declared in genesis.
+func (g *Genesis) Params() (chain.Params, error) {
+
+	h, err := g.Hasher()
+	if err != nil {
+		return chain.Params{}, err
+	}
+
+	genesisHash, err := g.Hash()
+	if err != nil {
+		return chain.Params{}, err
+	}
+
+	p := chain.Params{
+		ID:                g.ChainID,
+		Hasher:            h,
+		Algorithms:        g.AlgorithmSchedule,
+		GenesisHash:       genesisHash,
+		MaxBlockTxs:       g.Consensus.MaxBlockTxs,
+		MaxBlockBytes:     g.Consensus.MaxBlockBytes,
+		AllowedAlgorithms: g.Consensus.AllowedAlgorithms,
+		StateRootInterval: g.Consensus.StateRootInterval,
+	}
+
+	// Validate the ID the same way signing will.
+	if _, err := p.Domain(crypto.MessageBlock).Context(); err != nil {
+		return chain.Params{}, fmt.Errorf("invalid chain_id %q", g.ChainID)
+	}
+
+	if err := p.ValidateSchedule(); err != nil {
+		return chain.Params{}, err
+	}
+
+	return p, nil
+}
+
+// Validate checks the whole document: chain parameters, consensus
+// limits and every validator entry.
+func (g *Genesis) Validate() error {
+
+	p, err := g.Params()
+	if err != nil {
+		return err
+	}
+
+	if g.GenesisTime.IsZero() {
+		return errors.New("genesis_time must be set")
+	}
+
+	c := g.Consensus
+
+	if c.MaxBlockTxs < 0 || c.MaxBlockBytes < 0 {
+		return errors.New("consensus block limits must not be negative")
+	}
+
+	if c.ProposeTimeout < 0 || c.CommitTimeout < 0 {
+		return errors.New("consensus timeouts must not be negative")
+	}
+
+	if c.StateRootInterval < 0 {
+		return errors.New("state_root_interval must not be negative")
+	}
+
+	for _, alg := range c.AllowedAlgorithms {
+		if !crypto.SupportedAlgorithm(alg) {
+			return fmt.Errorf("allowed_algorithms: unsupported algorithm %q", alg)
+		}
+	}
+
+	for _, e := range g.AlgorithmSchedule {
+		if !p.Allows(e.Algorithm) {
+			return fmt.Errorf("algorithm_schedule: %s is not an allowed algorithm", e.Algorithm)
+		}
+	}
+
+	if len(g.Validators) == 0 {
+		return errors.New("genesis must contain at least one validator")
+	}
+
+	nodeIDs := make(map[string]bool)
+	keys := make(map[string]bool)
+
+	for i, v := range g.Validators {
+
+		if err := v.validate(p); err != nil {
+			return fmt.Errorf("genesis validator %d: %w", i, err)
+		}
+
+		if nodeIDs[v.NodeID] {
+			return fmt.Errorf("genesis validator %d: duplicate node_id %s", i, v.NodeID)
+		}
+
+		if keys[string(v.PublicKey)] {
+			return fmt.Errorf("genesis validator %d: duplicate public_key", i)
+		}
+
+		nodeIDs[v.NodeID] = true
+		keys[string(v.PublicKey)] = true
+	}
+
+	if len(g.AppState) > 0 && !json.Valid(g.AppState) {
+		return errors.New("app_state must be valid JSON")
+	}
+
+	return nil
 }
 
-// LoadGenesis loads genesis configuration from file.
+func (v *GenesisValidator) validate(p chain.Params) error {
+
+	if v.NodeID == "" {
+		return errors.New("node_id must be set")
+	}
+
+	// Quorums count validators, so a weight other than 1 would be
+	// recorded but silently ignored
+	if v.Power != 1 {
+		return fmt.Errorf("power must be 1, got %d: voting is not weighted", v.Power)
+	}
+
+	if !p.Allows(v.Algorithm) {
+		return fmt.Errorf("algorithm %s is not allowed", v.Algorithm)
+	}
+
+	// Genesis keys sign from height 0
+	if alg := p.AlgorithmAt(0); alg != "" && v.Algorithm != alg {
+		return fmt.Errorf("algorithm %s, but the chain starts on %s", v.Algorithm, alg)
+	}
+
+	// Encoding the key checks its length and, for ECDSA, the curve point
+	if _, err := crypto.MarshalPublicKeyPEM(v.Algorithm, v.PublicKey); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ValidatorSet returns the initial validator set.
+func (g *Genesis) ValidatorSet() (*consensus.ValidatorSet, error) {
+
+	vs := consensus.NewValidatorSet()
+
+	for _, v := range g.Validators {
+		if err := vs.AddValidator(v.NodeID, v.PublicKey); err != nil {
+			return nil, err
+		}
+	}
+
+	return vs, nil
+}
+
+// State returns the application state at genesis.
+func (g *Genesis) State() (*state.State, error) {
+
+	validators := make([]state.Validator, 0, len(g.Validators))
+
+	for _, v := range g.Validators {
+		validators = append(validators, state.Validator{
+			NodeID:    v.NodeID,
+			Algorithm: v.Algorithm,
+			PublicKey: v.PublicKey,
+		})
+	}
+
+	return state.New(validators)
+}
+
+// Validator returns the genesis entry for nodeID.
+func (g *Genesis) Validator(nodeID string) (GenesisValidator, bool) {
+
+	for _, v := range g.Validators {
+		if v.NodeID == nodeID {
+			return v, true
+		}
+	}
+
+	return GenesisValidator{}, false
+}
+
+// LoadGenesis loads and validates a genesis document.
 func LoadGenesis(path string) (*Genesis, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 
+	g, err := ParseGenesis(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return g, nil
+}
+
+// ParseGenesis decodes and validates a genesis document.
+func ParseGenesis(data []byte) (*Genesis, error) {
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
 	var g Genesis
-	if err := json.Unmarshal(data, &g); err != nil {
+	if err := dec.Decode(&g); err != nil {
 		return nil, err
 	}
 
-	if len(g.Validators) == 0 {
-		return nil, errors.New("genesis must contain at least one validator")
+	if err := g.Validate(); err != nil {
+		return nil, err
 	}
 
 	return &g, nil
 }
 
-// IsValidator checks if provided public key (base64) is authorized.
-func (g *Genesis) IsValidator(pubKeyBase64 string) bool {
-	for _, v := range g.Validators {
-		if v == pubKeyBase64 {
-			return true
-		}
+// Save writes the genesis document to path, indented for editing. An
+// existing file is never overwritten.
+func (g *Genesis) Save(path string) error {
+	return g.write(path, os.O_EXCL)
+}
+
+// Replace atomically rewrites the genesis document at path, so a
+// reader never sees a partially written file.
+func (g *Genesis) Replace(path string) error {
+
+	tmp := path + ".tmp"
+
+	if err := g.write(tmp, os.O_TRUNC); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return nil
+}
+
+func (g *Genesis) write(path string, flag int) error {
+
+	data, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|flag, 0644)
+	if err != nil {
+		return err
 	}
-	return false
-}
\ No newline at end of file
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// AddValidator appends a validator and re-validates the document.
+func (g *Genesis) AddValidator(v GenesisValidator) error {
+
+	g.Validators = append(g.Validators, v)
+
+	if err := g.Validate(); err != nil {
+		g.Validators = g.Validators[:len(g.Validators)-1]
+		return err
+	}
+
+	return nil
+}
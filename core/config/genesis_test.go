@@ -0,0 +1,154 @@
+package config
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/identity"
+)
+
+func testGenesis(t *testing.T) *Genesis {
+
+	signer := &crypto.Ed25519Signer{}
+
+	g := &Genesis{
+		ChainID:     "aegisq-testnet",
+		GenesisTime: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+		Consensus:   DefaultConsensusParams,
+		AppState:    json.RawMessage(`{"accounts": []}`),
+	}
+
+	for _, id := range []string{"validator-1", "validator-2"} {
+		node, err := identity.NewNodeIdentity(id, signer)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		g.Validators = append(g.Validators, GenesisValidator{
+			NodeID:    id,
+			Algorithm: node.Algorithm(),
+			PublicKey: node.PublicKey,
+			Power:     1,
+		})
+	}
+
+	if err := g.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	return g
+}
+
+func TestGenesisSaveLoadKeepsHash(t *testing.T) {
+
+	g := testGenesis(t)
+
+	path := filepath.Join(t.TempDir(), "genesis.json")
+	if err := g.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.Save(path); err == nil {
+		t.Fatal("existing genesis must not be overwritten")
+	}
+
+	loaded, err := LoadGenesis(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, _ := g.Hash()
+	got, _ := loaded.Hash()
+
+	if string(want) != string(got) {
+		t.Fatal("genesis hash changed across save and load")
+	}
+
+	vs, err := loaded.ValidatorSet()
+	if err != nil || vs.Count() != 2 {
+		t.Fatal("validator set not built from genesis")
+	}
+}
+
+func TestGenesisHashIsCanonical(t *testing.T) {
+
+	g := testGenesis(t)
+	base, _ := g.Hash()
+
+	// Formatting of the app state and the time zone do not matter
+	g.AppState = json.RawMessage("{\n  \"accounts\" : [ ]\n}")
+	g.GenesisTime = g.GenesisTime.In(time.FixedZone("UTC+2", 2*60*60))
+
+	same, err := g.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(same) != string(base) {
+		t.Fatal("equivalent genesis documents hash differently")
+	}
+
+	// Content does
+	g.Validators[0].Power = 2
+
+	changed, _ := g.Hash()
+	if string(changed) == string(base) {
+		t.Fatal("validator power is not covered by the genesis hash")
+	}
+}
+
+func TestGenesisParams(t *testing.T) {
+
+	g := testGenesis(t)
+
+	p, err := g.Params()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, _ := g.Hash()
+
+	if p.ID != g.ChainID || string(p.GenesisHash) != string(hash) {
+		t.Fatal("chain parameters do not match genesis")
+	}
+
+	if p.MaxBlockTxs != DefaultConsensusParams.MaxBlockTxs {
+		t.Fatal("block limits not carried into chain parameters")
+	}
+}
+
+func TestGenesisValidateRejects(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		mutate func(g *Genesis)
+	}{
+		{"missing chain id", func(g *Genesis) { g.ChainID = "" }},
+		{"missing genesis time", func(g *Genesis) { g.GenesisTime = time.Time{} }},
+		{"no validators", func(g *Genesis) { g.Validators = nil }},
+		{"duplicate node id", func(g *Genesis) { g.Validators[1].NodeID = g.Validators[0].NodeID }},
+		{"duplicate key", func(g *Genesis) { g.Validators[1].PublicKey = g.Validators[0].PublicKey }},
+		{"zero power", func(g *Genesis) { g.Validators[0].Power = 0 }},
+		{"weighted power", func(g *Genesis) { g.Validators[0].Power = 2 }},
+		{"malformed key", func(g *Genesis) { g.Validators[0].PublicKey = []byte("short") }},
+		{"disallowed algorithm", func(g *Genesis) { g.Consensus.AllowedAlgorithms = []string{"dilithium2"} }},
+		{"unsupported allowed algorithm", func(g *Genesis) { g.Consensus.AllowedAlgorithms = []string{"rsa"} }},
+		{"negative limit", func(g *Genesis) { g.Consensus.MaxBlockTxs = -1 }},
+		{"negative timeout", func(g *Genesis) { g.Consensus.CommitTimeout = Duration(-time.Second) }},
+		{"invalid app state", func(g *Genesis) { g.AppState = json.RawMessage("{") }},
+		{"unknown hash", func(g *Genesis) { g.HashAlgorithm = "md5" }},
+	}
+
+	for _, test := range tests {
+
+		g := testGenesis(t)
+		test.mutate(g)
+
+		if err := g.Validate(); err == nil {
+			t.Fatalf("%s: genesis should be rejected", test.name)
+		}
+	}
+}
@@ -0,0 +1,477 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// NodeConfigFile is the node configuration file inside the home
+// directory.
+const NodeConfigFile = "config.yaml"
+
+// NodeConfig is the local configuration of one node. Unlike genesis it
+// differs between nodes of the same chain. Relative paths are resolved
+// against Home.
+//
+// Values are layered: defaults, then config.yaml, then AEGISQ_*
+// environment variables, then command-line flags.
+type NodeConfig struct {
+	Home string `yaml:"-"`
+
+	DBPath      string `yaml:"db_path"`
+	GenesisFile string `yaml:"genesis_file"`
+
+	API       APIConfig       `yaml:"api"`
+	P2P       P2PConfig       `yaml:"p2p"`
+	Validator ValidatorConfig `yaml:"validator"`
+	Block     BlockConfig     `yaml:"block"`
+	Mempool   MempoolConfig   `yaml:"mempool"`
+	Snapshot  SnapshotConfig  `yaml:"snapshot"`
+	Sync      SyncConfig      `yaml:"sync"`
+	Gossip    GossipConfig    `yaml:"gossip"`
+	Consensus TimeoutConfig   `yaml:"consensus"`
+	Dev       DevConfig       `yaml:"dev"`
+}
+
+type APIConfig struct {
+	Listen string `yaml:"listen"`
+}
+
+// P2PConfig controls the peers this node connects to. Peers are
+// persistent: always connected and redialled after any failure. Seeds
+// are only asked for more addresses, kept in the AddrBook file, of
+// which up to MaxOutbound are connected. Up to MaxInbound other hosts
+// are served at once. A host whose peers misbehave is banned for
+// BanDuration, doubling with every repeat.
+type P2PConfig struct {
+	Listen          string   `yaml:"listen"`
+	Peers           []string `yaml:"peers"`
+	Seeds           []string `yaml:"seeds"`
+	MaxInbound      int      `yaml:"max_inbound"`
+	MaxOutbound     int      `yaml:"max_outbound"`
+	ExternalAddress string   `yaml:"external_address"`
+	AddrBook        string   `yaml:"addr_book"`
+	BanDuration     Duration `yaml:"ban_duration"`
+}
+
+// ValidatorConfig locates the validator keys this node signs with:
+// keystores named <node-id>.json in KeystoreDir, or remote signers by
+// node ID. The keystore passphrase is only read from the environment.
+type ValidatorConfig struct {
+	KeystoreDir   string            `yaml:"keystore_dir"`
+	RemoteSigners map[string]string `yaml:"remote_signers"`
+}
+
+// BlockConfig controls the blocks this node proposes. MaxTxs may only
+// tighten the genesis limit; zero means the genesis limit.
+//
+// A block is proposed every Interval. With EmptyBlocks off the node
+// waits for pending transactions instead, proposing as soon as one
+// arrives (but no sooner than Interval after the last block), and an
+// empty block only once EmptyBlockInterval has passed; zero means
+// never. SyntheticTxs adds generated load to every block.
+type BlockConfig struct {
+	MaxTxs             int      `yaml:"max_txs"`
+	Interval           Duration `yaml:"interval"`
+	EmptyBlocks        bool     `yaml:"empty_blocks"`
+	EmptyBlockInterval Duration `yaml:"empty_block_interval"`
+	SyntheticTxs       int      `yaml:"synthetic_txs"`
+}
+
+// MempoolConfig bounds the pending transaction pool.
+type MempoolConfig struct {
+	Size int `yaml:"size"`
+}
+
+// SnapshotConfig controls the state snapshots this node takes and
+// serves to syncing nodes: one every Interval blocks, which must be a
+// multiple of the chain's state root interval; zero takes none. Only
+// the KeepRecent most recent snapshots are kept.
+type SnapshotConfig struct {
+	Interval   int `yaml:"interval"`
+	KeepRecent int `yaml:"keep_recent"`
+}
+
+// SyncConfig controls how this node catches up with its peers: it
+// asks for RangeSize blocks per request, with up to Parallel ranges in
+// flight, and polls the peers' heights every Interval.
+type SyncConfig struct {
+	RangeSize int      `yaml:"range_size"`
+	Parallel  int      `yaml:"parallel"`
+	Interval  Duration `yaml:"interval"`
+}
+
+// GossipConfig bounds the transactions and proposals relayed to each
+// peer: up to QueueSize wait for a peer, sent BatchSize at a time.
+type GossipConfig struct {
+	QueueSize int `yaml:"queue_size"`
+	BatchSize int `yaml:"batch_size"`
+}
+
+// TimeoutConfig overrides the genesis consensus timeouts locally;
+// zero keeps the genesis value.
+type TimeoutConfig struct {
+	ProposeTimeout Duration `yaml:"propose_timeout"`
+	CommitTimeout  Duration `yaml:"commit_timeout"`
+}
+
+// DevConfig applies only when the node runs without a genesis file.
+type DevConfig struct {
+	Validators int `yaml:"validators"`
+
+	// EphemeralKeys generates new validator keys on every start when
+	// AEGISQ_PASSPHRASE is not set, so the chain cannot be restarted.
+	EphemeralKeys bool `yaml:"ephemeral_keys"`
+}
+
+// DefaultNodeConfig returns the configuration of a node run from home
+// without a config file.
+func DefaultNodeConfig(home string) *NodeConfig {
+	return &NodeConfig{
+		Home:        home,
+		DBPath:      "aegisq.db",
+		GenesisFile: "genesis.json",
+		API:         APIConfig{Listen: ":8080"},
+		P2P:         P2PConfig{Listen: ":26656", MaxInbound: 40, MaxOutbound: 10, AddrBook: "addrbook.json", BanDuration: Duration(10 * time.Minute)},
+		Validator:   ValidatorConfig{KeystoreDir: "keystore"},
+		Block:       BlockConfig{Interval: Duration(time.Second), EmptyBlocks: true},
+		Mempool:     MempoolConfig{Size: 50000},
+		Snapshot:    SnapshotConfig{KeepRecent: 2},
+		Sync:        SyncConfig{RangeSize: 100, Parallel: 4, Interval: Duration(time.Second)},
+		Gossip:      GossipConfig{QueueSize: 1000, BatchSize: 100},
+		Dev:         DevConfig{Validators: 4},
+	}
+}
+
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return time.Duration(d).String(), nil
+}
+
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+
+	v, err := time.ParseDuration(value.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: duration must look like \"3s\": %w", value.Line, err)
+	}
+
+	*d = Duration(v)
+	return nil
+}
+
+// LoadNodeConfig reads home/config.yaml over the defaults. A missing
+// file leaves the defaults; unknown keys are rejected so typos do not
+// pass silently.
+func LoadNodeConfig(home string) (*NodeConfig, error) {
+
+	cfg := DefaultNodeConfig(home)
+
+	path := filepath.Join(home, NodeConfigFile)
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	cfg.Home = home
+	return cfg, nil
+}
+
+// ApplyEnv overrides settings from AEGISQ_* environment variables.
+func (c *NodeConfig) ApplyEnv(getenv func(string) string) error {
+
+	if v := getenv("AEGISQ_DB"); v != "" {
+		c.DBPath = v
+	}
+
+	if v := getenv("AEGISQ_GENESIS"); v != "" {
+		c.GenesisFile = v
+	}
+
+	if v := getenv("AEGISQ_API_LISTEN"); v != "" {
+		c.API.Listen = v
+	}
+
+	if v := getenv("AEGISQ_P2P_LISTEN"); v != "" {
+		c.P2P.Listen = v
+	}
+
+	if v := getenv("AEGISQ_PEERS"); v != "" {
+		c.P2P.Peers = SplitList(v)
+	}
+
+	if v := getenv("AEGISQ_SEEDS"); v != "" {
+		c.P2P.Seeds = SplitList(v)
+	}
+
+	if v := getenv("AEGISQ_REMOTE_SIGNERS"); v != "" {
+		signers, err := ParseRemoteSigners(v)
+		if err != nil {
+			return fmt.Errorf("AEGISQ_REMOTE_SIGNERS: %w", err)
+		}
+		c.Validator.RemoteSigners = signers
+	}
+
+	return nil
+}
+
+// Save writes the configuration to home/config.yaml. An existing file
+// is never overwritten.
+func (c *NodeConfig) Save() error {
+
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(filepath.Join(c.Home, NodeConfigFile), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// Path resolves a configured path against the home directory.
+func (c *NodeConfig) Path(p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(c.Home, p)
+}
+
+// Validate checks every setting and names the offending key.
+func (c *NodeConfig) Validate() error {
+
+	if c.DBPath == "" {
+		return errors.New("db_path must be set")
+	}
+
+	if c.GenesisFile == "" {
+		return errors.New("genesis_file must be set")
+	}
+
+	if err := checkListen(c.API.Listen); err != nil {
+		return fmt.Errorf("api.listen: %w", err)
+	}
+
+	if err := checkListen(c.P2P.Listen); err != nil {
+		return fmt.Errorf("p2p.listen: %w", err)
+	}
+
+	for _, peer := range c.P2P.Peers {
+		if _, port, err := net.SplitHostPort(peer); err != nil || port == "" {
+			return fmt.Errorf("p2p.peers: %q is not host:port", peer)
+		}
+	}
+
+	for _, seed := range c.P2P.Seeds {
+		if _, port, err := net.SplitHostPort(seed); err != nil || port == "" {
+			return fmt.Errorf("p2p.seeds: %q is not host:port", seed)
+		}
+	}
+
+	if c.P2P.MaxInbound < 0 {
+		return errors.New("p2p.max_inbound must not be negative")
+	}
+
+	if c.P2P.MaxOutbound < 0 {
+		return errors.New("p2p.max_outbound must not be negative")
+	}
+
+	if a := c.P2P.ExternalAddress; a != "" {
+		if _, port, err := net.SplitHostPort(a); err != nil || port == "" {
+			return fmt.Errorf("p2p.external_address: %q is not host:port", a)
+		}
+	}
+
+	if c.P2P.AddrBook == "" {
+		return errors.New("p2p.addr_book must be set")
+	}
+
+	if c.P2P.BanDuration <= 0 {
+		return errors.New("p2p.ban_duration must be positive")
+	}
+
+	if c.Validator.KeystoreDir == "" {
+		return errors.New("validator.keystore_dir must be set")
+	}
+
+	for nodeID, addr := range c.Validator.RemoteSigners {
+		if nodeID == "" || addr == "" {
+			return fmt.Errorf("validator.remote_signers: invalid entry %q: %q", nodeID, addr)
+		}
+	}
+
+	if c.Block.MaxTxs < 0 {
+		return errors.New("block.max_txs must not be negative")
+	}
+
+	if c.Block.Interval <= 0 {
+		return errors.New("block.interval must be positive")
+	}
+
+	if c.Block.EmptyBlockInterval < 0 {
+		return errors.New("block.empty_block_interval must not be negative")
+	}
+
+	if c.Mempool.Size < 0 {
+		return errors.New("mempool.size must not be negative")
+	}
+
+	if c.Block.SyntheticTxs < 0 {
+		return errors.New("block.synthetic_txs must not be negative")
+	}
+
+	if c.Block.MaxTxs > 0 && c.Block.SyntheticTxs > c.Block.MaxTxs {
+		return fmt.Errorf("block.synthetic_txs (%d) exceeds block.max_txs (%d)", c.Block.SyntheticTxs, c.Block.MaxTxs)
+	}
+
+	if c.Snapshot.Interval < 0 {
+		return errors.New("snapshot.interval must not be negative")
+	}
+
+	if c.Snapshot.KeepRecent < 1 {
+		return errors.New("snapshot.keep_recent must be at least 1")
+	}
+
+	if c.Sync.RangeSize < 1 || c.Sync.RangeSize > 500 {
+		return errors.New("sync.range_size must be between 1 and 500")
+	}
+
+	if c.Sync.Parallel < 1 {
+		return errors.New("sync.parallel must be at least 1")
+	}
+
+	if c.Sync.Interval <= 0 {
+		return errors.New("sync.interval must be positive")
+	}
+
+	if c.Gossip.QueueSize < 1 {
+		return errors.New("gossip.queue_size must be at least 1")
+	}
+
+	if c.Gossip.BatchSize < 1 || c.Gossip.BatchSize > c.Gossip.QueueSize {
+		return errors.New("gossip.batch_size must be between 1 and gossip.queue_size")
+	}
+
+	if c.Consensus.ProposeTimeout < 0 || c.Consensus.CommitTimeout < 0 {
+		return errors.New("consensus timeouts must not be negative")
+	}
+
+	if c.Dev.Validators < 1 {
+		return errors.New("dev.validators must be at least 1")
+	}
+
+	return nil
+}
+
+// CheckGenesis validates the settings that depend on the chain.
+func (c *NodeConfig) CheckGenesis(g *Genesis) error {
+
+	limit := g.Consensus.MaxBlockTxs
+
+	if limit > 0 && c.Block.MaxTxs > limit {
+		return fmt.Errorf("block.max_txs (%d) exceeds the genesis limit of %d", c.Block.MaxTxs, limit)
+	}
+
+	if limit > 0 && c.Block.SyntheticTxs > limit {
+		return fmt.Errorf("block.synthetic_txs (%d) exceeds the genesis limit of %d", c.Block.SyntheticTxs, limit)
+	}
+
+	if every := c.Snapshot.Interval; every > 0 {
+
+		roots := g.Consensus.StateRootInterval
+
+		if roots == 0 {
+			return errors.New("snapshot.interval is set, but the chain commits no state roots (genesis state_root_interval)")
+		}
+
+		if every%roots != 0 {
+			return fmt.Errorf("snapshot.interval (%d) must be a multiple of the genesis state_root_interval (%d)", every, roots)
+		}
+	}
+
+	return nil
+}
+
+// Timeouts returns the propose and commit timeouts: local overrides,
+// else the genesis values.
+func (c *NodeConfig) Timeouts(g *Genesis) (propose, commit time.Duration) {
+
+	propose = time.Duration(g.Consensus.ProposeTimeout)
+	commit = time.Duration(g.Consensus.CommitTimeout)
+
+	if c.Consensus.ProposeTimeout > 0 {
+		propose = time.Duration(c.Consensus.ProposeTimeout)
+	}
+
+	if c.Consensus.CommitTimeout > 0 {
+		commit = time.Duration(c.Consensus.CommitTimeout)
+	}
+
+	return propose, commit
+}
+
+func checkListen(addr string) error {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return fmt.Errorf("%q is not [host]:port", addr)
+	}
+	return nil
+}
+
+// SplitList splits a comma-separated list, dropping empty entries.
+func SplitList(value string) []string {
+
+	var out []string
+
+	for _, s := range strings.Split(value, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+
+	return out
+}
+
+// ParseRemoteSigners reads a comma-separated list of nodeID=address
+// pairs, e.g. "validator-1=unix:/run/aegisq/v1.sock".
+func ParseRemoteSigners(value string) (map[string]string, error) {
+
+	signers := make(map[string]string)
+
+	for _, entry := range SplitList(value) {
+
+		nodeID, addr, ok := strings.Cut(entry, "=")
+		if !ok || nodeID == "" || addr == "" {
+			return nil, fmt.Errorf("invalid entry: %q", entry)
+		}
+
+		signers[nodeID] = addr
+	}
+
+	return signers, nil
+}
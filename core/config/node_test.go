@@ -0,0 +1,173 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeNodeConfig(t *testing.T, home string, data string) {
+	if err := os.WriteFile(filepath.Join(home, NodeConfigFile), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadNodeConfigDefaults(t *testing.T) {
+
+	home := t.TempDir()
+
+	cfg, err := LoadNodeConfig(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Path(cfg.DBPath) != filepath.Join(home, "aegisq.db") {
+		t.Fatalf("db path not resolved against home: %s", cfg.Path(cfg.DBPath))
+	}
+
+	if cfg.API.Listen != ":8080" || cfg.Dev.Validators != 4 {
+		t.Fatalf("unexpected defaults: %+v", cfg)
+	}
+}
+
+func TestNodeConfigLayering(t *testing.T) {
+
+	home := t.TempDir()
+
+	writeNodeConfig(t, home, `
+db_path: /var/lib/aegisq/chain.db
+api:
+  listen: 127.0.0.1:9000
+p2p:
+  peers: [10.0.0.2:26656]
+block:
+  interval: 500ms
+  empty_blocks: false
+consensus:
+  propose_timeout: 5s
+`)
+
+	cfg, err := LoadNodeConfig(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Unset keys keep their defaults
+	if cfg.GenesisFile != "genesis.json" || cfg.P2P.Listen != ":26656" {
+		t.Fatalf("defaults lost: %+v", cfg)
+	}
+
+	if cfg.Block.EmptyBlocks || cfg.Block.Interval != Duration(500*time.Millisecond) {
+		t.Fatalf("block policy not loaded: %+v", cfg.Block)
+	}
+
+	if cfg.Path(cfg.DBPath) != "/var/lib/aegisq/chain.db" {
+		t.Fatalf("absolute path rewritten: %s", cfg.Path(cfg.DBPath))
+	}
+
+	env := map[string]string{
+		"AEGISQ_API_LISTEN":     ":9100",
+		"AEGISQ_PEERS":          "10.0.0.3:26656, 10.0.0.4:26656",
+		"AEGISQ_REMOTE_SIGNERS": "validator-1=unix:/run/v1.sock",
+	}
+
+	if err := cfg.ApplyEnv(func(k string) string { return env[k] }); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.API.Listen != ":9100" {
+		t.Fatalf("environment must override the file: %s", cfg.API.Listen)
+	}
+
+	if len(cfg.P2P.Peers) != 2 || cfg.P2P.Peers[1] != "10.0.0.4:26656" {
+		t.Fatalf("peers: %v", cfg.P2P.Peers)
+	}
+
+	if cfg.Validator.RemoteSigners["validator-1"] != "unix:/run/v1.sock" {
+		t.Fatalf("remote signers: %v", cfg.Validator.RemoteSigners)
+	}
+
+	g := &Genesis{Consensus: DefaultConsensusParams}
+
+	propose, commit := cfg.Timeouts(g)
+	if propose != 5*time.Second || commit != time.Second {
+		t.Fatalf("timeouts: propose %s commit %s", propose, commit)
+	}
+}
+
+func TestLoadNodeConfigRejectsUnknownKeys(t *testing.T) {
+
+	home := t.TempDir()
+	writeNodeConfig(t, home, "api:\n  listne: :9000\n")
+
+	if _, err := LoadNodeConfig(home); err == nil || !strings.Contains(err.Error(), "listne") {
+		t.Fatalf("unknown key should be rejected, got %v", err)
+	}
+}
+
+func TestNodeConfigSaveRoundTrip(t *testing.T) {
+
+	home := t.TempDir()
+
+	cfg := DefaultNodeConfig(home)
+	cfg.Consensus.CommitTimeout = Duration(2 * time.Second)
+
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cfg.Save(); err == nil {
+		t.Fatal("existing config must not be overwritten")
+	}
+
+	loaded, err := LoadNodeConfig(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded.Consensus.CommitTimeout != cfg.Consensus.CommitTimeout || loaded.DBPath != cfg.DBPath {
+		t.Fatalf("round trip changed the config: %+v", loaded)
+	}
+}
+
+func TestNodeConfigValidateRejects(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		mutate func(c *NodeConfig)
+	}{
+		{"empty db path", func(c *NodeConfig) { c.DBPath = "" }},
+		{"bad api listen", func(c *NodeConfig) { c.API.Listen = "8080" }},
+		{"bad peer", func(c *NodeConfig) { c.P2P.Peers = []string{"10.0.0.2"} }},
+		{"negative max txs", func(c *NodeConfig) { c.Block.MaxTxs = -1 }},
+		{"zero interval", func(c *NodeConfig) { c.Block.Interval = 0 }},
+		{"negative empty interval", func(c *NodeConfig) { c.Block.EmptyBlockInterval = Duration(-time.Second) }},
+		{"negative mempool size", func(c *NodeConfig) { c.Mempool.Size = -1 }},
+		{"synthetic above max", func(c *NodeConfig) { c.Block.MaxTxs = 10; c.Block.SyntheticTxs = 11 }},
+		{"negative timeout", func(c *NodeConfig) { c.Consensus.ProposeTimeout = Duration(-time.Second) }},
+		{"no dev validators", func(c *NodeConfig) { c.Dev.Validators = 0 }},
+	}
+
+	for _, test := range tests {
+
+		cfg := DefaultNodeConfig(t.TempDir())
+		test.mutate(cfg)
+
+		if err := cfg.Validate(); err == nil {
+			t.Fatalf("%s: config should be rejected", test.name)
+		}
+	}
+
+	cfg := DefaultNodeConfig(t.TempDir())
+	cfg.Block.MaxTxs = DefaultConsensusParams.MaxBlockTxs + 1
+
+	if err := cfg.CheckGenesis(&Genesis{Consensus: DefaultConsensusParams}); err == nil {
+		t.Fatal("block.max_txs above the genesis limit should be rejected")
+	}
+}
@@ -120,8 +120,7 @@ func (vp *VotePool) HasQuorum(blockHash string, view int, voteType VoteType) boo
 		return false
 	}
 
-	f := (n - 1) / 3
-	quorum := 2*f + 1
+	quorum := Quorum(n)
 
 	if _, ok := vp.votes[blockHash]; !ok {
 		return false
@@ -68,4 +68,20 @@ func TestUnauthorizedValidatorRejected(t *testing.T) {
 	if err == nil {
 		t.Fatal("Unauthorized validator should be rejected")
 	}
-}
\ No newline at end of file
+}
+
+func TestQuorumIntersectsInHonestValidator(t *testing.T) {
+
+	for n, want := range map[int]int{1: 1, 4: 3, 5: 4, 6: 5, 7: 5, 10: 7} {
+
+		q := Quorum(n)
+		if q != want {
+			t.Fatalf("Quorum(%d) = %d, want %d", n, q, want)
+		}
+
+		// Two quorums overlap in 2q-n validators, more than f
+		if f := (n - 1) / 3; 2*q-n <= f {
+			t.Fatalf("quorums of %d among %d overlap in only %d validators", q, n, 2*q-n)
+		}
+	}
+}
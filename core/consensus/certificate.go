@@ -0,0 +1,86 @@
+package consensus
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/chain"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/identity"
+)
+
+/*
+Commit certificates
+
+A certificate proves a block was committed: the signed commit votes
+of a quorum of validators for its hash at its height and view. It
+travels with the block, so a node that was not part of the vote can
+check the block was finalized, not merely produced by its leader.
+
+Votes are signed in the chain's VOTE domain over CommitDigest.
+*/
+
+// CommitSig is one validator's signed commit vote.
+type CommitSig struct {
+	ValidatorID string `json:"validator_id"`
+	Signature   []byte `json:"signature"`
+}
+
+// Certificate holds the commit votes for the block BlockHash at
+// Height and View.
+type Certificate struct {
+	Height    int         `json:"height"`
+	View      int         `json:"view"`
+	BlockHash []byte      `json:"block_hash"`
+	Votes     []CommitSig `json:"votes"`
+}
+
+// Quorum returns the votes needed among n validators, of which f =
+// (n-1)/3 may be faulty: n-f, so any two quorums share an honest
+// validator. That is 2f+1 when n is 3f+1, and more otherwise: with 6
+// validators, two disjoint sets of 2f+1 = 3 could finalize different
+// blocks.
+func Quorum(n int) int {
+	f := (n - 1) / 3
+	return n - f
+}
+
+// CommitDigest is the message a commit vote signs, under the chain
+// hash h.
+func CommitDigest(h crypto.Hasher, height int, view int, blockHash []byte) []byte {
+
+	// Only integers and a byte slice: the encoding cannot fail
+	data, _ := json.Marshal(struct {
+		Type      string `json:"type"`
+		Height    int    `json:"height"`
+		View      int    `json:"view"`
+		BlockHash []byte `json:"block_hash"`
+	}{"commit", height, view, blockHash})
+
+	return h.Hash(data)
+}
+
+// NewCertificate starts an empty certificate for a block.
+func NewCertificate(height int, view int, blockHash []byte) *Certificate {
+	return &Certificate{Height: height, View: view, BlockHash: blockHash}
+}
+
+// Sign adds node's commit vote.
+func (c *Certificate) Sign(node *identity.NodeIdentity, p chain.Params) error {
+
+	for _, v := range c.Votes {
+		if v.ValidatorID == node.NodeID {
+			return errors.New("double vote detected")
+		}
+	}
+
+	digest := CommitDigest(p.Hasher, c.Height, c.View, c.BlockHash)
+
+	signature, err := node.SignAt(p.Domain(crypto.MessageVote), c.Height, c.View, digest)
+	if err != nil {
+		return err
+	}
+
+	c.Votes = append(c.Votes, CommitSig{ValidatorID: node.NodeID, Signature: signature})
+	return nil
+}
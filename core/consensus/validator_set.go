@@ -1,5 +1,7 @@
 package consensus
 
+import "errors"
+
 /*
 ValidatorSet manages authorized block-producing validators.
 
@@ -9,24 +11,57 @@ Security Purpose:
 - Enforces governance layer (Layer 6)
 
 Structure:
-NodeID → PublicKey
+NodeID → PublicKey per height range (key rotation)
 */
 
 type ValidatorSet struct {
-	validators map[string][]byte
+	// NodeID → keys ordered by activation height; the first starts at 0
+	validators map[string][]keyEpoch
+}
+
+// keyEpoch is a validator key and the first height it signs.
+type keyEpoch struct {
+	from int
+	key  []byte
 }
 
 // NewValidatorSet initializes an empty validator registry.
 func NewValidatorSet() *ValidatorSet {
 	return &ValidatorSet{
-		validators: make(map[string][]byte),
+		validators: make(map[string][]keyEpoch),
 	}
 }
 
-// AddValidator registers a new validator.
-// If NodeID already exists, it overwrites the public key.
-func (v *ValidatorSet) AddValidator(nodeID string, publicKey []byte) {
-	v.validators[nodeID] = publicKey
+// AddValidator registers a new validator with its genesis key.
+// Keys of registered validators change only through RotateKey.
+func (v *ValidatorSet) AddValidator(nodeID string, publicKey []byte) error {
+	if _, exists := v.validators[nodeID]; exists {
+		return errors.New("validator already registered: " + nodeID)
+	}
+	v.validators[nodeID] = []keyEpoch{{from: 0, key: publicKey}}
+	return nil
+}
+
+// RotateKey schedules newKey to replace the validator's key from
+// height on. Blocks below height keep verifying under the old key.
+func (v *ValidatorSet) RotateKey(nodeID string, newKey []byte, height int) error {
+	epochs, exists := v.validators[nodeID]
+	if !exists {
+		return errors.New("unknown validator: " + nodeID)
+	}
+
+	last := epochs[len(epochs)-1]
+
+	if height <= last.from {
+		return errors.New("key rotation must activate after the current key")
+	}
+
+	if len(newKey) == 0 || string(newKey) == string(last.key) {
+		return errors.New("key rotation must introduce a new key")
+	}
+
+	v.validators[nodeID] = append(epochs, keyEpoch{from: height, key: newKey})
+	return nil
 }
 
 // RemoveValidator removes a validator from the set.
@@ -35,9 +70,9 @@ func (v *ValidatorSet) RemoveValidator(nodeID string) {
 }
 
 // IsAuthorized checks if a validator is registered AND
-// that the provided public key matches the registered key.
+// that the provided public key matches its latest key.
 func (v *ValidatorSet) IsAuthorized(nodeID string, publicKey []byte) bool {
-	registeredKey, exists := v.validators[nodeID]
+	registeredKey, exists := v.GetValidator(nodeID)
 	if !exists {
 		return false
 	}
@@ -51,10 +86,45 @@ func (v *ValidatorSet) IsAuthorized(nodeID string, publicKey []byte) bool {
 	return true
 }
 
-// GetValidator returns the registered public key and existence flag.
+// IsAuthorizedAt is IsAuthorized for the key active at height.
+func (v *ValidatorSet) IsAuthorizedAt(nodeID string, publicKey []byte, height int) bool {
+	key, exists := v.KeyAt(nodeID, height)
+	return exists && string(key) == string(publicKey)
+}
+
+// GetValidator returns the latest registered public key, including a
+// scheduled rotation that is not active yet, and existence flag.
 func (v *ValidatorSet) GetValidator(nodeID string) ([]byte, bool) {
-	key, exists := v.validators[nodeID]
-	return key, exists
+	epochs, exists := v.validators[nodeID]
+	if !exists {
+		return nil, false
+	}
+	return epochs[len(epochs)-1].key, true
+}
+
+// KeyAt returns the key the validator signs with at height.
+func (v *ValidatorSet) KeyAt(nodeID string, height int) ([]byte, bool) {
+	epochs, exists := v.validators[nodeID]
+	if !exists {
+		return nil, false
+	}
+
+	key := epochs[0].key
+	for _, e := range epochs[1:] {
+		if e.from > height {
+			break
+		}
+		key = e.key
+	}
+
+	return key, true
+}
+
+// PendingRotation reports whether the validator has a rotation
+// scheduled above height.
+func (v *ValidatorSet) PendingRotation(nodeID string, height int) bool {
+	epochs := v.validators[nodeID]
+	return len(epochs) > 0 && epochs[len(epochs)-1].from > height
 }
 
 // Count returns total number of registered validators.
@@ -8,7 +8,9 @@ func TestAddAndAuthorizeValidator(t *testing.T) {
 
 	pub := []byte("validator_public_key")
 
-	vs.AddValidator("validator-1", pub)
+	if err := vs.AddValidator("validator-1", pub); err != nil {
+		t.Fatal(err)
+	}
 
 	if !vs.IsAuthorized("validator-1", pub) {
 		t.Fatal("validator should be authorized")
@@ -21,7 +23,9 @@ func TestUnauthorizedValidator(t *testing.T) {
 
 	pub := []byte("validator_public_key")
 
-	vs.AddValidator("validator-1", pub)
+	if err := vs.AddValidator("validator-1", pub); err != nil {
+		t.Fatal(err)
+	}
 
 	if vs.IsAuthorized("validator-2", pub) {
 		t.Fatal("unknown validator should not be authorized")
@@ -35,7 +39,9 @@ func TestWrongPublicKeyRejected(t *testing.T) {
 	pub := []byte("correct_key")
 	wrong := []byte("wrong_key")
 
-	vs.AddValidator("validator-1", pub)
+	if err := vs.AddValidator("validator-1", pub); err != nil {
+		t.Fatal(err)
+	}
 
 	if vs.IsAuthorized("validator-1", wrong) {
 		t.Fatal("authorization should fail for wrong public key")
@@ -48,7 +54,10 @@ func TestRemoveValidator(t *testing.T) {
 
 	pub := []byte("validator_public_key")
 
-	vs.AddValidator("validator-1", pub)
+	if err := vs.AddValidator("validator-1", pub); err != nil {
+		t.Fatal(err)
+	}
+
 	vs.RemoveValidator("validator-1")
 
 	if vs.IsAuthorized("validator-1", pub) {
@@ -60,10 +69,95 @@ func TestValidatorCount(t *testing.T) {
 
 	vs := NewValidatorSet()
 
-	vs.AddValidator("v1", []byte("key1"))
-	vs.AddValidator("v2", []byte("key2"))
+	if err := vs.AddValidator("v1", []byte("key1")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := vs.AddValidator("v2", []byte("key2")); err != nil {
+		t.Fatal(err)
+	}
 
 	if vs.Count() != 2 {
 		t.Fatal("validator count incorrect")
 	}
-}
\ No newline at end of file
+}
+
+func TestAddValidatorTwiceRejected(t *testing.T) {
+
+	vs := NewValidatorSet()
+
+	if err := vs.AddValidator("validator-1", []byte("key1")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := vs.AddValidator("validator-1", []byte("key2")); err == nil {
+		t.Fatal("re-registering a validator must not replace its key")
+	}
+
+	if !vs.IsAuthorized("validator-1", []byte("key1")) {
+		t.Fatal("original key should remain authorized")
+	}
+}
+
+func TestRotateKeyByHeight(t *testing.T) {
+
+	vs := NewValidatorSet()
+
+	oldKey := []byte("old_key")
+	newKey := []byte("new_key")
+
+	if err := vs.AddValidator("validator-1", oldKey); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := vs.RotateKey("validator-1", newKey, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	if !vs.PendingRotation("validator-1", 5) {
+		t.Fatal("rotation should be pending below its activation height")
+	}
+
+	if !vs.IsAuthorizedAt("validator-1", oldKey, 9) || vs.IsAuthorizedAt("validator-1", newKey, 9) {
+		t.Fatal("old key must sign below the activation height")
+	}
+
+	if !vs.IsAuthorizedAt("validator-1", newKey, 10) || vs.IsAuthorizedAt("validator-1", oldKey, 10) {
+		t.Fatal("new key must sign from the activation height")
+	}
+
+	if vs.PendingRotation("validator-1", 10) {
+		t.Fatal("rotation should no longer be pending once active")
+	}
+}
+
+func TestRotateKeyRejectsInvalid(t *testing.T) {
+
+	vs := NewValidatorSet()
+
+	if err := vs.AddValidator("validator-1", []byte("key1")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := vs.RotateKey("validator-1", []byte("key2"), 10); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		nodeID string
+		key    []byte
+		height int
+	}{
+		{"unknown validator", "validator-2", []byte("key3"), 20},
+		{"not after current key", "validator-1", []byte("key3"), 10},
+		{"same key", "validator-1", []byte("key2"), 20},
+		{"empty key", "validator-1", nil, 20},
+	}
+
+	for _, test := range tests {
+		if err := vs.RotateKey(test.nodeID, test.key, test.height); err == nil {
+			t.Fatalf("%s: rotation should be rejected", test.name)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package crypto
+
+import (
+	"runtime"
+	"sync"
+)
+
+// BatchItem is one signature to check in a batch. Context, when set,
+// is the signing context (see Domain.Context).
+type BatchItem struct {
+	PublicKey []byte
+	Message   []byte
+	Signature []byte
+	Context   []byte
+}
+
+// BatchVerifier is implemented by signers that can check many
+// signatures more cheaply than one Verify call per item.
+type BatchVerifier interface {
+	VerifyBatch(items []BatchItem) []bool
+}
+
+// VerifyBatch checks every item and returns one result per item.
+//
+// Signers implementing BatchVerifier are used directly; otherwise the
+// items are spread over a pool of workers (GOMAXPROCS when workers <= 0)
+// calling Verify. The signer must be safe for concurrent Verify calls.
+func VerifyBatch(signer Signer, items []BatchItem, workers int) []bool {
+
+	if bv, ok := signer.(BatchVerifier); ok {
+		return bv.VerifyBatch(items)
+	}
+
+	results := make([]bool, len(items))
+
+	ParallelFor(len(items), workers, func(i int) {
+		it := items[i]
+		results[i] = VerifyInContext(signer, it.PublicKey, it.Message, it.Signature, it.Context)
+	})
+
+	return results
+}
+
+// ParallelFor runs fn(0..n-1) on up to workers goroutines
+// (GOMAXPROCS when workers <= 0) and returns when all calls finished.
+// Indices are handed out in contiguous chunks to keep overhead low.
+func ParallelFor(n int, workers int, fn func(i int)) {
+
+	if n == 0 {
+		return
+	}
+
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	if workers > n {
+		workers = n
+	}
+
+	if workers == 1 {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+		return
+	}
+
+	chunk := (n + workers - 1) / workers
+
+	var wg sync.WaitGroup
+
+	for start := 0; start < n; start += chunk {
+
+		end := start + chunk
+		if end > n {
+			end = n
+		}
+
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			for i := start; i < end; i++ {
+				fn(i)
+			}
+		}(start, end)
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,85 @@
+package crypto
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestVerifyBatchFlagsInvalidItems(t *testing.T) {
+	for name, signer := range getSigners(t) {
+		t.Run(name, func(t *testing.T) {
+
+			pub, priv, _ := signer.GenerateKeyPair()
+
+			items := make([]BatchItem, 64)
+
+			for i := range items {
+				msg := []byte{byte(i), 'm', 's', 'g'}
+				sig, err := signer.Sign(priv, msg)
+				if err != nil {
+					t.Fatal(err)
+				}
+				items[i] = BatchItem{PublicKey: pub, Message: msg, Signature: sig}
+			}
+
+			items[17].Message = []byte("tampered")
+			items[40].Signature = items[41].Signature
+
+			results := VerifyBatch(signer, items, 4)
+
+			for i, ok := range results {
+				want := i != 17 && i != 40
+				if ok != want {
+					t.Fatalf("item %d: got %v, want %v", i, ok, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParallelForVisitsEveryIndexOnce(t *testing.T) {
+
+	for _, workers := range []int{0, 1, 3, 16, 1000} {
+
+		var visits [257]int32
+
+		ParallelFor(len(visits), workers, func(i int) {
+			atomic.AddInt32(&visits[i], 1)
+		})
+
+		for i, v := range visits {
+			if v != 1 {
+				t.Fatalf("workers=%d: index %d visited %d times", workers, i, v)
+			}
+		}
+	}
+}
+
+func BenchmarkVerifyBatchDilithium(b *testing.B) {
+
+	signer, err := NewDilithiumSigner()
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer signer.Close()
+
+	pub, priv, _ := signer.GenerateKeyPair()
+
+	items := make([]BatchItem, 256)
+
+	for i := range items {
+		msg := []byte{byte(i), 'b', 'a', 't', 'c', 'h'}
+		sig, _ := signer.Sign(priv, msg)
+		items[i] = BatchItem{PublicKey: pub, Message: msg, Signature: sig}
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for _, ok := range VerifyBatch(signer, items, 0) {
+			if !ok {
+				b.Fatal("verify failed")
+			}
+		}
+	}
+}
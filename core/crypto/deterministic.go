@@ -0,0 +1,221 @@
+package crypto
+
+import (
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/cloudflare/circl/sign/mldsa/mldsa44"
+	"golang.org/x/crypto/sha3"
+)
+
+/*
+Deterministic mode.
+
+A DeterministicSigner derives every key pair from a SHAKE256 stream
+seeded by the caller and signs without randomness:
+
+  - ECDSA P-256 uses RFC 6979 nonces
+  - ML-DSA-44 uses the deterministic variant (FIPS 204, rnd = 0)
+  - Ed25519 is deterministic already
+
+Two signers built from the same algorithm and seed produce the same
+key sequence and byte-identical signatures, which makes golden blocks
+and cross-implementation test vectors reproducible. Signatures verify
+with the ordinary signers. Keys from a known seed are not secret:
+never use this mode for production validators.
+*/
+
+const deterministicDomain = "aegisq/deterministic-keygen/v1"
+
+// DeterministicSigner wraps the signer for one algorithm, replacing
+// key generation and signing. Verify and Algorithm are unchanged.
+type DeterministicSigner struct {
+	Signer
+
+	mu     sync.Mutex
+	stream sha3.ShakeHash
+}
+
+// NewDeterministicSigner returns a signer for alg whose keys are drawn
+// from seed and whose signatures are deterministic.
+func NewDeterministicSigner(alg string, seed []byte) (*DeterministicSigner, error) {
+
+	if len(seed) == 0 {
+		return nil, errors.New("empty deterministic seed")
+	}
+
+	switch alg {
+	case "dilithium2", "ECDSA_P256", "ed25519":
+	default:
+		return nil, fmt.Errorf("deterministic mode not supported for %s", alg)
+	}
+
+	base, err := NewSignerForAlgorithm(alg)
+	if err != nil {
+		return nil, err
+	}
+
+	stream := sha3.NewShake256()
+	stream.Write([]byte(deterministicDomain))
+	stream.Write([]byte{0})
+	stream.Write([]byte(alg))
+	stream.Write([]byte{0})
+	stream.Write(seed)
+
+	return &DeterministicSigner{Signer: base, stream: stream}, nil
+}
+
+// GenerateKeyPair returns the next key pair in the seeded sequence.
+func (d *DeterministicSigner) GenerateKeyPair() ([]byte, []byte, error) {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var seed [32]byte
+	defer Wipe(seed[:])
+
+	switch d.Algorithm() {
+
+	case "dilithium2":
+		d.stream.Read(seed[:])
+		pub, priv := dilithiumKeyFromSeed(&seed)
+		return pub, priv, nil
+
+	case "ECDSA_P256":
+		// Rejection-sample a scalar in [1, n-1].
+		for {
+			d.stream.Read(seed[:])
+
+			key, err := ecdh.P256().NewPrivateKey(seed[:])
+			if err != nil {
+				continue
+			}
+
+			priv := append([]byte(nil), seed[:]...)
+			return key.PublicKey().Bytes(), priv, nil
+		}
+
+	default:
+		d.stream.Read(seed[:])
+		priv := ed25519.NewKeyFromSeed(seed[:])
+		return priv.Public().(ed25519.PublicKey), priv, nil
+	}
+}
+
+// Sign produces a deterministic signature.
+func (d *DeterministicSigner) Sign(privateKey []byte, message []byte) ([]byte, error) {
+
+	switch d.Algorithm() {
+
+	case "dilithium2":
+		return signDilithiumDeterministic(privateKey, message, nil)
+
+	case "ECDSA_P256":
+		return signECDSA(privateKey, message, nil)
+
+	default:
+		return d.Signer.Sign(privateKey, message)
+	}
+}
+
+// SignContext binds context natively for ML-DSA and as a message
+// prefix otherwise, exactly as SignInContext does for the wrapped
+// signer.
+func (d *DeterministicSigner) SignContext(privateKey []byte, message []byte, context []byte) ([]byte, error) {
+
+	if d.Algorithm() == "dilithium2" {
+		return signDilithiumDeterministic(privateKey, message, context)
+	}
+
+	return d.Sign(privateKey, contextMessage(context, message))
+}
+
+// VerifyContext verifies with the wrapped signer.
+func (d *DeterministicSigner) VerifyContext(publicKey []byte, message []byte, signature []byte, context []byte) bool {
+	return VerifyInContext(d.Signer, publicKey, message, signature, context)
+}
+
+// SignSecret signs with a protected private key.
+func (d *DeterministicSigner) SignSecret(privateKey *SecretKey, message []byte) ([]byte, error) {
+	return signSecret(d.Sign, privateKey, message)
+}
+
+// Close releases the wrapped signer's resources, if it holds any.
+func (d *DeterministicSigner) Close() {
+	if c, ok := d.Signer.(interface{ Close() }); ok {
+		c.Close()
+	}
+}
+
+// dilithiumKeyFromSeed expands a 32-byte ML-DSA seed (FIPS 204 ξ)
+// into encoded public and private keys.
+func dilithiumKeyFromSeed(seed *[mldsa44.SeedSize]byte) ([]byte, []byte) {
+	pk, sk := mldsa44.NewKeyFromSeed(seed)
+	return pk.Bytes(), sk.Bytes()
+}
+
+// ML-DSA-44 private key layout (FIPS 204 skEncode): ρ, K and tr, then
+// s1 and s2 packed at 3 bits per coefficient, then t0.
+const (
+	dilithiumEta       = 2
+	dilithiumSOffset   = 32 + 32 + 64
+	dilithiumSLen      = (4 + 4) * 256 * 3 / 8
+	dilithiumSecretLen = dilithiumSOffset + dilithiumSLen + 4*256*13/8
+)
+
+// checkDilithiumPrivateKey rejects an encoded private key whose s1 or
+// s2 coefficients lie outside [-η, η]. FIPS 204 treats such keys as
+// malformed, but neither backend checks them when signing.
+func checkDilithiumPrivateKey(privateKey []byte) error {
+
+	if len(privateKey) != dilithiumSecretLen {
+		return errors.New("invalid Dilithium private key")
+	}
+
+	packed := privateKey[dilithiumSOffset : dilithiumSOffset+dilithiumSLen]
+
+	// Every 3 bytes hold 8 coefficients, each encoded as η - s
+	for i := 0; i < len(packed); i += 3 {
+
+		bits := uint32(packed[i]) | uint32(packed[i+1])<<8 | uint32(packed[i+2])<<16
+
+		for j := 0; j < 8; j++ {
+			if (bits>>(3*j))&7 > 2*dilithiumEta {
+				return errors.New("invalid Dilithium private key")
+			}
+		}
+	}
+
+	return nil
+}
+
+// signDilithiumDeterministic signs with the deterministic ML-DSA
+// variant. Both backends share the FIPS 204 key encoding, so this
+// works for keys from either.
+func signDilithiumDeterministic(privateKey []byte, message []byte, context []byte) ([]byte, error) {
+
+	if len(privateKey) == 0 || len(message) == 0 {
+		return nil, errors.New("invalid input to Sign")
+	}
+
+	if err := checkDilithiumPrivateKey(privateKey); err != nil {
+		return nil, err
+	}
+
+	var sk mldsa44.PrivateKey
+	defer func() { sk = mldsa44.PrivateKey{} }()
+
+	if err := sk.UnmarshalBinary(privateKey); err != nil {
+		return nil, errors.New("invalid Dilithium private key")
+	}
+
+	signature := make([]byte, mldsa44.SignatureSize)
+	if err := mldsa44.SignTo(&sk, message, context, false, signature); err != nil {
+		return nil, errors.New("sign failed")
+	}
+
+	return signature, nil
+}
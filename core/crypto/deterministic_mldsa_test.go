@@ -0,0 +1,43 @@
+//go:build go1.27
+
+package crypto
+
+import (
+	"bytes"
+	"crypto/mldsa"
+	"testing"
+)
+
+// Deterministic ML-DSA signatures must match the standard library's
+// independent implementation byte for byte.
+func TestDeterministicMLDSAMatchesStdlib(t *testing.T) {
+
+	var seed [32]byte
+	for i := range seed {
+		seed[i] = byte(i)
+	}
+
+	_, priv := dilithiumKeyFromSeed(&seed)
+
+	ref, err := mldsa.NewPrivateKey(mldsa.MLDSA44(), seed[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, msg := range [][]byte{[]byte("a"), []byte("golden block hash"), bytes.Repeat([]byte{7}, 1000)} {
+
+		got, err := signDilithiumDeterministic(priv, msg, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want, err := ref.SignDeterministic(msg, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(got, want) {
+			t.Fatalf("deterministic signature differs from crypto/mldsa for %d-byte message", len(msg))
+		}
+	}
+}
@@ -1,3 +1,5 @@
+//go:build cgo && liboqs
+
 package crypto
 
 /*
@@ -9,46 +11,131 @@ import "C"
 
 import (
 	"errors"
+	"runtime"
+	"sync"
 	"unsafe"
 )
 
+var errSignerClosed = errors.New("Dilithium signer closed")
+
+// DilithiumSigner is the liboqs-backed ML-DSA-44 signer.
+//
+// It is safe for concurrent use. Each operation borrows an OQS_SIG
+// handle from a small pool (at most GOMAXPROCS handles), so goroutines
+// never share C state. Operations hold a shared lock for their whole
+// duration and Close takes it exclusively, so Close waits for in-flight
+// calls and later calls fail cleanly instead of touching freed memory.
+// A finalizer frees the handles if Close is never called.
 type DilithiumSigner struct {
-	alg *C.OQS_SIG
-}
+	mu     sync.RWMutex
+	closed bool
 
-// Constructor
-func NewDilithiumSigner() (*DilithiumSigner, error) {
+	// idle handles; created counts all handles ever allocated
+	handles    chan *C.OQS_SIG
+	createMu   sync.Mutex
+	created    int
+	maxHandles int
+
+	publicKeyLen C.size_t
+	secretKeyLen C.size_t
+	signatureLen C.size_t
+}
 
+func newOQSHandle() *C.OQS_SIG {
 	name := C.CString("ML-DSA-44")
 	defer C.free(unsafe.Pointer(name))
 
-	alg := C.OQS_SIG_new(name)
+	return C.OQS_SIG_new(name)
+}
+
+// Constructor
+func NewDilithiumSigner() (*DilithiumSigner, error) {
+	alg := newOQSHandle()
 	if alg == nil {
 		return nil, errors.New("failed to initialize Dilithium2")
 	}
 
-	return &DilithiumSigner{alg: alg}, nil
+	max := runtime.GOMAXPROCS(0)
+
+	d := &DilithiumSigner{
+		handles:      make(chan *C.OQS_SIG, max),
+		created:      1,
+		maxHandles:   max,
+		publicKeyLen: alg.length_public_key,
+		secretKeyLen: alg.length_secret_key,
+		signatureLen: alg.length_signature,
+	}
+
+	d.handles <- alg
+
+	runtime.SetFinalizer(d, (*DilithiumSigner).Close)
+
+	return d, nil
+}
+
+// acquire borrows a handle, allocating a new one while under the
+// pool limit and otherwise waiting for one to be released.
+// Callers must hold d.mu for reading.
+func (d *DilithiumSigner) acquire() (*C.OQS_SIG, error) {
+	select {
+	case h := <-d.handles:
+		return h, nil
+	default:
+	}
+
+	d.createMu.Lock()
+	if d.created < d.maxHandles {
+		d.created++
+		d.createMu.Unlock()
+
+		h := newOQSHandle()
+		if h == nil {
+			d.createMu.Lock()
+			d.created--
+			d.createMu.Unlock()
+			return nil, errors.New("failed to initialize Dilithium2")
+		}
+		return h, nil
+	}
+	d.createMu.Unlock()
+
+	return <-d.handles, nil
+}
+
+func (d *DilithiumSigner) release(h *C.OQS_SIG) {
+	d.handles <- h
 }
 
 // GenerateKeyPair generates public and private keys
 func (d *DilithiumSigner) GenerateKeyPair() ([]byte, []byte, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
 
-	if d.alg == nil {
-		return nil, nil, errors.New("Dilithium signer not initialized")
+	if d.closed {
+		return nil, nil, errSignerClosed
 	}
 
-	pub := C.malloc(C.size_t(d.alg.length_public_key))
-	priv := C.malloc(C.size_t(d.alg.length_secret_key))
+	alg, err := d.acquire()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer d.release(alg)
 
+	pub := C.malloc(d.publicKeyLen)
+	priv := C.malloc(d.secretKeyLen)
 	if pub == nil || priv == nil {
+		C.free(pub)
+		C.free(priv)
 		return nil, nil, errors.New("memory allocation failed")
 	}
-
 	defer C.free(pub)
-	defer C.free(priv)
+	defer func() {
+		C.OQS_MEM_cleanse(priv, d.secretKeyLen)
+		C.free(priv)
+	}()
 
 	res := C.OQS_SIG_keypair(
-		d.alg,
+		alg,
 		(*C.uint8_t)(pub),
 		(*C.uint8_t)(priv),
 	)
@@ -57,24 +144,49 @@ func (d *DilithiumSigner) GenerateKeyPair() ([]byte, []byte, error) {
 		return nil, nil, errors.New("keypair generation failed")
 	}
 
-	publicKey := C.GoBytes(pub, C.int(d.alg.length_public_key))
-	privateKey := C.GoBytes(priv, C.int(d.alg.length_secret_key))
+	publicKey := C.GoBytes(pub, C.int(d.publicKeyLen))
+	privateKey := C.GoBytes(priv, C.int(d.secretKeyLen))
 
 	return publicKey, privateKey, nil
 }
 
 // Sign signs a message using Dilithium
 func (d *DilithiumSigner) Sign(privateKey []byte, message []byte) ([]byte, error) {
+	return d.SignContext(privateKey, message, nil)
+}
 
-	if d.alg == nil {
-		return nil, errors.New("Dilithium signer not initialized")
+// SignContext signs with an ML-DSA context string (at most 255 bytes).
+func (d *DilithiumSigner) SignContext(privateKey []byte, message []byte, context []byte) ([]byte, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.closed {
+		return nil, errSignerClosed
 	}
 
 	if len(privateKey) == 0 || len(message) == 0 {
 		return nil, errors.New("invalid input to Sign")
 	}
 
-	sig := C.malloc(C.size_t(d.alg.length_signature))
+	if C.size_t(len(privateKey)) != d.secretKeyLen {
+		return nil, errors.New("invalid Dilithium private key")
+	}
+
+	if err := checkDilithiumPrivateKey(privateKey); err != nil {
+		return nil, err
+	}
+
+	if len(context) > MaxContextSize {
+		return nil, errors.New("context string too long")
+	}
+
+	alg, err := d.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer d.release(alg)
+
+	sig := C.malloc(d.signatureLen)
 	if sig == nil {
 		return nil, errors.New("memory allocation failed")
 	}
@@ -82,12 +194,14 @@ func (d *DilithiumSigner) Sign(privateKey []byte, message []byte) ([]byte, error
 
 	var sigLen C.size_t
 
-	res := C.OQS_SIG_sign(
-		d.alg,
+	res := C.OQS_SIG_sign_with_ctx_str(
+		alg,
 		(*C.uint8_t)(sig),
 		&sigLen,
 		(*C.uint8_t)(unsafe.Pointer(&message[0])),
 		C.size_t(len(message)),
+		cBytes(context),
+		C.size_t(len(context)),
 		(*C.uint8_t)(unsafe.Pointer(&privateKey[0])),
 	)
 
@@ -96,13 +210,26 @@ func (d *DilithiumSigner) Sign(privateKey []byte, message []byte) ([]byte, error
 	}
 
 	signature := C.GoBytes(sig, C.int(sigLen))
+
 	return signature, nil
 }
 
+// SignSecret signs with a protected private key.
+func (d *DilithiumSigner) SignSecret(privateKey *SecretKey, message []byte) ([]byte, error) {
+	return signSecret(d.Sign, privateKey, message)
+}
+
 // Verify verifies a Dilithium signature
 func (d *DilithiumSigner) Verify(publicKey []byte, message []byte, signature []byte) bool {
+	return d.VerifyContext(publicKey, message, signature, nil)
+}
+
+// VerifyContext verifies a signature made with SignContext.
+func (d *DilithiumSigner) VerifyContext(publicKey []byte, message []byte, signature []byte, context []byte) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
 
-	if d.alg == nil {
+	if d.closed {
 		return false
 	}
 
@@ -110,27 +237,61 @@ func (d *DilithiumSigner) Verify(publicKey []byte, message []byte, signature []b
 		return false
 	}
 
-	res := C.OQS_SIG_verify(
-		d.alg,
+	if C.size_t(len(publicKey)) != d.publicKeyLen || len(context) > MaxContextSize {
+		return false
+	}
+
+	alg, err := d.acquire()
+	if err != nil {
+		return false
+	}
+	defer d.release(alg)
+
+	res := C.OQS_SIG_verify_with_ctx_str(
+		alg,
 		(*C.uint8_t)(unsafe.Pointer(&message[0])),
 		C.size_t(len(message)),
 		(*C.uint8_t)(unsafe.Pointer(&signature[0])),
 		C.size_t(len(signature)),
+		cBytes(context),
+		C.size_t(len(context)),
 		(*C.uint8_t)(unsafe.Pointer(&publicKey[0])),
 	)
 
 	return res == C.OQS_SUCCESS
 }
 
+// cBytes returns a C pointer to b, or NULL for an empty slice.
+func cBytes(b []byte) *C.uint8_t {
+	if len(b) == 0 {
+		return nil
+	}
+	return (*C.uint8_t)(unsafe.Pointer(&b[0]))
+}
+
 // Algorithm returns algorithm identifier
 func (d *DilithiumSigner) Algorithm() string {
 	return "dilithium2"
 }
 
-// Close frees underlying C memory (CRITICAL for long-running systems)
+// Close frees underlying C memory (CRITICAL for long-running systems).
+// It waits for in-flight operations, is idempotent, and any call made
+// after it returns an error instead of crashing.
 func (d *DilithiumSigner) Close() {
-	if d.alg != nil {
-		C.OQS_SIG_free(d.alg)
-		d.alg = nil
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return
 	}
+	d.closed = true
+
+	// No operation holds a handle while we own the write lock,
+	// so every created handle is back in the pool.
+	for i := 0; i < d.created; i++ {
+		C.OQS_SIG_free(<-d.handles)
+	}
+	d.created = 0
+
+	runtime.SetFinalizer(d, nil)
 }
@@ -0,0 +1,111 @@
+//go:build cgo && liboqs
+
+package crypto
+
+import (
+	"testing"
+
+	"github.com/cloudflare/circl/sign/mldsa/mldsa44"
+)
+
+// These tests check that the liboqs backend and the pure-Go fallback
+// agree on key and signature encodings.
+
+func TestLiboqsSignatureVerifiesInPureGo(t *testing.T) {
+
+	signer, err := NewDilithiumSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer signer.Close()
+
+	pub, priv, err := signer.GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := []byte("liboqs -> pure Go")
+
+	sig, err := signer.Sign(priv, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pk mldsa44.PublicKey
+	if err := pk.UnmarshalBinary(pub); err != nil {
+		t.Fatal(err)
+	}
+
+	if !mldsa44.Verify(&pk, msg, nil, sig) {
+		t.Fatal("pure-Go verifier rejected liboqs signature")
+	}
+}
+
+func TestPureGoSignatureVerifiesInLiboqs(t *testing.T) {
+
+	signer, err := NewDilithiumSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer signer.Close()
+
+	pk, sk, err := mldsa44.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := []byte("pure Go -> liboqs")
+
+	sig := make([]byte, mldsa44.SignatureSize)
+	if err := mldsa44.SignTo(sk, msg, nil, true, sig); err != nil {
+		t.Fatal(err)
+	}
+
+	if !signer.Verify(pk.Bytes(), msg, sig) {
+		t.Fatal("liboqs rejected pure-Go signature")
+	}
+
+	// And liboqs must be able to sign with a pure-Go private key.
+	sig2, err := signer.Sign(sk.Bytes(), msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !mldsa44.Verify(pk, msg, nil, sig2) {
+		t.Fatal("signature from imported private key did not verify")
+	}
+}
+
+func TestLiboqsSignerFailsAfterClose(t *testing.T) {
+
+	signer, err := NewDilithiumSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pub, priv, err := signer.GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := []byte("after close")
+
+	sig, err := signer.Sign(priv, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer.Close()
+
+	if _, err := signer.Sign(priv, msg); err == nil {
+		t.Fatal("Sign after Close should fail")
+	}
+
+	if signer.Verify(pub, msg, sig) {
+		t.Fatal("Verify after Close should fail")
+	}
+
+	if _, _, err := signer.GenerateKeyPair(); err == nil {
+		t.Fatal("GenerateKeyPair after Close should fail")
+	}
+}
@@ -0,0 +1,99 @@
+package crypto
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// Run with -race; DilithiumSigner is shared by every validator in aegisqd.
+
+func TestDilithiumConcurrentSignVerify(t *testing.T) {
+
+	signer, err := NewDilithiumSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer signer.Close()
+
+	pub, priv, err := signer.GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 32)
+
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+
+			for i := 0; i < 20; i++ {
+				msg := []byte(fmt.Sprintf("goroutine %d message %d", g, i))
+
+				sig, err := signer.Sign(priv, msg)
+				if err != nil {
+					errs <- err
+					return
+				}
+
+				if !signer.Verify(pub, msg, sig) {
+					errs <- fmt.Errorf("goroutine %d: verify failed", g)
+					return
+				}
+			}
+
+			if _, _, err := signer.GenerateKeyPair(); err != nil {
+				errs <- err
+			}
+		}(g)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatal(err)
+	}
+}
+
+func TestDilithiumCloseDuringUse(t *testing.T) {
+
+	signer, err := NewDilithiumSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pub, priv, err := signer.GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := []byte("close race")
+
+	sig, err := signer.Sign(priv, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Results may fail once closed; the process must not crash.
+			for i := 0; i < 50; i++ {
+				signer.Sign(priv, msg)
+				signer.Verify(pub, msg, sig)
+			}
+		}()
+	}
+
+	signer.Close()
+	wg.Wait()
+
+	// Close is idempotent.
+	signer.Close()
+}
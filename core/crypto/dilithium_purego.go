@@ -0,0 +1,98 @@
+//go:build !cgo || !liboqs
+
+package crypto
+
+import (
+	"crypto/rand"
+	"errors"
+
+	"github.com/cloudflare/circl/sign/mldsa/mldsa44"
+)
+
+// DilithiumSigner is the pure-Go ML-DSA-44 backend, used whenever the
+// module is built without cgo or without the liboqs build tag.
+// Keys and signatures use the FIPS 204 encodings, so they are
+// byte-compatible with the liboqs-backed signer. It holds no state and
+// is safe for concurrent use.
+type DilithiumSigner struct{}
+
+// Constructor
+func NewDilithiumSigner() (*DilithiumSigner, error) {
+	return &DilithiumSigner{}, nil
+}
+
+// GenerateKeyPair generates public and private keys
+func (d *DilithiumSigner) GenerateKeyPair() ([]byte, []byte, error) {
+	var seed [mldsa44.SeedSize]byte
+	if _, err := rand.Read(seed[:]); err != nil {
+		return nil, nil, errors.New("keypair generation failed")
+	}
+
+	pub, priv := dilithiumKeyFromSeed(&seed)
+	return pub, priv, nil
+}
+
+// Sign signs a message using Dilithium
+func (d *DilithiumSigner) Sign(privateKey []byte, message []byte) ([]byte, error) {
+	return d.SignContext(privateKey, message, nil)
+}
+
+// SignContext signs with an ML-DSA context string (at most 255 bytes).
+func (d *DilithiumSigner) SignContext(privateKey []byte, message []byte, context []byte) ([]byte, error) {
+	if len(privateKey) == 0 || len(message) == 0 {
+		return nil, errors.New("invalid input to Sign")
+	}
+
+	if err := checkDilithiumPrivateKey(privateKey); err != nil {
+		return nil, err
+	}
+
+	// The unpacked key lives on our stack frame; clear it after use.
+	var sk mldsa44.PrivateKey
+	defer func() { sk = mldsa44.PrivateKey{} }()
+
+	if err := sk.UnmarshalBinary(privateKey); err != nil {
+		return nil, errors.New("invalid Dilithium private key")
+	}
+
+	// Hedged signing, matching OQS_SIG_sign.
+	signature := make([]byte, mldsa44.SignatureSize)
+	if err := mldsa44.SignTo(&sk, message, context, true, signature); err != nil {
+		return nil, errors.New("sign failed")
+	}
+
+	return signature, nil
+}
+
+// SignSecret signs with a protected private key.
+func (d *DilithiumSigner) SignSecret(privateKey *SecretKey, message []byte) ([]byte, error) {
+	return signSecret(d.Sign, privateKey, message)
+}
+
+// Verify verifies a Dilithium signature
+func (d *DilithiumSigner) Verify(publicKey []byte, message []byte, signature []byte) bool {
+	return d.VerifyContext(publicKey, message, signature, nil)
+}
+
+// VerifyContext verifies a signature made with SignContext.
+func (d *DilithiumSigner) VerifyContext(publicKey []byte, message []byte, signature []byte, context []byte) bool {
+	if len(publicKey) == 0 || len(message) == 0 || len(signature) == 0 {
+		return false
+	}
+
+	var pk mldsa44.PublicKey
+	if err := pk.UnmarshalBinary(publicKey); err != nil {
+		return false
+	}
+
+	return mldsa44.Verify(&pk, message, context, signature)
+}
+
+// Algorithm returns algorithm identifier
+func (d *DilithiumSigner) Algorithm() string {
+	return "dilithium2"
+}
+
+// Close is a no-op for the pure-Go backend; it exists so callers can
+// release signers the same way regardless of build tags.
+func (d *DilithiumSigner) Close() {}
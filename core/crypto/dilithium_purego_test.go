@@ -0,0 +1,91 @@
+//go:build !cgo || !liboqs
+
+package crypto
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+type mldsaKeyGenKAT struct {
+	ParameterSet string `json:"parameterSet"`
+	Tests        []struct {
+		TcID int    `json:"tcId"`
+		Seed string `json:"seed"`
+		Pk   string `json:"pk"`
+		Sk   string `json:"sk"`
+	} `json:"tests"`
+}
+
+func TestDilithiumKeyGenKAT(t *testing.T) {
+
+	data, err := os.ReadFile("testdata/mldsa44_keygen_kat.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var kat mldsaKeyGenKAT
+	if err := json.Unmarshal(data, &kat); err != nil {
+		t.Fatal(err)
+	}
+
+	if kat.ParameterSet != "ML-DSA-44" || len(kat.Tests) == 0 {
+		t.Fatal("unexpected KAT file contents")
+	}
+
+	for _, tc := range kat.Tests {
+
+		seedBytes, _ := hex.DecodeString(tc.Seed)
+		wantPub, _ := hex.DecodeString(tc.Pk)
+		wantPriv, _ := hex.DecodeString(tc.Sk)
+
+		var seed [32]byte
+		copy(seed[:], seedBytes)
+
+		pub, priv := dilithiumKeyFromSeed(&seed)
+
+		if !bytes.Equal(pub, wantPub) {
+			t.Fatalf("tc %d: public key mismatch", tc.TcID)
+		}
+
+		if !bytes.Equal(priv, wantPriv) {
+			t.Fatalf("tc %d: private key mismatch", tc.TcID)
+		}
+	}
+}
+
+func TestDilithiumKATKeySignVerify(t *testing.T) {
+
+	data, err := os.ReadFile("testdata/mldsa44_keygen_kat.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var kat mldsaKeyGenKAT
+	if err := json.Unmarshal(data, &kat); err != nil {
+		t.Fatal(err)
+	}
+
+	signer, _ := NewDilithiumSigner()
+
+	pub, _ := hex.DecodeString(kat.Tests[0].Pk)
+	priv, _ := hex.DecodeString(kat.Tests[0].Sk)
+
+	msg := []byte("AegisQ KAT key")
+
+	sig, err := signer.Sign(priv, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sig) != 2420 {
+		t.Fatalf("expected 2420-byte ML-DSA-44 signature, got %d", len(sig))
+	}
+
+	if !signer.Verify(pub, msg, sig) {
+		t.Fatal("signature from KAT key did not verify")
+	}
+}
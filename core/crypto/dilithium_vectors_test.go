@@ -0,0 +1,131 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+// mldsaSigVectors holds FIPS 204 signature vectors for the external
+// (pure) interface, with context strings: the deterministic signature
+// expected for a private key, or whether a signature verifies under a
+// public key.
+type mldsaSigVectors struct {
+	ParameterSet string `json:"parameterSet"`
+	Tests        []struct {
+		TcID       int    `json:"tcId"`
+		Comment    string `json:"comment"`
+		PrivateKey string `json:"privateKey"`
+		PublicKey  string `json:"publicKey"`
+		Msg        string `json:"msg"`
+		Ctx        string `json:"ctx"`
+		Sig        string `json:"sig"`
+		Valid      bool   `json:"valid"`
+	} `json:"tests"`
+}
+
+func loadSigVectors(t *testing.T, name string) *mldsaSigVectors {
+
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var v mldsaSigVectors
+	if err := json.Unmarshal(data, &v); err != nil {
+		t.Fatal(err)
+	}
+
+	if v.ParameterSet != "ML-DSA-44" || len(v.Tests) == 0 {
+		t.Fatalf("unexpected contents of %s", name)
+	}
+
+	return &v
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// Deterministic signatures must match the vectors byte for byte, and
+// invalid keys or contexts must be refused, under either backend's
+// key encoding.
+func TestDilithiumSigGenVectors(t *testing.T) {
+
+	vectors := loadSigVectors(t, "mldsa44_siggen_wycheproof.json")
+
+	signer, err := NewDilithiumSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer signer.Close()
+
+	for _, tc := range vectors.Tests {
+
+		priv := mustHex(t, tc.PrivateKey)
+		msg := mustHex(t, tc.Msg)
+		ctx := mustHex(t, tc.Ctx)
+
+		sig, err := signDilithiumDeterministic(priv, msg, ctx)
+
+		if !tc.Valid {
+			if err == nil {
+				t.Fatalf("tc %d (%s): signed", tc.TcID, tc.Comment)
+			}
+			if _, err := signer.SignContext(priv, msg, ctx); err == nil {
+				t.Fatalf("tc %d (%s): hedged signer signed", tc.TcID, tc.Comment)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("tc %d (%s): %v", tc.TcID, tc.Comment, err)
+		}
+
+		if !bytes.Equal(sig, mustHex(t, tc.Sig)) {
+			t.Fatalf("tc %d (%s): signature mismatch", tc.TcID, tc.Comment)
+		}
+	}
+}
+
+// Verification must accept exactly the valid signatures, rejecting
+// modified signatures, malformed hints, wrong lengths and overlong
+// contexts.
+func TestDilithiumSigVerVectors(t *testing.T) {
+
+	vectors := loadSigVectors(t, "mldsa44_sigver_wycheproof.json")
+
+	signer, err := NewDilithiumSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer signer.Close()
+
+	var rejected int
+
+	for _, tc := range vectors.Tests {
+
+		pub := mustHex(t, tc.PublicKey)
+		msg := mustHex(t, tc.Msg)
+		sig := mustHex(t, tc.Sig)
+		ctx := mustHex(t, tc.Ctx)
+
+		if got := signer.VerifyContext(pub, msg, sig, ctx); got != tc.Valid {
+			t.Fatalf("tc %d (%s): verified %v, want %v", tc.TcID, tc.Comment, got, tc.Valid)
+		}
+
+		if !tc.Valid {
+			rejected++
+		}
+	}
+
+	if rejected == 0 {
+		t.Fatal("no invalid signatures among the vectors")
+	}
+}
@@ -0,0 +1,113 @@
+package crypto
+
+import (
+	"errors"
+	"strings"
+)
+
+/*
+Signature domain separation.
+
+Every signature a node produces is bound to a Domain: the chain ID and
+the kind of message (transaction, block, vote). The domain is encoded
+as a context string of at most 255 bytes,
+
+	"aegisq/" || type || "/" || chain ID
+
+Signers with a native context (ML-DSA, FIPS 204 §5.2) receive it
+directly. For the others the signed message is the FIPS 204-style
+encoding len(ctx) || ctx || message, so a signature made for one
+domain never verifies in another, on any algorithm.
+*/
+
+type MessageType string
+
+const (
+	MessageTx    MessageType = "TX"
+	MessageBlock MessageType = "BLOCK"
+	MessageVote  MessageType = "VOTE"
+
+	// MessageKeyRotation is the incoming key's consent to a rotation.
+	MessageKeyRotation MessageType = "KEYROT"
+)
+
+// MaxContextSize is the ML-DSA context string limit.
+const MaxContextSize = 255
+
+var errInvalidDomain = errors.New("invalid signature domain")
+
+// Domain identifies what a signature is for.
+type Domain struct {
+	ChainID string      `json:"chain_id"`
+	Type    MessageType `json:"type"`
+}
+
+// Context encodes the domain as a signing context string.
+func (d Domain) Context() ([]byte, error) {
+
+	if d.ChainID == "" || d.Type == "" || strings.Contains(string(d.Type), "/") {
+		return nil, errInvalidDomain
+	}
+
+	ctx := "aegisq/" + string(d.Type) + "/" + d.ChainID
+	if len(ctx) > MaxContextSize {
+		return nil, errInvalidDomain
+	}
+
+	return []byte(ctx), nil
+}
+
+// ContextSigner is implemented by signers that bind a context string
+// natively instead of prefixing it to the message.
+type ContextSigner interface {
+	SignContext(privateKey []byte, message []byte, context []byte) ([]byte, error)
+	VerifyContext(publicKey []byte, message []byte, signature []byte, context []byte) bool
+}
+
+// contextMessage is len(ctx) || ctx || message.
+func contextMessage(context, message []byte) []byte {
+	out := make([]byte, 0, 1+len(context)+len(message))
+	out = append(out, byte(len(context)))
+	out = append(out, context...)
+	return append(out, message...)
+}
+
+// SignInContext signs message bound to context.
+func SignInContext(signer Signer, privateKey []byte, message []byte, context []byte) ([]byte, error) {
+
+	if len(context) == 0 || len(context) > MaxContextSize {
+		return nil, errInvalidDomain
+	}
+
+	if cs, ok := signer.(ContextSigner); ok {
+		return cs.SignContext(privateKey, message, context)
+	}
+
+	return signer.Sign(privateKey, contextMessage(context, message))
+}
+
+// SignSecretInContext is SignInContext with a protected private key.
+func SignSecretInContext(signer Signer, privateKey *SecretKey, message []byte, context []byte) ([]byte, error) {
+	return signSecret(func(key, msg []byte) ([]byte, error) {
+		return SignInContext(signer, key, msg, context)
+	}, privateKey, message)
+}
+
+// VerifyInContext checks a signature made by SignInContext. An empty
+// context verifies a plain signature over message.
+func VerifyInContext(signer Signer, publicKey []byte, message []byte, signature []byte, context []byte) bool {
+
+	if len(context) == 0 {
+		return signer.Verify(publicKey, message, signature)
+	}
+
+	if len(context) > MaxContextSize {
+		return false
+	}
+
+	if cs, ok := signer.(ContextSigner); ok {
+		return cs.VerifyContext(publicKey, message, signature, context)
+	}
+
+	return signer.Verify(publicKey, contextMessage(context, message), signature)
+}
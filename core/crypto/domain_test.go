@@ -0,0 +1,89 @@
+package crypto
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDomainContext(t *testing.T) {
+
+	ctx, err := Domain{ChainID: "aegisq-testnet", Type: MessageBlock}.Context()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(ctx) != "aegisq/BLOCK/aegisq-testnet" {
+		t.Fatalf("unexpected context %q", ctx)
+	}
+
+	invalid := []Domain{
+		{ChainID: "", Type: MessageTx},
+		{ChainID: "chain", Type: ""},
+		{ChainID: "chain", Type: "TX/BLOCK"},
+		{ChainID: strings.Repeat("x", MaxContextSize), Type: MessageTx},
+	}
+
+	for _, d := range invalid {
+		if _, err := d.Context(); err == nil {
+			t.Fatalf("domain %+v should be rejected", d)
+		}
+	}
+}
+
+func TestSignInContextSeparatesDomains(t *testing.T) {
+
+	for name, signer := range getSigners(t) {
+		t.Run(name, func(t *testing.T) {
+
+			pub, priv, _ := signer.GenerateKeyPair()
+			msg := []byte("32-byte digest stand-in")
+
+			tx := []byte("aegisq/TX/chain")
+			block := []byte("aegisq/BLOCK/chain")
+
+			sig, err := SignInContext(signer, priv, msg, tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !VerifyInContext(signer, pub, msg, sig, tx) {
+				t.Fatal("signature rejected in its own context")
+			}
+
+			if VerifyInContext(signer, pub, msg, sig, block) {
+				t.Fatal("signature accepted in another context")
+			}
+
+			if signer.Verify(pub, msg, sig) {
+				t.Fatal("context signature accepted as plain signature")
+			}
+		})
+	}
+}
+
+// ML-DSA must bind the domain through its native context string, so
+// that other FIPS 204 implementations verify it with the same context.
+func TestDilithiumUsesNativeContext(t *testing.T) {
+
+	signer, _ := NewDilithiumSigner()
+	defer signer.Close()
+
+	var _ ContextSigner = signer
+
+	pub, priv, _ := signer.GenerateKeyPair()
+	msg := []byte("digest")
+	ctx := []byte("aegisq/VOTE/chain")
+
+	sig, err := SignInContext(signer, priv, msg, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !signer.VerifyContext(pub, msg, sig, ctx) {
+		t.Fatal("native context verification failed")
+	}
+
+	if signer.Verify(pub, contextMessage(ctx, msg), sig) {
+		t.Fatal("ML-DSA fell back to message prefixing")
+	}
+}
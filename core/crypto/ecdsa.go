@@ -1,10 +1,13 @@
 package crypto
 
 import (
+	stdcrypto "crypto"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/sha256"
+	"encoding/asn1"
+	"io"
 	"math/big"
 )
 
@@ -33,10 +36,17 @@ func (e *ECDSASigner) GenerateKeyPair() ([]byte, []byte, error) {
 }
 
 func (e *ECDSASigner) Sign(privateKey []byte, message []byte) ([]byte, error) {
+	return signECDSA(privateKey, message, rand.Reader)
+}
+
+// signECDSA signs with a fresh nonce from random, or with an RFC 6979
+// deterministic nonce when random is nil.
+func signECDSA(privateKey []byte, message []byte, random io.Reader) ([]byte, error) {
 
 	curve := elliptic.P256()
 
 	d := new(big.Int).SetBytes(privateKey)
+	defer wipeBigInt(d)
 
 	priv := new(ecdsa.PrivateKey)
 	priv.PublicKey.Curve = curve
@@ -45,9 +55,25 @@ func (e *ECDSASigner) Sign(privateKey []byte, message []byte) ([]byte, error) {
 
 	hash := sha256.Sum256(message)
 
-	r, s, err := ecdsa.Sign(rand.Reader, priv, hash[:])
-	if err != nil {
-		return nil, err
+	var r, s *big.Int
+
+	if random == nil {
+		der, err := priv.Sign(nil, hash[:], stdcrypto.SHA256)
+		if err != nil {
+			return nil, err
+		}
+
+		var parsed struct{ R, S *big.Int }
+		if _, err := asn1.Unmarshal(der, &parsed); err != nil {
+			return nil, err
+		}
+		r, s = parsed.R, parsed.S
+	} else {
+		var err error
+		r, s, err = ecdsa.Sign(random, priv, hash[:])
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// FIXED 64-byte signature
@@ -62,6 +88,11 @@ func (e *ECDSASigner) Sign(privateKey []byte, message []byte) ([]byte, error) {
 	return sig, nil
 }
 
+// SignSecret signs with a protected private key.
+func (e *ECDSASigner) SignSecret(privateKey *SecretKey, message []byte) ([]byte, error) {
+	return signSecret(e.Sign, privateKey, message)
+}
+
 func (e *ECDSASigner) Verify(publicKey, message, signature []byte) bool {
 
 	curve := elliptic.P256()
@@ -88,3 +119,12 @@ func (e *ECDSASigner) Verify(publicKey, message, signature []byte) bool {
 func (e *ECDSASigner) Algorithm() string {
 	return "ECDSA_P256"
 }
+
+// wipeBigInt zeroes the words backing a secret scalar.
+func wipeBigInt(n *big.Int) {
+	words := n.Bits()
+	for i := range words {
+		words[i] = 0
+	}
+	n.SetInt64(0)
+}
@@ -18,6 +18,11 @@ func (e *Ed25519Signer) Sign(privateKey []byte, message []byte) ([]byte, error)
 	return signature, nil
 }
 
+// SignSecret signs with a protected private key.
+func (e *Ed25519Signer) SignSecret(privateKey *SecretKey, message []byte) ([]byte, error) {
+	return signSecret(e.Sign, privateKey, message)
+}
+
 func (e *Ed25519Signer) Verify(publicKey []byte, message []byte, signature []byte) bool {
 	pub := ed25519.PublicKey(publicKey)
 	return ed25519.Verify(pub, message, signature)
@@ -3,6 +3,7 @@ package crypto
 import (
 	"fmt"
 	"os"
+	"sync"
 )
 
 func NewDefaultSigner() (Signer, error) {
@@ -20,3 +21,64 @@ func NewDefaultSigner() (Signer, error) {
 		return s, err
 	}
 }
+
+// NewSignerForAlgorithm returns a signer whose Algorithm() matches alg.
+// It is used when keys are loaded from disk and the algorithm is only
+// known by name.
+func NewSignerForAlgorithm(alg string) (Signer, error) {
+
+	switch alg {
+
+	case "dilithium2":
+		s, err := NewDilithiumSigner()
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+
+	case "ECDSA_P256":
+		return NewECDSASigner()
+
+	case "ed25519":
+		return &Ed25519Signer{}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported signature algorithm: %s", alg)
+	}
+}
+
+// SupportedAlgorithm reports whether alg names a signature algorithm
+// NewSignerForAlgorithm can build.
+func SupportedAlgorithm(alg string) bool {
+	switch alg {
+	case "dilithium2", "ECDSA_P256", "ed25519":
+		return true
+	}
+	return false
+}
+
+var (
+	verifiersMu sync.Mutex
+	verifiers   = make(map[string]Signer)
+)
+
+// Verifier returns a process-wide signer for alg, for verifying
+// signatures made under an algorithm other than the node's own, e.g.
+// blocks from before an algorithm migration. It must not be closed.
+func Verifier(alg string) (Signer, error) {
+
+	verifiersMu.Lock()
+	defer verifiersMu.Unlock()
+
+	if v, ok := verifiers[alg]; ok {
+		return v, nil
+	}
+
+	v, err := NewSignerForAlgorithm(alg)
+	if err != nil {
+		return nil, err
+	}
+
+	verifiers[alg] = v
+	return v, nil
+}
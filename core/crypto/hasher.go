@@ -0,0 +1,123 @@
+package crypto
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/sha3"
+)
+
+/*
+Chain hash functions.
+
+The hash used for transaction digests, Merkle nodes and block hashes
+is a chain parameter declared in genesis. Names are canonical and
+carry the output length where it is configurable:
+
+  - sha3-256, sha3-512
+  - shake256-<bits>   256 to 1024 bits, in bytes
+  - blake2b-<bits>    256 to 512 bits, in bytes
+
+Larger outputs raise the collision and preimage margin against
+Grover-style quantum search. Signers sign the resulting digest as an
+opaque message; the SHA-256 inside ECDSA P-256 is part of that
+signature scheme and is not affected.
+*/
+
+// DefaultHashAlgorithm is used when genesis does not declare one.
+const DefaultHashAlgorithm = "sha3-256"
+
+// Hasher is a chain hash function. Implementations are stateless and
+// safe for concurrent use.
+type Hasher interface {
+	Hash(data []byte) []byte
+	Size() int
+	Algorithm() string
+}
+
+// DefaultHasher is SHA3-256, the hash every chain used before hash
+// agility; it matches Hash.
+var DefaultHasher Hasher = sha3Hasher{size: 32}
+
+// sha3Hasher is SHA3-256 or SHA3-512, selected by size.
+type sha3Hasher struct{ size int }
+
+func (h sha3Hasher) Hash(data []byte) []byte {
+	if h.size == 64 {
+		sum := sha3.Sum512(data)
+		return sum[:]
+	}
+	sum := sha3.Sum256(data)
+	return sum[:]
+}
+
+func (h sha3Hasher) Size() int { return h.size }
+
+func (h sha3Hasher) Algorithm() string {
+	return fmt.Sprintf("sha3-%d", h.size*8)
+}
+
+type shake256Hasher struct{ size int }
+
+func (h shake256Hasher) Hash(data []byte) []byte {
+	out := make([]byte, h.size)
+	sha3.ShakeSum256(out, data)
+	return out
+}
+
+func (h shake256Hasher) Size() int { return h.size }
+
+func (h shake256Hasher) Algorithm() string {
+	return fmt.Sprintf("shake256-%d", h.size*8)
+}
+
+type blake2bHasher struct{ size int }
+
+func (h blake2bHasher) Hash(data []byte) []byte {
+	// New only fails for out-of-range sizes or oversized keys, both
+	// excluded by NewHasher.
+	d, _ := blake2b.New(h.size, nil)
+	d.Write(data)
+	return d.Sum(nil)
+}
+
+func (h blake2bHasher) Size() int { return h.size }
+
+func (h blake2bHasher) Algorithm() string {
+	return fmt.Sprintf("blake2b-%d", h.size*8)
+}
+
+// NewHasher returns the hash function with the given canonical name.
+// An empty name selects DefaultHashAlgorithm.
+func NewHasher(name string) (Hasher, error) {
+
+	switch name {
+	case "", "sha3-256":
+		return sha3Hasher{size: 32}, nil
+	case "sha3-512":
+		return sha3Hasher{size: 64}, nil
+	}
+
+	family, bits, ok := strings.Cut(name, "-")
+	if !ok {
+		return nil, fmt.Errorf("unsupported hash algorithm: %s", name)
+	}
+
+	// Only the canonical spelling, so one hash has one name in genesis
+	n, err := strconv.Atoi(bits)
+	if err != nil || bits != strconv.Itoa(n) || n%8 != 0 {
+		return nil, fmt.Errorf("unsupported hash algorithm: %s", name)
+	}
+	size := n / 8
+
+	switch {
+	case family == "shake256" && size >= 32 && size <= 128:
+		return shake256Hasher{size: size}, nil
+	case family == "blake2b" && size >= 32 && size <= blake2b.Size:
+		return blake2bHasher{size: size}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported hash algorithm: %s", name)
+}
@@ -0,0 +1,63 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestHasherVectors(t *testing.T) {
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"sha3-256", "3a985da74fe225b2045c172d6bd390bd855f086e3e9d525b46bfe24511431532"},
+		{"sha3-512", "b751850b1a57168a5693cd924b6b096e08f621827444f70d884f5d0240d2712e10e116e9192af3c91a7ec57647e3934057340b4cf408d5a56592f8274eec53f0"},
+		{"shake256-384", "483366601360a8771c6863080cc4114d8db44530f8f1e1ee4f94ea37e78b5739d5a15bef186a5386c75744c0527e1faa"},
+		{"shake256-512", "483366601360a8771c6863080cc4114d8db44530f8f1e1ee4f94ea37e78b5739d5a15bef186a5386c75744c0527e1faa9f8726e462a12a4feb06bd8801e751e4"},
+		{"blake2b-256", "bddd813c634239723171ef3fee98579b94964e3bb1cb3e427262c8c068d52319"},
+		{"blake2b-512", "ba80a53f981c4d0d6a2797b69f12f6e94c212f14685ac4b74b12bb6fdbffa2d17d87c5392aab792dc252d5de4533cc9518d38aa8dbf1925ab92386edd4009923"},
+	}
+
+	for _, test := range tests {
+
+		h, err := NewHasher(test.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want, _ := hex.DecodeString(test.want)
+		got := h.Hash([]byte("abc"))
+
+		if !bytes.Equal(got, want) {
+			t.Fatalf("%s: got %x", test.name, got)
+		}
+
+		if h.Size() != len(want) || h.Algorithm() != test.name {
+			t.Fatalf("%s: reported %s/%d", test.name, h.Algorithm(), h.Size())
+		}
+	}
+}
+
+func TestDefaultHasherMatchesHash(t *testing.T) {
+
+	h, _ := NewHasher("")
+
+	if h.Algorithm() != DefaultHashAlgorithm || DefaultHasher.Algorithm() != DefaultHashAlgorithm {
+		t.Fatal("empty name must select the default hash")
+	}
+
+	if !bytes.Equal(DefaultHasher.Hash([]byte("AegisQ")), Hash([]byte("AegisQ"))) {
+		t.Fatal("DefaultHasher must match Hash")
+	}
+}
+
+func TestNewHasherRejectsUnsupported(t *testing.T) {
+
+	for _, name := range []string{"sha256", "md5", "shake256-128", "shake256-2048", "shake256-260", "blake2b-1024", "blake2b-x", "sha3-384", "shake256-0256", "shake256-+256", "blake2b-0512"} {
+		if _, err := NewHasher(name); err == nil {
+			t.Fatalf("%s should be rejected", name)
+		}
+	}
+}
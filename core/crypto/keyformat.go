@@ -0,0 +1,296 @@
+package crypto
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/cloudflare/circl/sign/mldsa/mldsa44"
+)
+
+/*
+Standard key serialization.
+
+Public keys are encoded as PKIX SubjectPublicKeyInfo and private keys
+as PKCS#8, both wrapped in PEM ("PUBLIC KEY" / "PRIVATE KEY").
+
+ML-DSA-44 uses the NIST OID id-ml-dsa-44 (2.16.840.1.101.3.4.3.17)
+with absent parameters, following the IETF LAMPS ML-DSA profile.
+Private keys are written in the expandedKey form, which is the format
+the signers hold; seed-only and seed+expanded inputs are accepted.
+*/
+
+const (
+	pemPublicKeyType  = "PUBLIC KEY"
+	pemPrivateKeyType = "PRIVATE KEY"
+)
+
+var oidMLDSA44 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 17}
+
+type subjectPublicKeyInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+type pkcs8PrivateKey struct {
+	Version    int
+	Algorithm  pkix.AlgorithmIdentifier
+	PrivateKey []byte
+}
+
+// mldsaBothKey is the seed+expandedKey CHOICE of an ML-DSA private key.
+type mldsaBothKey struct {
+	Seed        []byte
+	ExpandedKey []byte
+}
+
+// MarshalPublicKeyPEM encodes a raw public key of the given algorithm
+// as a PEM-wrapped PKIX SubjectPublicKeyInfo.
+func MarshalPublicKeyPEM(alg string, pub []byte) ([]byte, error) {
+
+	var der []byte
+	var err error
+
+	switch alg {
+
+	case "dilithium2":
+		if len(pub) != mldsa44.PublicKeySize {
+			return nil, errors.New("invalid ML-DSA-44 public key length")
+		}
+		der, err = asn1.Marshal(subjectPublicKeyInfo{
+			Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidMLDSA44},
+			PublicKey: asn1.BitString{Bytes: pub, BitLength: 8 * len(pub)},
+		})
+
+	case "ECDSA_P256":
+		x, y := elliptic.Unmarshal(elliptic.P256(), pub)
+		if x == nil {
+			return nil, errors.New("invalid P-256 public key")
+		}
+		der, err = x509.MarshalPKIXPublicKey(&ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y})
+
+	case "ed25519":
+		if len(pub) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 public key length")
+		}
+		der, err = x509.MarshalPKIXPublicKey(ed25519.PublicKey(pub))
+
+	default:
+		return nil, fmt.Errorf("unsupported signature algorithm: %s", alg)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: pemPublicKeyType, Bytes: der}), nil
+}
+
+// ParsePublicKeyPEM decodes a PEM "PUBLIC KEY" block and returns the
+// algorithm identifier and raw public key used by the signers.
+func ParsePublicKeyPEM(data []byte) (string, []byte, error) {
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != pemPublicKeyType {
+		return "", nil, errors.New("no PUBLIC KEY PEM block found")
+	}
+
+	var spki subjectPublicKeyInfo
+	if rest, err := asn1.Unmarshal(block.Bytes, &spki); err != nil || len(rest) != 0 {
+		return "", nil, errors.New("malformed SubjectPublicKeyInfo")
+	}
+
+	if spki.Algorithm.Algorithm.Equal(oidMLDSA44) {
+
+		if len(spki.Algorithm.Parameters.FullBytes) != 0 {
+			return "", nil, errors.New("ML-DSA parameters must be absent")
+		}
+
+		pub := spki.PublicKey.RightAlign()
+		if len(pub) != mldsa44.PublicKeySize {
+			return "", nil, errors.New("invalid ML-DSA-44 public key length")
+		}
+
+		return "dilithium2", pub, nil
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return "", nil, err
+	}
+
+	switch k := key.(type) {
+
+	case *ecdsa.PublicKey:
+		if k.Curve != elliptic.P256() {
+			return "", nil, errors.New("unsupported ECDSA curve")
+		}
+		return "ECDSA_P256", elliptic.Marshal(k.Curve, k.X, k.Y), nil
+
+	case ed25519.PublicKey:
+		return "ed25519", []byte(k), nil
+
+	default:
+		return "", nil, errors.New("unsupported public key type")
+	}
+}
+
+// MarshalPrivateKeyPEM encodes a raw private key of the given algorithm
+// as a PEM-wrapped PKCS#8 PrivateKeyInfo.
+func MarshalPrivateKeyPEM(alg string, priv []byte) ([]byte, error) {
+
+	var der []byte
+	var err error
+
+	switch alg {
+
+	case "dilithium2":
+		if len(priv) != mldsa44.PrivateKeySize {
+			return nil, errors.New("invalid ML-DSA-44 private key length")
+		}
+
+		var inner []byte
+		inner, err = asn1.Marshal(priv)
+		if err != nil {
+			return nil, err
+		}
+
+		der, err = asn1.Marshal(pkcs8PrivateKey{
+			Algorithm:  pkix.AlgorithmIdentifier{Algorithm: oidMLDSA44},
+			PrivateKey: inner,
+		})
+
+	case "ECDSA_P256":
+		if len(priv) != 32 {
+			return nil, errors.New("invalid P-256 private key length")
+		}
+
+		curve := elliptic.P256()
+		key := &ecdsa.PrivateKey{D: new(big.Int).SetBytes(priv)}
+		key.PublicKey.Curve = curve
+		key.PublicKey.X, key.PublicKey.Y = curve.ScalarBaseMult(priv)
+
+		der, err = x509.MarshalPKCS8PrivateKey(key)
+
+	case "ed25519":
+		if len(priv) != ed25519.PrivateKeySize {
+			return nil, errors.New("invalid Ed25519 private key length")
+		}
+		der, err = x509.MarshalPKCS8PrivateKey(ed25519.PrivateKey(priv))
+
+	default:
+		return nil, fmt.Errorf("unsupported signature algorithm: %s", alg)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: pemPrivateKeyType, Bytes: der}), nil
+}
+
+// ParsePrivateKeyPEM decodes a PEM "PRIVATE KEY" block and returns the
+// algorithm identifier and raw private key used by the signers.
+func ParsePrivateKeyPEM(data []byte) (string, []byte, error) {
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != pemPrivateKeyType {
+		return "", nil, errors.New("no PRIVATE KEY PEM block found")
+	}
+
+	var p8 pkcs8PrivateKey
+	if rest, err := asn1.Unmarshal(block.Bytes, &p8); err != nil || len(rest) != 0 {
+		return "", nil, errors.New("malformed PKCS#8 private key")
+	}
+
+	if p8.Algorithm.Algorithm.Equal(oidMLDSA44) {
+		priv, err := parseMLDSA44PrivateKey(p8.PrivateKey)
+		if err != nil {
+			return "", nil, err
+		}
+		return "dilithium2", priv, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return "", nil, err
+	}
+
+	switch k := key.(type) {
+
+	case *ecdsa.PrivateKey:
+		if k.Curve != elliptic.P256() {
+			return "", nil, errors.New("unsupported ECDSA curve")
+		}
+		d := make([]byte, 32)
+		k.D.FillBytes(d)
+		return "ECDSA_P256", d, nil
+
+	case ed25519.PrivateKey:
+		return "ed25519", []byte(k), nil
+
+	default:
+		return "", nil, errors.New("unsupported private key type")
+	}
+}
+
+// parseMLDSA44PrivateKey accepts the seed [0], expandedKey and
+// both encodings and always returns the expanded key.
+func parseMLDSA44PrivateKey(data []byte) ([]byte, error) {
+
+	var raw asn1.RawValue
+	if rest, err := asn1.Unmarshal(data, &raw); err != nil || len(rest) != 0 {
+		return nil, errors.New("malformed ML-DSA private key")
+	}
+
+	switch {
+
+	case raw.Class == asn1.ClassContextSpecific && raw.Tag == 0 && !raw.IsCompound:
+		return expandMLDSA44Seed(raw.Bytes)
+
+	case raw.Class == asn1.ClassUniversal && raw.Tag == asn1.TagOctetString:
+		if len(raw.Bytes) != mldsa44.PrivateKeySize {
+			return nil, errors.New("invalid ML-DSA-44 private key length")
+		}
+		return raw.Bytes, nil
+
+	case raw.Class == asn1.ClassUniversal && raw.Tag == asn1.TagSequence:
+		var both mldsaBothKey
+		if _, err := asn1.Unmarshal(data, &both); err != nil {
+			return nil, errors.New("malformed ML-DSA private key")
+		}
+
+		expanded, err := expandMLDSA44Seed(both.Seed)
+		if err != nil {
+			return nil, err
+		}
+
+		if string(expanded) != string(both.ExpandedKey) {
+			return nil, errors.New("ML-DSA seed and expanded key do not match")
+		}
+		return expanded, nil
+
+	default:
+		return nil, errors.New("unsupported ML-DSA private key encoding")
+	}
+}
+
+func expandMLDSA44Seed(seed []byte) ([]byte, error) {
+
+	if len(seed) != mldsa44.SeedSize {
+		return nil, errors.New("invalid ML-DSA seed length")
+	}
+
+	var s [mldsa44.SeedSize]byte
+	copy(s[:], seed)
+
+	_, sk := mldsa44.NewKeyFromSeed(&s)
+	return sk.Bytes(), nil
+}
@@ -0,0 +1,96 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/pem"
+	"testing"
+)
+
+func TestKeyPEMRoundTrip(t *testing.T) {
+
+	signers := getSigners(t)
+	signers["Ed25519"] = &Ed25519Signer{}
+
+	for name, signer := range signers {
+		t.Run(name, func(t *testing.T) {
+
+			pub, priv, err := signer.GenerateKeyPair()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			pubPEM, err := MarshalPublicKeyPEM(signer.Algorithm(), pub)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			privPEM, err := MarshalPrivateKeyPEM(signer.Algorithm(), priv)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			alg, pub2, err := ParsePublicKeyPEM(pubPEM)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if alg != signer.Algorithm() || !bytes.Equal(pub, pub2) {
+				t.Fatal("public key round trip mismatch")
+			}
+
+			alg, priv2, err := ParsePrivateKeyPEM(privPEM)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if alg != signer.Algorithm() || !bytes.Equal(priv, priv2) {
+				t.Fatal("private key round trip mismatch")
+			}
+
+			msg := []byte("PEM round trip")
+
+			sig, err := signer.Sign(priv2, msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !signer.Verify(pub2, msg, sig) {
+				t.Fatal("signature with re-imported keys failed")
+			}
+		})
+	}
+}
+
+func TestMLDSAPublicKeyUsesNISTOID(t *testing.T) {
+
+	signer, _ := NewDilithiumSigner()
+	defer signer.Close()
+
+	pub, _, _ := signer.GenerateKeyPair()
+
+	pubPEM, err := MarshalPublicKeyPEM("dilithium2", pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block, _ := pem.Decode(pubPEM)
+
+	// SEQUENCE { SEQUENCE { OID 2.16.840.1.101.3.4.3.17 } BIT STRING ... }
+	wantAlgID := []byte{0x30, 0x0b, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x03, 0x11}
+
+	if !bytes.Contains(block.Bytes[:20], wantAlgID) {
+		t.Fatalf("unexpected AlgorithmIdentifier: %x", block.Bytes[:20])
+	}
+}
+
+func TestParseRejectsWrongPEMType(t *testing.T) {
+
+	signer := &Ed25519Signer{}
+	pub, _, _ := signer.GenerateKeyPair()
+
+	pubPEM, _ := MarshalPublicKeyPEM("ed25519", pub)
+
+	if _, _, err := ParsePrivateKeyPEM(pubPEM); err == nil {
+		t.Fatal("public key PEM must not parse as private key")
+	}
+}
@@ -0,0 +1,85 @@
+//go:build go1.27
+
+package crypto
+
+import (
+	"crypto/mldsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+)
+
+// Cross-checks the ML-DSA encodings against the standard library,
+// whose x509 package implements the same IETF profile.
+
+func TestMLDSAPublicKeyParsesWithX509(t *testing.T) {
+
+	signer, _ := NewDilithiumSigner()
+	defer signer.Close()
+
+	pub, priv, _ := signer.GenerateKeyPair()
+
+	pubPEM, err := MarshalPublicKeyPEM("dilithium2", pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block, _ := pem.Decode(pubPEM)
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mpk, ok := key.(*mldsa.PublicKey)
+	if !ok {
+		t.Fatalf("expected *mldsa.PublicKey, got %T", key)
+	}
+
+	msg := []byte("interop")
+
+	sig, err := signer.Sign(priv, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mldsa.Verify(mpk, msg, sig, nil); err != nil {
+		t.Fatal("standard library rejected signature:", err)
+	}
+}
+
+func TestMLDSASeedPrivateKeyFromX509(t *testing.T) {
+
+	sk, err := mldsa.GenerateKey(mldsa.MLDSA44())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alg, priv, err := ParsePrivateKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if alg != "dilithium2" {
+		t.Fatal("unexpected algorithm", alg)
+	}
+
+	signer, _ := NewDilithiumSigner()
+	defer signer.Close()
+
+	msg := []byte("seed-only import")
+
+	sig, err := signer.Sign(priv, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mldsa.Verify(sk.PublicKey(), msg, sig, nil); err != nil {
+		t.Fatal("imported seed key produced invalid signature:", err)
+	}
+}
@@ -20,6 +20,11 @@ func (p *PQCSigner) Sign(privateKey []byte, message []byte) ([]byte, error) {
 	return hash[:], nil
 }
 
+// SignSecret signs with a protected private key.
+func (p *PQCSigner) SignSecret(privateKey *SecretKey, message []byte) ([]byte, error) {
+	return signSecret(p.Sign, privateKey, message)
+}
+
 func (p *PQCSigner) Verify(publicKey []byte, message []byte, signature []byte) bool {
 	hash := sha3.Sum256(append(publicKey, message...))
 	return string(hash[:]) == string(signature)
@@ -0,0 +1,122 @@
+package crypto
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// SecretKey holds private key material outside ordinary Go values.
+//
+// The bytes live in locked, non-swappable memory where the platform
+// allows it (outside the Go heap, so heap dumps do not contain them),
+// are zeroed by Destroy, and never appear in fmt or JSON output.
+type SecretKey struct {
+	mu     sync.RWMutex
+	buf    []byte
+	locked bool
+}
+
+var errSecretDestroyed = errors.New("secret key destroyed")
+
+// NewSecretKey copies b into protected memory and wipes b.
+func NewSecretKey(b []byte) *SecretKey {
+
+	buf, locked := allocSecret(len(b))
+	copy(buf, b)
+	Wipe(b)
+
+	k := &SecretKey{buf: buf, locked: locked}
+	runtime.SetFinalizer(k, (*SecretKey).Destroy)
+
+	return k
+}
+
+// Wipe overwrites b with zeros.
+func Wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+	runtime.KeepAlive(b)
+}
+
+// Use calls fn with the raw key bytes. The slice must not be retained
+// after fn returns; it is only valid until Destroy.
+func (k *SecretKey) Use(fn func(key []byte) error) error {
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	if k.buf == nil {
+		return errSecretDestroyed
+	}
+
+	return fn(k.buf)
+}
+
+// Bytes returns a copy of the key. Callers own the copy and should
+// Wipe it when done; prefer Use where possible.
+func (k *SecretKey) Bytes() []byte {
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return append([]byte(nil), k.buf...)
+}
+
+// Len returns the key length, or 0 after Destroy.
+func (k *SecretKey) Len() int {
+	if k == nil {
+		return 0
+	}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return len(k.buf)
+}
+
+// Locked reports whether the key is held in locked memory.
+func (k *SecretKey) Locked() bool {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return k.locked
+}
+
+// Destroy zeroes and releases the key. It is idempotent.
+func (k *SecretKey) Destroy() {
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.buf == nil {
+		return
+	}
+
+	Wipe(k.buf)
+	freeSecret(k.buf, k.locked)
+
+	k.buf = nil
+	k.locked = false
+
+	runtime.SetFinalizer(k, nil)
+}
+
+func (k *SecretKey) String() string {
+	return "SecretKey(REDACTED)"
+}
+
+func (k *SecretKey) GoString() string {
+	return k.String()
+}
+
+func (k *SecretKey) Format(f fmt.State, verb rune) {
+	fmt.Fprint(f, k.String())
+}
+
+// MarshalJSON refuses to serialize key material.
+func (k *SecretKey) MarshalJSON() ([]byte, error) {
+	return nil, errors.New("secret key must not be serialized")
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package crypto
+
+// allocSecret has no locked memory on this platform; the key is
+// still wiped on Destroy.
+func allocSecret(n int) ([]byte, bool) {
+	return make([]byte, n), false
+}
+
+func freeSecret(buf []byte, locked bool) {}
@@ -0,0 +1,35 @@
+//go:build unix
+
+package crypto
+
+import "golang.org/x/sys/unix"
+
+// allocSecret maps anonymous memory and locks it into RAM. If either
+// step fails (e.g. RLIMIT_MEMLOCK is exhausted) it falls back to the
+// Go heap and reports locked=false.
+func allocSecret(n int) ([]byte, bool) {
+
+	if n == 0 {
+		return []byte{}, false
+	}
+
+	buf, err := unix.Mmap(-1, 0, n, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_ANON|unix.MAP_PRIVATE)
+	if err != nil {
+		return make([]byte, n), false
+	}
+
+	if err := unix.Mlock(buf); err != nil {
+		unix.Munmap(buf)
+		return make([]byte, n), false
+	}
+
+	return buf, true
+}
+
+func freeSecret(buf []byte, locked bool) {
+	if !locked {
+		return
+	}
+	unix.Munlock(buf)
+	unix.Munmap(buf)
+}
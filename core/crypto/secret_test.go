@@ -0,0 +1,108 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestSecretKeyWipesSource(t *testing.T) {
+
+	src := []byte{1, 2, 3, 4}
+	k := NewSecretKey(src)
+	defer k.Destroy()
+
+	if !bytes.Equal(src, make([]byte, 4)) {
+		t.Fatal("source slice not wiped")
+	}
+
+	if !bytes.Equal(k.Bytes(), []byte{1, 2, 3, 4}) {
+		t.Fatal("key material not preserved")
+	}
+}
+
+func TestSecretKeyDestroy(t *testing.T) {
+
+	k := NewSecretKey([]byte("private key material"))
+
+	var view []byte
+	k.Use(func(b []byte) error {
+		view = b
+		return nil
+	})
+
+	// view aliases the protected buffer; only read it on the heap
+	// fallback, where the memory stays mapped after Destroy.
+	locked := k.Locked()
+
+	k.Destroy()
+	k.Destroy()
+
+	if !locked && !bytes.Equal(view, make([]byte, len(view))) {
+		t.Fatal("buffer not zeroed on Destroy")
+	}
+
+	if k.Len() != 0 {
+		t.Fatal("destroyed key still reports a length")
+	}
+
+	if err := k.Use(func([]byte) error { return nil }); err == nil {
+		t.Fatal("Use after Destroy should fail")
+	}
+}
+
+func TestSecretKeyRedacted(t *testing.T) {
+
+	k := NewSecretKey([]byte("hunter2-hunter2"))
+	defer k.Destroy()
+
+	holder := struct{ Key *SecretKey }{k}
+
+	for _, format := range []string{"%v", "%+v", "%#v", "%s", "%x", "%q"} {
+		out := fmt.Sprintf(format, holder)
+		if bytes.Contains([]byte(out), []byte("hunter2")) || bytes.Contains([]byte(out), []byte("68756e74")) {
+			t.Fatalf("%s leaked key material: %s", format, out)
+		}
+	}
+
+	if _, err := json.Marshal(holder); err == nil {
+		t.Fatal("JSON serialization of secret key should fail")
+	}
+}
+
+func TestSignSecret(t *testing.T) {
+
+	signers := []Signer{&Ed25519Signer{}}
+
+	ecdsa, _ := NewECDSASigner()
+	dil, _ := NewDilithiumSigner()
+	defer dil.Close()
+
+	signers = append(signers, ecdsa, dil)
+
+	for _, s := range signers {
+
+		pub, priv, err := s.GenerateKeyPair()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		k := NewSecretKey(priv)
+
+		sig, err := s.SignSecret(k, []byte("message"))
+		if err != nil {
+			t.Fatalf("%s: %v", s.Algorithm(), err)
+		}
+
+		if !s.Verify(pub, []byte("message"), sig) {
+			t.Fatalf("%s: signature from SecretKey failed verification", s.Algorithm())
+		}
+
+		k.Destroy()
+
+		if _, err := s.SignSecret(k, []byte("message")); err == nil {
+			t.Fatalf("%s: signing with destroyed key should fail", s.Algorithm())
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package crypto
+
+import (
+	"container/list"
+	"encoding/binary"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// DefaultSignatureCacheSize bounds the process-wide cache; at 10,000
+// transactions per block this covers several blocks of pool traffic.
+const DefaultSignatureCacheSize = 100000
+
+// DefaultSignatureCache is consulted by transaction and block
+// verification. Set it to nil to disable caching.
+var DefaultSignatureCache = NewSignatureCache(DefaultSignatureCacheSize)
+
+type sigCacheKey [32]byte
+
+// SignatureCache is a bounded LRU set of signatures that have already
+// verified successfully. Failed verifications are never cached.
+// It is safe for concurrent use.
+type SignatureCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	entries  map[sigCacheKey]*list.Element
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// SignatureCacheStats reports cache effectiveness.
+type SignatureCacheStats struct {
+	Hits     uint64  `json:"hits"`
+	Misses   uint64  `json:"misses"`
+	HitRate  float64 `json:"hit_rate"`
+	Size     int     `json:"size"`
+	Capacity int     `json:"capacity"`
+}
+
+func NewSignatureCache(capacity int) *SignatureCache {
+	if capacity <= 0 {
+		capacity = 1
+	}
+	return &SignatureCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[sigCacheKey]*list.Element),
+	}
+}
+
+// cacheKey commits to (algorithm, context, public key, message,
+// signature) with length prefixes so field boundaries cannot be shifted.
+func cacheKey(alg string, it BatchItem) sigCacheKey {
+
+	h := sha3.New256()
+
+	var n [8]byte
+	for _, part := range [][]byte{[]byte(alg), it.Context, it.PublicKey, it.Message, it.Signature} {
+		binary.BigEndian.PutUint64(n[:], uint64(len(part)))
+		h.Write(n[:])
+		h.Write(part)
+	}
+
+	var key sigCacheKey
+	h.Sum(key[:0])
+	return key
+}
+
+func (c *SignatureCache) contains(key sigCacheKey) bool {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if ok {
+		c.order.MoveToFront(el)
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+
+	return ok
+}
+
+func (c *SignatureCache) add(key sigCacheKey) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(key)
+
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(sigCacheKey))
+	}
+}
+
+// Verify returns true for signatures already known to be valid and
+// otherwise verifies the item, remembering a successful result.
+// A nil cache verifies without caching.
+func (c *SignatureCache) Verify(signer Signer, it BatchItem) bool {
+
+	if c == nil {
+		return VerifyInContext(signer, it.PublicKey, it.Message, it.Signature, it.Context)
+	}
+
+	key := cacheKey(signer.Algorithm(), it)
+
+	if c.contains(key) {
+		return true
+	}
+
+	if !VerifyInContext(signer, it.PublicKey, it.Message, it.Signature, it.Context) {
+		return false
+	}
+
+	c.add(key)
+	return true
+}
+
+// VerifyBatch is VerifyBatch with cached items skipped; only the
+// misses are handed to the signer.
+func (c *SignatureCache) VerifyBatch(signer Signer, items []BatchItem, workers int) []bool {
+
+	if c == nil {
+		return VerifyBatch(signer, items, workers)
+	}
+
+	alg := signer.Algorithm()
+
+	results := make([]bool, len(items))
+	keys := make([]sigCacheKey, len(items))
+
+	var pending []BatchItem
+	var pendingIdx []int
+
+	for i, it := range items {
+		keys[i] = cacheKey(alg, it)
+
+		if c.contains(keys[i]) {
+			results[i] = true
+			continue
+		}
+
+		pending = append(pending, it)
+		pendingIdx = append(pendingIdx, i)
+	}
+
+	for j, ok := range VerifyBatch(signer, pending, workers) {
+		i := pendingIdx[j]
+		results[i] = ok
+		if ok {
+			c.add(keys[i])
+		}
+	}
+
+	return results
+}
+
+// Stats returns a snapshot of hit/miss counters and occupancy.
+func (c *SignatureCache) Stats() SignatureCacheStats {
+
+	if c == nil {
+		return SignatureCacheStats{}
+	}
+
+	c.mu.Lock()
+	size := c.order.Len()
+	c.mu.Unlock()
+
+	hits := c.hits.Load()
+	misses := c.misses.Load()
+
+	var rate float64
+	if hits+misses > 0 {
+		rate = float64(hits) / float64(hits+misses)
+	}
+
+	return SignatureCacheStats{
+		Hits:     hits,
+		Misses:   misses,
+		HitRate:  rate,
+		Size:     size,
+		Capacity: c.capacity,
+	}
+}
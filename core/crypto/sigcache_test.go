@@ -0,0 +1,114 @@
+package crypto
+
+import "testing"
+
+// countingSigner records how often Verify reaches the real signer.
+type countingSigner struct {
+	Signer
+	calls int
+}
+
+func (c *countingSigner) Verify(publicKey, message, signature []byte) bool {
+	c.calls++
+	return c.Signer.Verify(publicKey, message, signature)
+}
+
+func TestSignatureCacheSkipsRepeatVerification(t *testing.T) {
+
+	signer := &countingSigner{Signer: &Ed25519Signer{}}
+	cache := NewSignatureCache(16)
+
+	pub, priv, _ := signer.GenerateKeyPair()
+	msg := []byte("pool admission")
+	sig, _ := signer.Sign(priv, msg)
+
+	for i := 0; i < 3; i++ {
+		if !cache.Verify(signer, BatchItem{PublicKey: pub, Message: msg, Signature: sig}) {
+			t.Fatal("valid signature rejected")
+		}
+	}
+
+	if signer.calls != 1 {
+		t.Fatalf("expected 1 real verification, got %d", signer.calls)
+	}
+
+	stats := cache.Stats()
+	if stats.Hits != 2 || stats.Misses != 1 || stats.Size != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestSignatureCacheDoesNotCacheFailures(t *testing.T) {
+
+	signer := &countingSigner{Signer: &Ed25519Signer{}}
+	cache := NewSignatureCache(16)
+
+	pub, priv, _ := signer.GenerateKeyPair()
+	sig, _ := signer.Sign(priv, []byte("original"))
+
+	for i := 0; i < 2; i++ {
+		if cache.Verify(signer, BatchItem{PublicKey: pub, Message: []byte("tampered"), Signature: sig}) {
+			t.Fatal("invalid signature accepted")
+		}
+	}
+
+	if signer.calls != 2 || cache.Stats().Size != 0 {
+		t.Fatal("failed verification must not be cached")
+	}
+}
+
+func TestSignatureCacheEvictsLeastRecentlyUsed(t *testing.T) {
+
+	signer := &Ed25519Signer{}
+	cache := NewSignatureCache(2)
+
+	pub, priv, _ := signer.GenerateKeyPair()
+
+	msgs := [][]byte{[]byte("a"), []byte("b"), []byte("c")}
+	sigs := make([][]byte, len(msgs))
+
+	for i, m := range msgs {
+		sigs[i], _ = signer.Sign(priv, m)
+	}
+
+	cache.Verify(signer, BatchItem{PublicKey: pub, Message: msgs[0], Signature: sigs[0]})
+	cache.Verify(signer, BatchItem{PublicKey: pub, Message: msgs[1], Signature: sigs[1]})
+	cache.Verify(signer, BatchItem{PublicKey: pub, Message: msgs[0], Signature: sigs[0]}) // a is now most recent
+	cache.Verify(signer, BatchItem{PublicKey: pub, Message: msgs[2], Signature: sigs[2]}) // evicts b
+
+	if !cache.contains(cacheKey(signer.Algorithm(), BatchItem{PublicKey: pub, Message: msgs[0], Signature: sigs[0]})) {
+		t.Fatal("recently used entry evicted")
+	}
+
+	if cache.contains(cacheKey(signer.Algorithm(), BatchItem{PublicKey: pub, Message: msgs[1], Signature: sigs[1]})) {
+		t.Fatal("least recently used entry not evicted")
+	}
+}
+
+func TestSignatureCacheBatchOnlyVerifiesMisses(t *testing.T) {
+
+	signer := &countingSigner{Signer: &Ed25519Signer{}}
+	cache := NewSignatureCache(16)
+
+	pub, priv, _ := signer.GenerateKeyPair()
+
+	items := make([]BatchItem, 4)
+	for i := range items {
+		msg := []byte{byte(i)}
+		sig, _ := signer.Sign(priv, msg)
+		items[i] = BatchItem{PublicKey: pub, Message: msg, Signature: sig}
+	}
+
+	cache.Verify(signer, items[0])
+	cache.Verify(signer, items[1])
+
+	for _, ok := range cache.VerifyBatch(signer, items, 1) {
+		if !ok {
+			t.Fatal("valid batch item rejected")
+		}
+	}
+
+	if signer.calls != 4 {
+		t.Fatalf("expected 4 real verifications, got %d", signer.calls)
+	}
+}
@@ -3,6 +3,36 @@ package crypto
 type Signer interface {
 	GenerateKeyPair() ([]byte, []byte, error)
 	Sign(privateKey []byte, message []byte) ([]byte, error)
+	SignSecret(privateKey *SecretKey, message []byte) ([]byte, error)
 	Verify(publicKey []byte, message []byte, signature []byte) bool
 	Algorithm() string
-}
\ No newline at end of file
+}
+
+// signSecret runs sign over a transient heap copy of the protected key
+// and wipes the copy afterwards. Standard library signers may cache
+// derived state keyed by the key's address (crypto/ed25519 does), which
+// only works for Go-heap memory, so the locked buffer is never passed
+// to them directly.
+func signSecret(
+	sign func(privateKey []byte, message []byte) ([]byte, error),
+	privateKey *SecretKey,
+	message []byte,
+) ([]byte, error) {
+
+	if privateKey == nil {
+		return nil, errSecretDestroyed
+	}
+
+	var signature []byte
+
+	err := privateKey.Use(func(key []byte) error {
+		tmp := append([]byte(nil), key...)
+		defer Wipe(tmp)
+
+		var err error
+		signature, err = sign(tmp, message)
+		return err
+	})
+
+	return signature, err
+}
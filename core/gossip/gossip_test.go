@@ -0,0 +1,392 @@
+package gossip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/chain"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/identity"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/transaction"
+)
+
+// testTxs returns n signed transactions.
+func testTxs(t *testing.T, n int) []*transaction.Transaction {
+
+	node, err := identity.NewNodeIdentity("client", &crypto.Ed25519Signer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var txs []*transaction.Transaction
+
+	for i := 0; i < n; i++ {
+		tx := transaction.NewTransaction(node, fmt.Sprintf("data-%d", i), "")
+		if err := tx.SignWith(node, chain.Default); err != nil {
+			t.Fatal(err)
+		}
+		txs = append(txs, tx)
+	}
+
+	return txs
+}
+
+// recorder counts the messages applied by one node.
+type recorder struct {
+	mu      sync.Mutex
+	applied map[string]int
+}
+
+func newRecorder() *recorder {
+	return &recorder{applied: make(map[string]int)}
+}
+
+func (rec *recorder) apply(m *Message) error {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	rec.applied[m.Tx.DataHash]++
+	return nil
+}
+
+func (rec *recorder) count() int {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	return len(rec.applied)
+}
+
+func quietRouter(apply Apply, cfg Config) *Router {
+	r := NewRouter(chain.Default.Hasher, apply, cfg)
+	r.Logf = func(string, ...interface{}) {}
+	return r
+}
+
+// connect makes a and b peers of each other in process.
+func connect(routers []*Router, a, b int) {
+	routers[a].AddPeer(NewLocalPeer(fmt.Sprint(b), fmt.Sprint(a), routers[b]))
+	routers[b].AddPeer(NewLocalPeer(fmt.Sprint(a), fmt.Sprint(b), routers[a]))
+}
+
+func waitFor(t *testing.T, what string, done func() bool) {
+
+	deadline := time.Now().Add(10 * time.Second)
+
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSeenSetEvictsOldest(t *testing.T) {
+
+	s := newSeenSet(2)
+
+	if !s.add("a") || !s.add("b") || s.add("a") {
+		t.Fatal("unexpected membership")
+	}
+
+	// "c" evicts "a"
+	if !s.add("c") || !s.add("a") || s.add("c") {
+		t.Fatal("oldest ID was not evicted")
+	}
+}
+
+func TestSeenSetRemovedIDKeepsNewSlot(t *testing.T) {
+
+	s := newSeenSet(3)
+
+	s.add("a")
+	s.add("b")
+	s.remove("a")
+
+	// "a" is added again in a new slot; evicting its old one must not
+	// forget it
+	if !s.add("a") || !s.add("c") {
+		t.Fatal("unexpected membership")
+	}
+
+	if s.add("a") {
+		t.Fatal("re-added ID was evicted with its old slot")
+	}
+
+	if !s.add("d") || s.add("a") {
+		t.Fatal("unexpected membership after eviction")
+	}
+}
+
+// TestFloodPropagation floods transactions from random nodes of a
+// random network and reports how they spread.
+func TestFloodPropagation(t *testing.T) {
+
+	const nodes, degree, count = 40, 3, 50
+
+	rng := rand.New(rand.NewSource(1))
+
+	recorders := make([]*recorder, nodes)
+	routers := make([]*Router, nodes)
+
+	for i := range routers {
+		recorders[i] = newRecorder()
+		routers[i] = quietRouter(recorders[i].apply, Config{})
+		defer routers[i].Close()
+	}
+
+	// A ring keeps the network connected; random links shorten it
+	links := 0
+	for i := 0; i < nodes; i++ {
+		connect(routers, i, (i+1)%nodes)
+		links++
+
+		for j := 2; j < degree; j++ {
+			if k := rng.Intn(nodes); k != i && k != (i+1)%nodes {
+				connect(routers, i, k)
+				links++
+			}
+		}
+	}
+
+	txs := testTxs(t, count)
+	start := time.Now()
+
+	for _, tx := range txs {
+
+		origin := rng.Intn(nodes)
+		recorders[origin].apply(&Message{Kind: KindTx, Tx: tx})
+
+		if err := routers[origin].Publish(context.Background(), NewTx(tx)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	waitFor(t, "the flood", func() bool {
+		for _, rec := range recorders {
+			if rec.count() < count {
+				return false
+			}
+		}
+		return true
+	})
+
+	elapsed := time.Since(start)
+
+	var total Stats
+	for i, r := range routers {
+
+		for hash, n := range recorders[i].applied {
+			if n != 1 {
+				t.Fatalf("node %d applied %s %d times", i, hash, n)
+			}
+		}
+
+		s := r.Stats()
+		total.Received += s.Received
+		total.Duplicates += s.Duplicates
+		total.Sent += s.Sent
+		total.Dropped += s.Dropped
+		if s.MaxHops > total.MaxHops {
+			total.MaxHops = s.MaxHops
+		}
+	}
+
+	if total.Received != (nodes-1)*count || total.Dropped != 0 {
+		t.Fatalf("%d messages received and %d dropped, want %d and 0", total.Received, total.Dropped, (nodes-1)*count)
+	}
+
+	t.Logf("%d txs over %d nodes and %d links in %s: max %d hops, %d sends (%.1f per delivery), %d duplicates",
+		count, nodes, links, elapsed.Round(time.Millisecond), total.MaxHops,
+		total.Sent, float64(total.Sent)/float64(total.Received), total.Duplicates)
+}
+
+func TestRejectedMessagesAreNotRelayed(t *testing.T) {
+
+	txs := testTxs(t, 1)
+
+	var mu sync.Mutex
+	rejecting := true
+
+	last := newRecorder()
+	routers := []*Router{
+		quietRouter(newRecorder().apply, Config{}),
+		quietRouter(func(m *Message) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if rejecting {
+				return errors.New("invalid")
+			}
+			return nil
+		}, Config{}),
+		quietRouter(last.apply, Config{}),
+	}
+
+	for _, r := range routers {
+		defer r.Close()
+	}
+
+	connect(routers, 0, 1)
+	connect(routers, 1, 2)
+
+	if err := routers[0].Publish(context.Background(), NewTx(txs[0])); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, "the rejection", func() bool { return routers[1].Stats().Rejected == 1 })
+
+	time.Sleep(20 * time.Millisecond)
+	if last.count() != 0 {
+		t.Fatal("rejected message was relayed")
+	}
+
+	// The rejected ID was forgotten, so a valid copy goes through
+	mu.Lock()
+	rejecting = false
+	mu.Unlock()
+
+	if err := routers[1].Receive("0", []Message{NewTx(txs[0])}); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, "the relay", func() bool { return last.count() == 1 })
+}
+
+// gatedPeer is busy until opened.
+type gatedPeer struct {
+	mu       sync.Mutex
+	open     bool
+	received int
+}
+
+func (p *gatedPeer) ID() string { return "gated" }
+
+func (p *gatedPeer) Send(ctx context.Context, msgs []Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.open {
+		return ErrBusy
+	}
+
+	p.received += len(msgs)
+	return nil
+}
+
+func (p *gatedPeer) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.received
+}
+
+func TestPublishBackpressure(t *testing.T) {
+
+	cfg := Config{QueueSize: 4, BatchSize: 2, Retry: time.Millisecond}
+
+	r := quietRouter(newRecorder().apply, cfg)
+	defer r.Close()
+
+	peer := &gatedPeer{}
+	r.AddPeer(peer)
+
+	txs := testTxs(t, 20)
+
+	// The busy peer's sender holds a batch and its queue fills up
+	published := 0
+	var busy error
+
+	for _, tx := range txs {
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		busy = r.Publish(ctx, NewTx(tx))
+		cancel()
+
+		if busy != nil {
+			break
+		}
+		published++
+	}
+
+	if !errors.Is(busy, ErrBusy) || published > cfg.QueueSize+cfg.BatchSize {
+		t.Fatalf("published %d messages to a busy peer (%v)", published, busy)
+	}
+
+	// Once the peer takes messages again, the refused one can be
+	// published again and everything arrives
+	peer.mu.Lock()
+	peer.open = true
+	peer.mu.Unlock()
+
+	if err := r.Publish(context.Background(), NewTx(txs[published])); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, "the backlog", func() bool { return peer.count() == published+1 })
+
+	if s := r.Stats(); s.Dropped != 0 || s.Sent != uint64(published+1) {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+}
+
+func TestRelayDropsForFullQueues(t *testing.T) {
+
+	cfg := Config{QueueSize: 2, BatchSize: 1, Retry: time.Hour}
+
+	r := quietRouter(newRecorder().apply, cfg)
+	defer r.Close()
+
+	r.AddPeer(&gatedPeer{})
+
+	txs := testTxs(t, 10)
+
+	for _, tx := range txs {
+		if err := r.Broadcast(NewTx(tx)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Two queued, one more if the sender took its batch early, the
+	// rest dropped
+	waitFor(t, "the drops", func() bool { return r.Stats().Dropped >= 7 })
+
+	if d := r.Stats().Dropped; d > 8 {
+		t.Fatalf("%d messages dropped", d)
+	}
+}
+
+func TestHTTPTransport(t *testing.T) {
+
+	txs := testTxs(t, 3)
+
+	recv := newRecorder()
+	block := make(chan struct{})
+
+	// The receiver is stuck in its first message, so its inbox fills
+	receiver := quietRouter(func(m *Message) error {
+		<-block
+		return recv.apply(m)
+	}, Config{QueueSize: 1})
+	defer receiver.Close()
+	defer close(block)
+
+	srv := httptest.NewServer(Handler(receiver))
+	defer srv.Close()
+
+	peer := NewHTTPPeer(srv.URL)
+
+	var err error
+	for i := 0; i < 3 && err == nil; i++ {
+		err = peer.Send(context.Background(), []Message{NewTx(txs[i])})
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if !errors.Is(err, ErrBusy) {
+		t.Fatal("a full inbox should answer busy:", err)
+	}
+}
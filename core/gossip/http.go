@@ -0,0 +1,116 @@
+package gossip
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxBatchBytes bounds a batch read from a peer: BatchSize Dilithium
+// transactions, or a proposal of a full block.
+const maxBatchBytes = 64 << 20
+
+// Handler serves r to gossiping peers:
+//
+//	POST /gossip    a JSON array of messages
+//
+// A full inbox answers 503, and the peer retries the batch. Senders
+// are known by their host.
+func Handler(r *Router) http.Handler {
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/gossip", func(w http.ResponseWriter, req *http.Request) {
+
+		if req.Method != http.MethodPost {
+			http.Error(w, "POST a batch of messages", 405)
+			return
+		}
+
+		from, _, err := net.SplitHostPort(req.RemoteAddr)
+		if err != nil {
+			from = req.RemoteAddr
+		}
+
+		var msgs []Message
+
+		if err := json.NewDecoder(http.MaxBytesReader(w, req.Body, maxBatchBytes)).Decode(&msgs); err != nil {
+			r.reject(from, fmt.Errorf("%w: %v", ErrMalformed, err))
+			http.Error(w, "invalid batch: "+err.Error(), 400)
+			return
+		}
+
+		if err := r.Receive(from, msgs); err != nil {
+			http.Error(w, err.Error(), 503)
+			return
+		}
+
+		w.WriteHeader(202)
+	})
+
+	return mux
+}
+
+// httpPeer reaches a node serving Handler.
+type httpPeer struct {
+	id   string
+	base string
+	http *http.Client
+}
+
+// NewHTTPPeer returns the peer at addr, a host:port or URL serving
+// Handler. The peer's ID is addr.
+func NewHTTPPeer(addr string) Peer {
+
+	base := addr
+	if !strings.Contains(base, "://") {
+		base = "http://" + base
+	}
+
+	return &httpPeer{
+		id:   addr,
+		base: strings.TrimRight(base, "/"),
+		http: &http.Client{Timeout: time.Minute},
+	}
+}
+
+func (p *httpPeer) ID() string {
+	return p.id
+}
+
+func (p *httpPeer) Send(ctx context.Context, msgs []Message) error {
+
+	data, err := json.Marshal(msgs)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.base+"/gossip", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	switch resp.StatusCode {
+	case http.StatusAccepted, http.StatusOK:
+		return nil
+	case http.StatusServiceUnavailable:
+		return ErrBusy
+	}
+
+	return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+}
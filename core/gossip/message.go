@@ -0,0 +1,136 @@
+// Package gossip floods new transactions and block proposals through
+// the network: every node relays a message it has not seen before to
+// all its peers, so a transaction submitted to any node reaches the
+// leader, and a committed block reaches every node without waiting
+// for block sync to poll.
+//
+// Messages are deduplicated by a bounded set of the IDs seen, the
+// transaction hash or the block hash. Each peer has a bounded queue
+// drained in batches by its own sender, and inbound messages wait in a
+// bounded inbox: when it is full the sender is told to back off and
+// retries, so a slow node slows the nodes that feed it instead of
+// growing their memory. Peers are reached over HTTP (Handler,
+// NewHTTPPeer) or, for tests and simulations, in process
+// (NewLocalPeer).
+package gossip
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/block"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/blocksync"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/consensus"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/transaction"
+)
+
+// Kind tells the messages apart.
+type Kind string
+
+const (
+	KindTx       Kind = "tx"
+	KindProposal Kind = "proposal"
+)
+
+// Message is one gossiped item: a signed transaction, or a proposal,
+// the block a leader committed with its certificate.
+type Message struct {
+	Kind     Kind                     `json:"kind"`
+	Tx       *transaction.Transaction `json:"tx,omitempty"`
+	Proposal *blocksync.Committed     `json:"proposal,omitempty"`
+
+	// Hops counts the nodes the message passed to get here
+	Hops int `json:"hops"`
+}
+
+// NewTx returns the message announcing tx.
+func NewTx(tx *transaction.Transaction) Message {
+	return Message{Kind: KindTx, Tx: tx}
+}
+
+// NewProposal returns the message announcing b, committed by commit.
+func NewProposal(b *block.Block, commit *consensus.Certificate) Message {
+	return Message{Kind: KindProposal, Proposal: &blocksync.Committed{Block: b, Commit: commit}}
+}
+
+// ID identifies m for deduplication: the transaction's payload hash
+// under the chain hash h, or the proposed block's hash.
+func (m *Message) ID(h crypto.Hasher) (string, error) {
+
+	switch m.Kind {
+
+	case KindTx:
+		if m.Tx == nil {
+			return "", errors.New("tx message without a transaction")
+		}
+
+		hash, err := m.Tx.HashWith(h)
+		if err != nil {
+			return "", err
+		}
+
+		return "tx:" + string(hash), nil
+
+	case KindProposal:
+		if m.Proposal == nil || m.Proposal.Block == nil {
+			return "", errors.New("proposal message without a block")
+		}
+
+		return "proposal:" + string(m.Proposal.Block.Hash), nil
+	}
+
+	return "", fmt.Errorf("unknown message kind %q", m.Kind)
+}
+
+// seenSet holds the most recent IDs, evicting the oldest beyond its
+// size. ids maps each ID to its slot in order. It is not safe for
+// concurrent use.
+type seenSet struct {
+	ids   map[string]int
+	order []string
+	next  int
+	size  int
+}
+
+func newSeenSet(size int) *seenSet {
+	return &seenSet{ids: make(map[string]int), size: size}
+}
+
+// add records id and reports whether it was new.
+func (s *seenSet) add(id string) bool {
+
+	if _, ok := s.ids[id]; ok {
+		return false
+	}
+
+	slot := len(s.order)
+
+	if slot < s.size {
+		s.order = append(s.order, id)
+	} else {
+		slot = s.next
+		if old := s.order[slot]; old != "" {
+			delete(s.ids, old)
+		}
+		s.order[slot] = id
+		s.next = (s.next + 1) % s.size
+	}
+
+	s.ids[id] = slot
+	return true
+}
+
+// remove forgets id, so a later copy is processed again. Its slot is
+// emptied, so evicting it later leaves a new copy of id alone; the slot
+// itself is only reclaimed by eviction.
+func (s *seenSet) remove(id string) {
+
+	slot, ok := s.ids[id]
+	if !ok {
+		return
+	}
+
+	s.order[slot] = ""
+	delete(s.ids, id)
+}
@@ -0,0 +1,51 @@
+package gossip
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Peer is a node messages are relayed to. Send returns ErrBusy when
+// the peer cannot take the messages yet.
+type Peer interface {
+	ID() string
+	Send(ctx context.Context, msgs []Message) error
+}
+
+// localPeer delivers to a Router in the same process.
+type localPeer struct {
+	id   string
+	from string
+	to   *Router
+}
+
+// NewLocalPeer returns the peer id served by the router to in the
+// same process, for tests and simulations. Messages arrive from the
+// peer named from, and are copied through their JSON encoding, as if
+// they had crossed the network.
+func NewLocalPeer(id, from string, to *Router) Peer {
+	return &localPeer{id: id, from: from, to: to}
+}
+
+func (p *localPeer) ID() string {
+	return p.id
+}
+
+func (p *localPeer) Send(ctx context.Context, msgs []Message) error {
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(msgs)
+	if err != nil {
+		return err
+	}
+
+	var copied []Message
+	if err := json.Unmarshal(data, &copied); err != nil {
+		return err
+	}
+
+	return p.to.Receive(p.from, copied)
+}
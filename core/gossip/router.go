@@ -0,0 +1,495 @@
+package gossip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
+)
+
+var (
+	// ErrBusy is returned when messages cannot be queued: the inbox of
+	// a receiving node is full, or a local message found no room with
+	// a peer in time. The sender should retry later.
+	ErrBusy = errors.New("gossip queue full")
+
+	ErrClosed = errors.New("gossip router closed")
+
+	// ErrMalformed marks a message or batch that could not be decoded
+	// or identified.
+	ErrMalformed = errors.New("malformed gossip message")
+)
+
+// Config tunes a Router.
+type Config struct {
+	// QueueSize bounds the messages waiting for each peer, and the
+	// inbound messages waiting to be handled.
+	QueueSize int
+
+	// BatchSize bounds the messages sent to a peer at once.
+	BatchSize int
+
+	// SeenSize is the number of message IDs remembered.
+	SeenSize int
+
+	// Retry is the pause before sending to a peer again after it was
+	// busy or failed.
+	Retry time.Duration
+
+	// Timeout bounds one send to a peer.
+	Timeout time.Duration
+}
+
+// DefaultConfig returns the settings a node gossips with.
+func DefaultConfig() Config {
+	return Config{
+		QueueSize: 1000,
+		BatchSize: 100,
+		SeenSize:  100000,
+		Retry:     100 * time.Millisecond,
+		Timeout:   5 * time.Second,
+	}
+}
+
+// Apply checks and applies a new message from a peer. A message it
+// rejects is not relayed, and is forgotten so that a valid copy with
+// the same ID is still accepted.
+type Apply func(m *Message) error
+
+// Stats counts a router's traffic.
+type Stats struct {
+	Received   uint64 `json:"received"`   // new messages accepted from peers
+	Duplicates uint64 `json:"duplicates"` // messages already seen
+	Rejected   uint64 `json:"rejected"`   // messages the handler refused
+	Sent       uint64 `json:"sent"`       // messages delivered to peers
+	Dropped    uint64 `json:"dropped"`    // messages lost to a full queue or a failing peer
+	MaxHops    int    `json:"max_hops"`   // longest path of a received message
+}
+
+// Router relays messages between this node and its peers.
+type Router struct {
+	hasher crypto.Hasher
+	handle Apply
+	cfg    Config
+
+	inbox chan inbound
+	stop  chan struct{}
+	wg    sync.WaitGroup
+
+	mu     sync.Mutex
+	seen   *seenSet
+	peers  map[string]*outbox
+	stats  Stats
+	closed bool
+
+	// Logf reports failing peers; log.Printf by default.
+	Logf func(format string, args ...interface{})
+
+	// OnReject, if set, is told of every message from a peer that was
+	// malformed, wrapping ErrMalformed, or refused by the handler. It
+	// may be called concurrently.
+	OnReject func(from string, err error)
+
+	// OnSendError, if set, is told of every failed send to a peer but
+	// for ErrBusy. It may be called concurrently.
+	OnSendError func(peer string, err error)
+}
+
+type inbound struct {
+	from string
+	msg  Message
+}
+
+// outbox is the queue of messages for one peer.
+type outbox struct {
+	peer  Peer
+	queue chan Message
+	stop  chan struct{}
+
+	// failing is set while sends to the peer fail; local messages then
+	// do not wait for it
+	failing atomic.Bool
+}
+
+// NewRouter starts a router that hands new messages from peers to
+// handle. Message IDs hash transactions with the chain hash h. Zero
+// settings in cfg take their default. Close stops it.
+func NewRouter(h crypto.Hasher, handle Apply, cfg Config) *Router {
+
+	def := DefaultConfig()
+
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = def.QueueSize
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = def.BatchSize
+	}
+	if cfg.SeenSize <= 0 {
+		cfg.SeenSize = def.SeenSize
+	}
+	if cfg.Retry <= 0 {
+		cfg.Retry = def.Retry
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = def.Timeout
+	}
+
+	r := &Router{
+		hasher: h,
+		handle: handle,
+		cfg:    cfg,
+		inbox:  make(chan inbound, cfg.QueueSize),
+		stop:   make(chan struct{}),
+		seen:   newSeenSet(cfg.SeenSize),
+		peers:  make(map[string]*outbox),
+		Logf:   log.Printf,
+	}
+
+	r.wg.Add(1)
+	go r.process()
+
+	return r
+}
+
+// AddPeer starts relaying to p. A peer already added is kept.
+func (r *Router) AddPeer(p Peer) {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed || r.peers[p.ID()] != nil {
+		return
+	}
+
+	o := &outbox{peer: p, queue: make(chan Message, r.cfg.QueueSize), stop: make(chan struct{})}
+	r.peers[p.ID()] = o
+
+	r.wg.Add(1)
+	go r.send(o)
+}
+
+// RemovePeer stops relaying to the peer id and drops its queue.
+func (r *Router) RemovePeer(id string) {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if o := r.peers[id]; o != nil {
+		delete(r.peers, id)
+		close(o.stop)
+	}
+}
+
+// Peers returns the IDs of the peers relayed to, sorted.
+func (r *Router) Peers() []string {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := make([]string, 0, len(r.peers))
+	for id := range r.peers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	return ids
+}
+
+// Stats returns the traffic so far.
+func (r *Router) Stats() Stats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.stats
+}
+
+// Close stops the router and its senders; queued messages are lost.
+func (r *Router) Close() {
+
+	r.mu.Lock()
+
+	if !r.closed {
+		r.closed = true
+		close(r.stop)
+
+		for id, o := range r.peers {
+			delete(r.peers, id)
+			close(o.stop)
+		}
+	}
+
+	r.mu.Unlock()
+
+	r.wg.Wait()
+}
+
+// Publish floods a message that originated at this node, which the
+// caller has already applied. It waits for room in the queue of every
+// peer that is not failing, so local submissions go no faster than
+// the peers take them; if ctx ends first it returns ErrBusy and
+// forgets the message, so it can be published again. A message
+// already seen is not sent twice.
+func (r *Router) Publish(ctx context.Context, m Message) error {
+
+	id, err := m.ID(r.hasher)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+
+	if r.closed {
+		r.mu.Unlock()
+		return ErrClosed
+	}
+
+	if !r.seen.add(id) {
+		r.mu.Unlock()
+		return nil
+	}
+
+	peers := r.outboxes("")
+	r.mu.Unlock()
+
+	for _, o := range peers {
+
+		if o.failing.Load() {
+			r.push(o, m)
+			continue
+		}
+
+		select {
+		case o.queue <- m:
+		case <-o.stop:
+		case <-ctx.Done():
+			r.forget(id)
+			return ErrBusy
+		}
+	}
+
+	return nil
+}
+
+// Broadcast floods a message that originated at this node without
+// waiting: peers whose queue is full miss it.
+func (r *Router) Broadcast(m Message) error {
+
+	id, err := m.ID(r.hasher)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	fresh := !r.closed && r.seen.add(id)
+	r.mu.Unlock()
+
+	if fresh {
+		r.relay(m, "")
+	}
+
+	return nil
+}
+
+// Receive queues messages sent by the peer from for handling. It
+// returns ErrBusy when the inbox is full; the messages queued before
+// it are kept, and those resent are deduplicated.
+func (r *Router) Receive(from string, msgs []Message) error {
+
+	for _, m := range msgs {
+
+		m.Hops++
+
+		select {
+		case r.inbox <- inbound{from, m}:
+		case <-r.stop:
+			return ErrClosed
+		default:
+			return ErrBusy
+		}
+	}
+
+	return nil
+}
+
+// process handles inbound messages one at a time.
+func (r *Router) process() {
+	defer r.wg.Done()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case in := <-r.inbox:
+			r.accept(in.from, in.msg)
+		}
+	}
+}
+
+// accept handles a message from a peer and, if it is new and valid,
+// relays it to the other peers.
+func (r *Router) accept(from string, m Message) {
+
+	id, err := m.ID(r.hasher)
+
+	r.mu.Lock()
+
+	switch {
+	case err != nil:
+		r.stats.Rejected++
+		r.mu.Unlock()
+		r.reject(from, fmt.Errorf("%w: %v", ErrMalformed, err))
+		return
+	case !r.seen.add(id):
+		r.stats.Duplicates++
+		r.mu.Unlock()
+		return
+	}
+
+	r.mu.Unlock()
+
+	if err := r.handle(&m); err != nil {
+
+		r.mu.Lock()
+		r.seen.remove(id)
+		r.stats.Rejected++
+		r.mu.Unlock()
+
+		r.reject(from, err)
+		return
+	}
+
+	r.mu.Lock()
+	r.stats.Received++
+	if m.Hops > r.stats.MaxHops {
+		r.stats.MaxHops = m.Hops
+	}
+	r.mu.Unlock()
+
+	r.relay(m, from)
+}
+
+func (r *Router) reject(from string, err error) {
+	if r.OnReject != nil {
+		r.OnReject(from, err)
+	}
+}
+
+// relay queues m for every peer but except, without waiting.
+func (r *Router) relay(m Message, except string) {
+
+	r.mu.Lock()
+	peers := r.outboxes(except)
+	r.mu.Unlock()
+
+	for _, o := range peers {
+		r.push(o, m)
+	}
+}
+
+// push queues m for o unless its queue is full.
+func (r *Router) push(o *outbox, m Message) {
+
+	select {
+	case o.queue <- m:
+	default:
+		r.count(&r.stats.Dropped, 1)
+	}
+}
+
+// outboxes returns the queues of every peer but except. The caller
+// holds mu.
+func (r *Router) outboxes(except string) []*outbox {
+
+	list := make([]*outbox, 0, len(r.peers))
+	for id, o := range r.peers {
+		if id != except {
+			list = append(list, o)
+		}
+	}
+
+	return list
+}
+
+func (r *Router) forget(id string) {
+	r.mu.Lock()
+	r.seen.remove(id)
+	r.mu.Unlock()
+}
+
+func (r *Router) count(c *uint64, n int) {
+	r.mu.Lock()
+	*c += uint64(n)
+	r.mu.Unlock()
+}
+
+// send drains o to its peer in batches. A busy peer gets the same
+// batch again after a pause; a failing one loses it, so a dead peer
+// never holds more than its queue.
+func (r *Router) send(o *outbox) {
+	defer r.wg.Done()
+
+	var batch []Message
+
+	for {
+		if len(batch) == 0 {
+
+			select {
+			case <-o.stop:
+				return
+			case m := <-o.queue:
+				batch = append(batch, m)
+			}
+
+			// Take what else is waiting, up to a batch
+		fill:
+			for len(batch) < r.cfg.BatchSize {
+				select {
+				case m := <-o.queue:
+					batch = append(batch, m)
+				default:
+					break fill
+				}
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), r.cfg.Timeout)
+		err := o.peer.Send(ctx, batch)
+		cancel()
+
+		switch {
+
+		case err == nil:
+			if o.failing.Swap(false) {
+				r.Logf("Gossip: peer %s is back", o.peer.ID())
+			}
+			r.count(&r.stats.Sent, len(batch))
+			batch = nil
+			continue
+
+		case errors.Is(err, ErrBusy):
+			// Keep the batch for when the peer catches up
+
+		default:
+			if !o.failing.Swap(true) {
+				r.Logf("Gossip: peer %s: %v", o.peer.ID(), err)
+			}
+			if r.OnSendError != nil {
+				r.OnSendError(o.peer.ID(), err)
+			}
+			r.count(&r.stats.Dropped, len(batch))
+			batch = nil
+		}
+
+		select {
+		case <-o.stop:
+			return
+		case <-time.After(r.cfg.Retry):
+		}
+	}
+}
@@ -2,16 +2,29 @@ package identity
 
 import (
 	"encoding/base64"
+	"errors"
 	"fmt"
 
 	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
 )
 
+// KeyBackend produces signatures for an identity whose private key is
+// held elsewhere, e.g. by a remote signer process. Blocks and votes
+// come with the height and view they are for, so the backend can
+// refuse to sign two different messages at one position.
+type KeyBackend interface {
+	Sign(domain crypto.Domain, height int, view int, message []byte) ([]byte, error)
+}
+
 type NodeIdentity struct {
 	NodeID     string
 	PublicKey  []byte
-	PrivateKey []byte
+	PrivateKey *crypto.SecretKey
 	Signer     crypto.Signer
+
+	// Backend, when set, signs instead of Signer and PrivateKey.
+	// Verification always uses Signer with PublicKey.
+	Backend KeyBackend
 }
 
 func NewNodeIdentity(nodeID string, signer crypto.Signer) (*NodeIdentity, error) {
@@ -23,17 +36,66 @@ func NewNodeIdentity(nodeID string, signer crypto.Signer) (*NodeIdentity, error)
 	return &NodeIdentity{
 		NodeID:     nodeID,
 		PublicKey:  pub,
-		PrivateKey: priv,
+		PrivateKey: crypto.NewSecretKey(priv),
 		Signer:     signer,
 	}, nil
 }
 
-func (n *NodeIdentity) Sign(message []byte) ([]byte, error) {
-	return n.Signer.Sign(n.PrivateKey, message)
+// NewRemoteNodeIdentity builds an identity without a local private key;
+// all signing is delegated to backend.
+func NewRemoteNodeIdentity(
+	nodeID string,
+	publicKey []byte,
+	signer crypto.Signer,
+	backend KeyBackend,
+) *NodeIdentity {
+	return &NodeIdentity{
+		NodeID:    nodeID,
+		PublicKey: publicKey,
+		Signer:    signer,
+		Backend:   backend,
+	}
 }
 
-func (n *NodeIdentity) Verify(message []byte, signature []byte) bool {
-	return n.Signer.Verify(n.PublicKey, message, signature)
+// Sign signs message bound to domain, so the signature cannot be
+// replayed as another message type or on another chain.
+func (n *NodeIdentity) Sign(domain crypto.Domain, message []byte) ([]byte, error) {
+	return n.SignAt(domain, 0, 0, message)
+}
+
+// SignAt signs a block or vote for consensus height and view. Local
+// keys sign as Sign does; a backend is told the position.
+func (n *NodeIdentity) SignAt(domain crypto.Domain, height int, view int, message []byte) ([]byte, error) {
+	if n.Backend != nil {
+		return n.Backend.Sign(domain, height, view, message)
+	}
+	if n.PrivateKey == nil {
+		return nil, errors.New("identity has no private key")
+	}
+
+	ctx, err := domain.Context()
+	if err != nil {
+		return nil, err
+	}
+
+	return crypto.SignSecretInContext(n.Signer, n.PrivateKey, message, ctx)
+}
+
+// Destroy wipes the private key. The identity can still verify but
+// no longer sign locally.
+func (n *NodeIdentity) Destroy() {
+	if n.PrivateKey != nil {
+		n.PrivateKey.Destroy()
+	}
+}
+
+// Verify checks a signature made by Sign for the same domain.
+func (n *NodeIdentity) Verify(domain crypto.Domain, message []byte, signature []byte) bool {
+	ctx, err := domain.Context()
+	if err != nil {
+		return false
+	}
+	return crypto.VerifyInContext(n.Signer, n.PublicKey, message, signature, ctx)
 }
 
 func (n *NodeIdentity) Algorithm() string {
@@ -44,6 +106,15 @@ func (n *NodeIdentity) PublicKeyBase64() string {
 	return base64.StdEncoding.EncodeToString(n.PublicKey)
 }
 
+// PublicKeyPEM returns the public key as a PEM-encoded PKIX block.
+func (n *NodeIdentity) PublicKeyPEM() (string, error) {
+	data, err := crypto.MarshalPublicKeyPEM(n.Algorithm(), n.PublicKey)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
 func (n *NodeIdentity) String() string {
 	return fmt.Sprintf(
 		"NodeID: %s\nPublicKey: %s\nAlgorithm: %s\n",
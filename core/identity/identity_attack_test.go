@@ -13,9 +13,9 @@ func TestAttack_WrongPublicKey(t *testing.T) {
 	node2, _ := NewNodeIdentity("node2", signer)
 
 	msg := []byte("secure message")
-	sig, _ := node1.Sign(msg)
+	sig, _ := node1.Sign(testDomain, msg)
 
-	if signer.Verify(node2.PublicKey, msg, sig) {
+	if node2.Verify(testDomain, msg, sig) {
 		t.Fatal("Layer1 failed: signature verified with wrong public key")
 	}
 }
@@ -6,6 +6,8 @@ import (
 	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
 )
 
+var testDomain = crypto.Domain{ChainID: "test-chain", Type: crypto.MessageTx}
+
 func TestIdentitySignVerify(t *testing.T) {
 	signer := &crypto.Ed25519Signer{}
 
@@ -16,12 +18,12 @@ func TestIdentitySignVerify(t *testing.T) {
 
 	message := []byte("Test message")
 
-	signature, err := node.Sign(message)
+	signature, err := node.Sign(testDomain, message)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	if !node.Verify(message, signature) {
+	if !node.Verify(testDomain, message, signature) {
 		t.Fatal("Signature verification failed")
 	}
 }
@@ -32,11 +34,11 @@ func TestSignatureFailsOnModifiedMessage(t *testing.T) {
 	node, _ := NewNodeIdentity("validator-1", signer)
 
 	message := []byte("Original message")
-	signature, _ := node.Sign(message)
+	signature, _ := node.Sign(testDomain, message)
 
 	modified := []byte("Tampered message")
 
-	if node.Verify(modified, signature) {
+	if node.Verify(testDomain, modified, signature) {
 		t.Fatal("Signature should fail for modified message")
 	}
 }
\ No newline at end of file
@@ -0,0 +1,273 @@
+package identity
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+/*
+Keystore persists a NodeIdentity to disk so validator keys survive
+restarts.
+
+The private key is encrypted with XChaCha20-Poly1305 under a key
+derived from a passphrase with scrypt. Node ID, algorithm and public
+key are stored in clear but bound to the ciphertext as associated
+data, so they cannot be swapped without failing decryption.
+*/
+
+const (
+	keystoreVersion = 1
+
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = chacha20poly1305.KeySize
+	saltLen      = 32
+
+	// Limits on the scrypt parameters a keystore may ask for, so a
+	// crafted file can neither weaken the key derivation nor make it
+	// take gigabytes of memory: 128·N·r bytes is at most 256 MiB.
+	scryptMinN = 1 << 14
+	scryptMaxN = 1 << 18
+	scryptMaxR = 8
+	scryptMaxP = 4
+	minSaltLen = 16
+)
+
+var (
+	ErrInvalidPassphrase = errors.New("invalid passphrase or corrupted keystore")
+	ErrKeyMismatch       = errors.New("keystore private key does not match its public key")
+)
+
+// keyProbe is signed with a decrypted key to check it against the
+// stored public key.
+var keyProbe = []byte("aegisq keystore key check")
+
+type keystoreKDF struct {
+	Name string `json:"name"`
+	N    int    `json:"n"`
+	R    int    `json:"r"`
+	P    int    `json:"p"`
+	Salt []byte `json:"salt"`
+}
+
+type keystoreCipher struct {
+	Name       string `json:"name"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+type keystoreFile struct {
+	Version   int            `json:"version"`
+	NodeID    string         `json:"node_id"`
+	Algorithm string         `json:"algorithm"`
+	PublicKey []byte         `json:"public_key"`
+	KDF       keystoreKDF    `json:"kdf"`
+	Cipher    keystoreCipher `json:"cipher"`
+}
+
+// check rejects KDF parameters outside the fixed limits.
+func (k *keystoreKDF) check() error {
+
+	if k.N < scryptMinN || k.N > scryptMaxN || k.N&(k.N-1) != 0 {
+		return fmt.Errorf("unsupported scrypt N: %d", k.N)
+	}
+
+	if k.R < 1 || k.R > scryptMaxR {
+		return fmt.Errorf("unsupported scrypt r: %d", k.R)
+	}
+
+	if k.P < 1 || k.P > scryptMaxP {
+		return fmt.Errorf("unsupported scrypt p: %d", k.P)
+	}
+
+	if len(k.Salt) < minSaltLen {
+		return errors.New("keystore salt too short")
+	}
+
+	return nil
+}
+
+// associatedData binds the clear-text fields to the encrypted key.
+func (k *keystoreFile) associatedData() ([]byte, error) {
+	return json.Marshal(struct {
+		Version   int    `json:"version"`
+		NodeID    string `json:"node_id"`
+		Algorithm string `json:"algorithm"`
+		PublicKey []byte `json:"public_key"`
+	}{
+		Version:   k.Version,
+		NodeID:    k.NodeID,
+		Algorithm: k.Algorithm,
+		PublicKey: k.PublicKey,
+	})
+}
+
+// SaveKeystore encrypts the identity with passphrase and writes it to path.
+// An existing file is never overwritten.
+func SaveKeystore(path string, node *NodeIdentity, passphrase []byte) error {
+
+	if len(passphrase) == 0 {
+		return errors.New("empty keystore passphrase")
+	}
+
+	if node.NodeID == "" || node.PrivateKey.Len() == 0 {
+		return errors.New("incomplete node identity")
+	}
+
+	salt := make([]byte, saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+
+	key, err := scrypt.Key(passphrase, salt, scryptN, scryptR, scryptP, scryptKeyLen)
+	if err != nil {
+		return err
+	}
+	defer crypto.Wipe(key)
+
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	ks := &keystoreFile{
+		Version:   keystoreVersion,
+		NodeID:    node.NodeID,
+		Algorithm: node.Algorithm(),
+		PublicKey: node.PublicKey,
+		KDF: keystoreKDF{
+			Name: "scrypt",
+			N:    scryptN,
+			R:    scryptR,
+			P:    scryptP,
+			Salt: salt,
+		},
+	}
+
+	ad, err := ks.associatedData()
+	if err != nil {
+		return err
+	}
+
+	var ciphertext []byte
+
+	err = node.PrivateKey.Use(func(priv []byte) error {
+		ciphertext = aead.Seal(nil, nonce, priv, ad)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	ks.Cipher = keystoreCipher{
+		Name:       "xchacha20-poly1305",
+		Nonce:      nonce,
+		Ciphertext: ciphertext,
+	}
+
+	data, err := json.MarshalIndent(ks, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// LoadKeystore decrypts the keystore at path and rebuilds the identity
+// with a signer matching the stored algorithm.
+func LoadKeystore(path string, passphrase []byte) (*NodeIdentity, error) {
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var ks keystoreFile
+	if err := json.Unmarshal(data, &ks); err != nil {
+		return nil, fmt.Errorf("malformed keystore: %w", err)
+	}
+
+	if ks.Version != keystoreVersion {
+		return nil, fmt.Errorf("unsupported keystore version: %d", ks.Version)
+	}
+
+	if ks.KDF.Name != "scrypt" || ks.Cipher.Name != "xchacha20-poly1305" {
+		return nil, errors.New("unsupported keystore encryption")
+	}
+
+	if err := ks.KDF.check(); err != nil {
+		return nil, err
+	}
+
+	key, err := scrypt.Key(passphrase, ks.KDF.Salt, ks.KDF.N, ks.KDF.R, ks.KDF.P, scryptKeyLen)
+	if err != nil {
+		return nil, err
+	}
+	defer crypto.Wipe(key)
+
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ks.Cipher.Nonce) != aead.NonceSize() {
+		return nil, ErrInvalidPassphrase
+	}
+
+	ad, err := ks.associatedData()
+	if err != nil {
+		return nil, err
+	}
+
+	priv, err := aead.Open(nil, ks.Cipher.Nonce, ks.Cipher.Ciphertext, ad)
+	if err != nil {
+		return nil, ErrInvalidPassphrase
+	}
+
+	// NewSecretKey takes ownership and wipes the decrypted copy.
+	secret := crypto.NewSecretKey(priv)
+
+	signer, err := crypto.NewSignerForAlgorithm(ks.Algorithm)
+	if err != nil {
+		secret.Destroy()
+		return nil, err
+	}
+
+	// The public key is authenticated, but it must also belong to the
+	// private key it was stored with
+	sig, err := signer.SignSecret(secret, keyProbe)
+	if err != nil || !signer.Verify(ks.PublicKey, keyProbe, sig) {
+		secret.Destroy()
+		return nil, ErrKeyMismatch
+	}
+
+	return &NodeIdentity{
+		NodeID:     ks.NodeID,
+		PublicKey:  ks.PublicKey,
+		PrivateKey: secret,
+		Signer:     signer,
+	}, nil
+}
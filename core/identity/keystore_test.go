@@ -0,0 +1,166 @@
+package identity
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
+)
+
+func TestKeystoreRoundTrip(t *testing.T) {
+
+	signer, err := crypto.NewDilithiumSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer signer.Close()
+
+	node, err := NewNodeIdentity("validator-1", signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "validator-1.json")
+	pass := []byte("correct horse battery staple")
+
+	if err := SaveKeystore(path, node, pass); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadKeystore(path, pass)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded.NodeID != node.NodeID || loaded.Algorithm() != node.Algorithm() {
+		t.Fatal("loaded identity metadata differs")
+	}
+
+	msg := []byte("restart-stable identity")
+
+	sig, err := loaded.Sign(testDomain, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !node.Verify(testDomain, msg, sig) {
+		t.Fatal("loaded key does not match original public key")
+	}
+}
+
+func TestKeystoreWrongPassphrase(t *testing.T) {
+
+	node, _ := NewNodeIdentity("validator-1", &crypto.Ed25519Signer{})
+
+	path := filepath.Join(t.TempDir(), "key.json")
+
+	if err := SaveKeystore(path, node, []byte("right")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadKeystore(path, []byte("wrong")); err != ErrInvalidPassphrase {
+		t.Fatal("expected ErrInvalidPassphrase, got", err)
+	}
+}
+
+func TestKeystoreRejectsSwappedPublicKey(t *testing.T) {
+
+	node, _ := NewNodeIdentity("validator-1", &crypto.Ed25519Signer{})
+	other, _ := NewNodeIdentity("validator-2", &crypto.Ed25519Signer{})
+
+	path := filepath.Join(t.TempDir(), "key.json")
+	pass := []byte("pass")
+
+	if err := SaveKeystore(path, node, pass); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(path)
+
+	var raw map[string]interface{}
+	json.Unmarshal(data, &raw)
+	raw["public_key"] = other.PublicKey
+	data, _ = json.Marshal(raw)
+	os.WriteFile(path, data, 0600)
+
+	if _, err := LoadKeystore(path, pass); err == nil {
+		t.Fatal("tampered public key should fail to decrypt")
+	}
+}
+
+func TestKeystoreDoesNotOverwrite(t *testing.T) {
+
+	node, _ := NewNodeIdentity("validator-1", &crypto.Ed25519Signer{})
+
+	path := filepath.Join(t.TempDir(), "key.json")
+
+	if err := SaveKeystore(path, node, []byte("pass")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SaveKeystore(path, node, []byte("pass")); err == nil {
+		t.Fatal("existing keystore should not be overwritten")
+	}
+}
+
+func TestKeystoreRejectsKDFOutsideLimits(t *testing.T) {
+
+	node, _ := NewNodeIdentity("validator-1", &crypto.Ed25519Signer{})
+
+	path := filepath.Join(t.TempDir(), "key.json")
+	pass := []byte("pass")
+
+	if err := SaveKeystore(path, node, pass); err != nil {
+		t.Fatal(err)
+	}
+
+	original, _ := os.ReadFile(path)
+
+	for _, tc := range []struct {
+		name  string
+		key   string
+		value interface{}
+	}{
+		{"huge N", "n", 1 << 30},
+		{"weak N", "n", 2},
+		{"N not a power of two", "n", 3 << 14},
+		{"huge r", "r", 1 << 20},
+		{"huge p", "p", 1 << 20},
+		{"no r", "r", 0},
+		{"short salt", "salt", []byte{1}},
+	} {
+
+		var raw map[string]interface{}
+		json.Unmarshal(original, &raw)
+		raw["kdf"].(map[string]interface{})[tc.key] = tc.value
+		data, _ := json.Marshal(raw)
+		os.WriteFile(path, data, 0600)
+
+		if _, err := LoadKeystore(path, pass); err == nil || err == ErrInvalidPassphrase {
+			t.Fatalf("%s: got %v, want a KDF error", tc.name, err)
+		}
+	}
+}
+
+func TestKeystoreRejectsMismatchedKeyPair(t *testing.T) {
+
+	node, _ := NewNodeIdentity("validator-1", &crypto.Ed25519Signer{})
+	other, _ := NewNodeIdentity("validator-2", &crypto.Ed25519Signer{})
+
+	// Saved with another public key, the file authenticates but the
+	// private key signs for a different identity
+	node.PublicKey = other.PublicKey
+
+	path := filepath.Join(t.TempDir(), "key.json")
+	pass := []byte("pass")
+
+	if err := SaveKeystore(path, node, pass); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadKeystore(path, pass); err != ErrKeyMismatch {
+		t.Fatal("expected ErrKeyMismatch, got", err)
+	}
+}
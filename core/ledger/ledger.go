@@ -1,18 +1,25 @@
 package ledger
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 
 	"github.com/Sai-shashank-2005/aegisq-protocol/core/block"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/chain"
 	"github.com/Sai-shashank-2005/aegisq-protocol/core/consensus"
 	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
 	"github.com/Sai-shashank-2005/aegisq-protocol/core/scheduler"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/transaction"
 )
 
 type Ledger struct {
 	Blocks       []*block.Block
 	ValidatorSet *consensus.ValidatorSet
 	Scheduler    *scheduler.RoundRobinScheduler
+
+	// Params are the chain ID and hash declared in genesis.
+	Params chain.Params
 }
 
 func NewLedger(genesis *block.Block, vs *consensus.ValidatorSet) *Ledger {
@@ -23,6 +30,7 @@ func NewLedger(genesis *block.Block, vs *consensus.ValidatorSet) *Ledger {
 		Blocks:       []*block.Block{genesis},
 		ValidatorSet: vs,
 		Scheduler:    s,
+		Params:       chain.Default,
 	}
 }
 
@@ -36,7 +44,38 @@ func (l *Ledger) AddBlock(
 	validatorPubKey []byte,
 ) error {
 
-	last := l.GetLastBlock()
+	if err := l.checkBlock(b, l.GetLastBlock(), signer, validatorPubKey); err != nil {
+		return err
+	}
+
+	for _, existing := range l.Blocks {
+		if string(existing.Hash) == string(b.Hash) {
+			return errors.New("duplicate block detected")
+		}
+	}
+
+	rotations, err := l.checkRotations(b)
+	if err != nil {
+		return err
+	}
+
+	l.Blocks = append(l.Blocks, b)
+
+	// Rotations were fully validated above, so applying them cannot fail
+	// halfway and leave the block half-applied.
+	for _, tx := range rotations {
+		if err := l.ValidatorSet.RotateKey(tx.SenderID, tx.Rotation.NewPublicKey, tx.Rotation.ActivationHeight); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkBlock checks b as the block after last: its linkage, its
+// scheduled leader and that leader's key at the height, the algorithm,
+// the limits and the block signature.
+func (l *Ledger) checkBlock(b, last *block.Block, signer crypto.Signer, validatorPubKey []byte) error {
 
 	if b.Index != last.Index+1 {
 		return errors.New("invalid block index")
@@ -56,30 +95,133 @@ func (l *Ledger) AddBlock(
 		return errors.New("block produced by wrong scheduled validator")
 	}
 
-	if !l.ValidatorSet.IsAuthorized(b.Validator, validatorPubKey) {
+	// Layer 6: the key must be the one active at this height, so a
+	// rotated-out key cannot keep producing blocks
+	if !l.ValidatorSet.IsAuthorizedAt(b.Validator, validatorPubKey, b.Index) {
 		return errors.New("validator not authorized")
 	}
 
-	valid, err := b.Verify(signer, validatorPubKey)
+	if alg := l.Params.AlgorithmAt(b.Index); alg != "" && signer.Algorithm() != alg {
+		return fmt.Errorf("chain requires %s signatures at height %d, got %s", alg, b.Index, signer.Algorithm())
+	}
+
+	if !l.Params.Allows(signer.Algorithm()) {
+		return fmt.Errorf("signature algorithm %s not allowed on this chain", signer.Algorithm())
+	}
+
+	if err := l.checkLimits(b); err != nil {
+		return err
+	}
+
+	if err := l.checkStateRoot(b); err != nil {
+		return err
+	}
+
+	valid, err := b.VerifyWith(signer, validatorPubKey, l.Params)
 	if err != nil || !valid {
 		return errors.New("block verification failed")
 	}
 
-	for _, existing := range l.Blocks {
-		if string(existing.Hash) == string(b.Hash) {
-			return errors.New("duplicate block detected")
+	return nil
+}
+
+// checkLimits enforces the consensus block limits from genesis.
+func (l *Ledger) checkLimits(b *block.Block) error {
+
+	if max := l.Params.MaxBlockTxs; max > 0 && len(b.Transactions) > max {
+		return fmt.Errorf("block has %d transactions, limit is %d", len(b.Transactions), max)
+	}
+
+	if max := l.Params.MaxBlockBytes; max > 0 {
+
+		data, err := json.Marshal(b)
+		if err != nil {
+			return err
+		}
+
+		if len(data) > max {
+			return fmt.Errorf("block is %d bytes, limit is %d", len(data), max)
 		}
 	}
 
-	l.Blocks = append(l.Blocks, b)
+	return nil
+}
+
+// checkStateRoot checks that b carries a state root exactly at the
+// heights the chain commits one. The root itself is checked by nodes
+// that track the state (see ChainVerifier).
+func (l *Ledger) checkStateRoot(b *block.Block) error {
+
+	if !l.Params.CommitsState(b.Index) {
+		if len(b.StateRoot) > 0 {
+			return fmt.Errorf("block %d must not carry a state root", b.Index)
+		}
+		return nil
+	}
+
+	if l.Params.Hasher == nil || len(b.StateRoot) != l.Params.Hasher.Size() {
+		return fmt.Errorf("block %d must carry a state root", b.Index)
+	}
 
 	return nil
 }
 
+// checkRotations validates the key rotations carried by b and returns
+// them in block order. Their signatures were already checked with the
+// rest of the block.
+func (l *Ledger) checkRotations(b *block.Block) ([]*transaction.Transaction, error) {
+
+	var rotations []*transaction.Transaction
+	seen := make(map[string]bool)
+
+	for _, tx := range b.Transactions {
+
+		if !tx.IsKeyRotation() {
+			continue
+		}
+
+		if !l.ValidatorSet.IsAuthorizedAt(tx.SenderID, tx.PublicKey, b.Index) {
+			return nil, errors.New("key rotation not signed by the active validator key")
+		}
+
+		if tx.Rotation.ActivationHeight <= b.Index {
+			return nil, errors.New("key rotation must activate above its block")
+		}
+
+		if seen[tx.SenderID] || l.ValidatorSet.PendingRotation(tx.SenderID, b.Index) {
+			return nil, errors.New("validator already has a pending key rotation")
+		}
+
+		if string(tx.Rotation.NewPublicKey) == string(tx.PublicKey) {
+			return nil, errors.New("key rotation must introduce a new key")
+		}
+
+		// The new key must be usable under the algorithm the chain
+		// requires once it activates
+		alg := l.Params.AlgorithmAt(tx.Rotation.ActivationHeight)
+		if alg != "" && tx.NewKeyAlgorithm() != alg {
+			return nil, fmt.Errorf("key rotation to %s activates where %s is required", tx.NewKeyAlgorithm(), alg)
+		}
+
+		if alg == "" && tx.NewKeyAlgorithm() != tx.Algorithm {
+			return nil, errors.New("key rotation to another algorithm needs an algorithm schedule")
+		}
+
+		seen[tx.SenderID] = true
+		rotations = append(rotations, tx)
+	}
+
+	return rotations, nil
+}
+
 func (l *Ledger) ValidateChain(
 	signer crypto.Signer,
 ) error {
 
+	if len(l.Params.GenesisHash) > 0 && string(l.Blocks[0].PreviousHash) != string(l.Params.GenesisHash) {
+		return errors.New("chain not anchored to genesis")
+	}
+
 	for i := 1; i < len(l.Blocks); i++ {
 
 		current := l.Blocks[i]
@@ -102,16 +244,35 @@ func (l *Ledger) ValidateChain(
 			return errors.New("invalid leader at block height")
 		}
 
-		validatorKey, exists := l.ValidatorSet.GetValidator(current.Validator)
+		validatorKey, exists := l.ValidatorSet.KeyAt(current.Validator, current.Index)
 		if !exists {
 			return errors.New("block signed by unknown validator")
 		}
 
-		valid, err := current.Verify(signer, validatorKey)
+		verifier, err := l.verifierAt(current.Index, signer)
+		if err != nil {
+			return err
+		}
+
+		valid, err := current.VerifyWith(verifier, validatorKey, l.Params)
 		if err != nil || !valid {
 			return errors.New("block verification failed")
 		}
 	}
 
 	return nil
+}
+
+// verifierAt returns signer, or a verifier for the algorithm the
+// migration schedule requires at height when that differs, so a chain
+// spanning an algorithm switch validates end to end.
+func (l *Ledger) verifierAt(height int, signer crypto.Signer) (crypto.Signer, error) {
+
+	alg := l.Params.AlgorithmAt(height)
+
+	if alg == "" || alg == signer.Algorithm() {
+		return signer, nil
+	}
+
+	return crypto.Verifier(alg)
 }
\ No newline at end of file
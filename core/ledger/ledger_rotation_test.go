@@ -0,0 +1,137 @@
+package ledger
+
+import (
+	"testing"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/block"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/chain"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/identity"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/transaction"
+)
+
+func rotationTx(t *testing.T, current, next *identity.NodeIdentity, activation int) *transaction.Transaction {
+
+	tx := transaction.NewKeyRotation(current, next.Algorithm(), next.PublicKey, activation)
+
+	if err := tx.SignRotation(current, next, chain.Default); err != nil {
+		t.Fatal(err)
+	}
+
+	return tx
+}
+
+func nextBlock(t *testing.T, l *Ledger, signer *identity.NodeIdentity, txs ...*transaction.Transaction) *block.Block {
+
+	if len(txs) == 0 {
+		txs = []*transaction.Transaction{createDummyTransaction(t, signer)}
+	}
+
+	b := block.NewBlock(l.GetLastBlock().Index+1, 0, l.GetLastBlock().Hash, txs)
+
+	if err := b.Finalize(signer); err != nil {
+		t.Fatal(err)
+	}
+
+	return b
+}
+
+func TestLedgerKeyRotation(t *testing.T) {
+
+	l, node, signer := setupLedger(t)
+
+	next, err := identity.NewNodeIdentity(node.NodeID, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Block 1 schedules the new key for height 3
+	b1 := nextBlock(t, l, node, rotationTx(t, node, next, 3))
+	if err := l.AddBlock(b1, signer, node.PublicKey); err != nil {
+		t.Fatal("rotation block rejected:", err)
+	}
+
+	// Block 2 is still signed with the old key
+	early := nextBlock(t, l, next)
+	if err := l.AddBlock(early, signer, next.PublicKey); err == nil {
+		t.Fatal("new key must not sign before activation")
+	}
+
+	b2 := nextBlock(t, l, node)
+	if err := l.AddBlock(b2, signer, node.PublicKey); err != nil {
+		t.Fatal(err)
+	}
+
+	// From height 3 only the new key is accepted
+	stale := nextBlock(t, l, node)
+	if err := l.AddBlock(stale, signer, node.PublicKey); err == nil {
+		t.Fatal("rotated-out key must not sign after activation")
+	}
+
+	b3 := nextBlock(t, l, next)
+	if err := l.AddBlock(b3, signer, next.PublicKey); err != nil {
+		t.Fatal(err)
+	}
+
+	// Old blocks keep verifying under the key active at their height
+	if err := l.ValidateChain(signer); err != nil {
+		t.Fatal("chain across rotation failed validation:", err)
+	}
+}
+
+func TestLedgerRejectsInvalidRotation(t *testing.T) {
+
+	tests := []struct {
+		name string
+		tx   func(node, next, other *identity.NodeIdentity) *transaction.Transaction
+	}{
+		{"activation not in the future", func(node, next, _ *identity.NodeIdentity) *transaction.Transaction {
+			return rotationTx(t, node, next, 1)
+		}},
+		{"missing new key signature", func(node, next, _ *identity.NodeIdentity) *transaction.Transaction {
+			tx := rotationTx(t, node, next, 5)
+			tx.Rotation.NewKeySignature = nil
+			return tx
+		}},
+		{"new key signature by another key", func(node, next, other *identity.NodeIdentity) *transaction.Transaction {
+			tx := rotationTx(t, node, next, 5)
+			tx.Rotation.NewPublicKey = other.PublicKey
+			tx.SignWithIdentity(node)
+			return tx
+		}},
+		{"signed by a non-validator key", func(_, next, other *identity.NodeIdentity) *transaction.Transaction {
+			return rotationTx(t, other, next, 5)
+		}},
+	}
+
+	for _, test := range tests {
+
+		l, node, signer := setupLedger(t)
+
+		next, _ := identity.NewNodeIdentity(node.NodeID, signer)
+		other, _ := identity.NewNodeIdentity(node.NodeID, signer)
+
+		b := nextBlock(t, l, node, test.tx(node, next, other))
+
+		if err := l.AddBlock(b, signer, node.PublicKey); err == nil {
+			t.Fatalf("%s: block should be rejected", test.name)
+		}
+	}
+}
+
+func TestLedgerRejectsSecondPendingRotation(t *testing.T) {
+
+	l, node, signer := setupLedger(t)
+
+	next, _ := identity.NewNodeIdentity(node.NodeID, signer)
+	other, _ := identity.NewNodeIdentity(node.NodeID, signer)
+
+	b1 := nextBlock(t, l, node, rotationTx(t, node, next, 5))
+	if err := l.AddBlock(b1, signer, node.PublicKey); err != nil {
+		t.Fatal(err)
+	}
+
+	b2 := nextBlock(t, l, node, rotationTx(t, node, other, 6))
+	if err := l.AddBlock(b2, signer, node.PublicKey); err == nil {
+		t.Fatal("second rotation while one is pending should be rejected")
+	}
+}
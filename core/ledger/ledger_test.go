@@ -147,4 +147,59 @@ func TestLedgerValidateChain(t *testing.T) {
 	if err := ledger.ValidateChain(signer); err != nil {
 		t.Fatal("Chain validation failed:", err)
 	}
-}
\ No newline at end of file
+}
+
+func TestLedgerEnforcesBlockLimits(t *testing.T) {
+
+	ledger, node, signer := setupLedger(t)
+	ledger.Params.MaxBlockTxs = 1
+
+	newBlock := block.NewBlock(
+		1,
+		0,
+		ledger.GetLastBlock().Hash,
+		[]*transaction.Transaction{
+			createDummyTransaction(t, node),
+			createDummyTransaction(t, node),
+		},
+	)
+
+	if err := newBlock.Finalize(node); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ledger.AddBlock(newBlock, signer, node.PublicKey); err == nil {
+		t.Fatal("block above the transaction limit should fail")
+	}
+
+	ledger.Params.MaxBlockTxs = 0
+	ledger.Params.MaxBlockBytes = 100
+
+	if err := ledger.AddBlock(newBlock, signer, node.PublicKey); err == nil {
+		t.Fatal("block above the size limit should fail")
+	}
+
+	ledger.Params.MaxBlockBytes = 0
+	ledger.Params.AllowedAlgorithms = []string{"dilithium2"}
+
+	if err := ledger.AddBlock(newBlock, signer, node.PublicKey); err == nil {
+		t.Fatal("block signed with a disallowed algorithm should fail")
+	}
+}
+
+func TestLedgerValidateChainChecksGenesisAnchor(t *testing.T) {
+
+	ledger, _, signer := setupLedger(t)
+
+	ledger.Params.GenesisHash = []byte("genesis")
+
+	if err := ledger.ValidateChain(signer); err != nil {
+		t.Fatal("anchored chain failed validation:", err)
+	}
+
+	ledger.Params.GenesisHash = []byte("other-genesis")
+
+	if err := ledger.ValidateChain(signer); err == nil {
+		t.Fatal("chain anchored to another genesis should fail")
+	}
+}
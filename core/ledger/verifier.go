@@ -0,0 +1,221 @@
+package ledger
+
+import (
+	"fmt"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/block"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/chain"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/consensus"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/state"
+)
+
+// ChainVerifier checks a chain block by block under the same rules as
+// AddBlock, keeping only the tip in memory, so a stored chain of any
+// length can be re-validated from its first block.
+//
+// Each block is checked against the key its producer holds at that
+// height, under the algorithm the chain requires there or, without a
+// migration schedule, the producer's genesis algorithm. Key rotations
+// are applied to the validator set as their blocks are accepted.
+//
+// A verifier tracking the application state also checks every state
+// root the chain commits.
+type ChainVerifier struct {
+	ledger     *Ledger
+	algorithms map[string]string
+	state      *state.State
+}
+
+// NewChainVerifier starts at genesis: the first block must link to
+// p.GenesisHash. vs is the genesis validator set and is updated by the
+// rotations found; algorithms maps each validator to its genesis
+// algorithm.
+func NewChainVerifier(p chain.Params, vs *consensus.ValidatorSet, algorithms map[string]string) *ChainVerifier {
+
+	// The genesis document stands in for block 0
+	anchor := &block.Block{Index: 0, Hash: p.GenesisHash}
+
+	l := NewLedger(anchor, vs)
+	l.Params = p
+
+	return &ChainVerifier{ledger: l, algorithms: algorithms}
+}
+
+// NewChainVerifierAt starts after tip, the block st was taken at, with
+// the validator keys held in st. It tracks st.
+func NewChainVerifierAt(p chain.Params, st *state.State, tip *block.Block) (*ChainVerifier, error) {
+
+	if uint64(tip.Index) != st.Height() {
+		return nil, fmt.Errorf("state at height %d does not belong to block %d", st.Height(), tip.Index)
+	}
+
+	vs, algorithms, err := st.ValidatorSet()
+	if err != nil {
+		return nil, err
+	}
+
+	l := NewLedger(tip, vs)
+	l.Params = p
+
+	return &ChainVerifier{ledger: l, algorithms: algorithms, state: st}, nil
+}
+
+// TrackState makes the verifier apply every verified block to st, the
+// state at its tip, and check the state roots against it.
+func (v *ChainVerifier) TrackState(st *state.State) {
+	v.state = st
+}
+
+// Verify checks b as the next block and advances the tip. A rejected
+// block leaves the verifier unchanged, so the height can be retried
+// with a block from elsewhere.
+func (v *ChainVerifier) Verify(b *block.Block) error {
+
+	key, verifier, err := v.keyAt(b.Validator, b.Index)
+	if err != nil {
+		return fmt.Errorf("block %d: %w", b.Index, err)
+	}
+
+	// The state root is checked before anything is applied
+	p := v.ledger.Params
+
+	if v.state != nil && p.CommitsState(b.Index) {
+
+		root, err := v.state.RootAfter(b, p.Hasher)
+		if err != nil {
+			return err
+		}
+
+		if string(root) != string(b.StateRoot) {
+			return fmt.Errorf("block %d: state root does not match the state", b.Index)
+		}
+	}
+
+	if err := v.ledger.AddBlock(b, verifier, key); err != nil {
+		return fmt.Errorf("block %d: %w", b.Index, err)
+	}
+
+	// Keep only the tip
+	v.ledger.Blocks = v.ledger.Blocks[len(v.ledger.Blocks)-1:]
+
+	if v.state == nil {
+		return nil
+	}
+
+	return v.state.Apply(b)
+}
+
+// VerifyPast checks b, a block for a height the verifier has already
+// passed, on top of parent, the block verified below it, or nil at
+// height 1: the ledger rules with the keys validators held at that
+// height, and the commit certificate c. Nothing is applied, and the
+// state root is only checked for its presence, since the state has
+// moved on.
+func (v *ChainVerifier) VerifyPast(b, parent *block.Block, c *consensus.Certificate) error {
+
+	if b.Index < 1 || b.Index > v.Height() {
+		return fmt.Errorf("block %d: not below the tip %d", b.Index, v.Height())
+	}
+
+	if parent == nil {
+		parent = &block.Block{Index: 0, Hash: v.ledger.Params.GenesisHash}
+	}
+
+	key, verifier, err := v.keyAt(b.Validator, b.Index)
+	if err != nil {
+		return fmt.Errorf("block %d: %w", b.Index, err)
+	}
+
+	if err := v.ledger.checkBlock(b, parent, verifier, key); err != nil {
+		return fmt.Errorf("block %d: %w", b.Index, err)
+	}
+
+	return v.VerifyCommit(b, c)
+}
+
+// VerifyCommit checks that c commits b: it must hold
+// the commit votes of a quorum of validators, each signed with the key
+// the validator holds at that height.
+func (v *ChainVerifier) VerifyCommit(b *block.Block, c *consensus.Certificate) error {
+
+	if c == nil {
+		return fmt.Errorf("block %d: commit certificate missing", b.Index)
+	}
+
+	if c.Height != b.Index || c.View != b.View || string(c.BlockHash) != string(b.Hash) {
+		return fmt.Errorf("block %d: commit certificate is for another block", b.Index)
+	}
+
+	p := v.ledger.Params
+
+	ctx, err := p.Domain(crypto.MessageVote).Context()
+	if err != nil {
+		return err
+	}
+
+	digest := consensus.CommitDigest(p.Hasher, c.Height, c.View, c.BlockHash)
+	voted := make(map[string]bool)
+
+	for _, vote := range c.Votes {
+
+		if voted[vote.ValidatorID] {
+			return fmt.Errorf("block %d: %s voted twice", b.Index, vote.ValidatorID)
+		}
+
+		key, verifier, err := v.keyAt(vote.ValidatorID, b.Index)
+		if err != nil {
+			return fmt.Errorf("block %d: commit vote: %w", b.Index, err)
+		}
+
+		if !crypto.VerifyInContext(verifier, key, digest, vote.Signature, ctx) {
+			return fmt.Errorf("block %d: invalid commit vote by %s", b.Index, vote.ValidatorID)
+		}
+
+		voted[vote.ValidatorID] = true
+	}
+
+	if quorum := consensus.Quorum(v.ledger.ValidatorSet.Count()); len(voted) < quorum {
+		return fmt.Errorf("block %d: %d commit votes, quorum is %d", b.Index, len(voted), quorum)
+	}
+
+	return nil
+}
+
+// keyAt returns the key validator signs with at height and a verifier
+// for the algorithm the chain requires there or, without a migration
+// schedule, the validator's genesis algorithm.
+func (v *ChainVerifier) keyAt(validator string, height int) ([]byte, crypto.Signer, error) {
+
+	key, ok := v.ledger.ValidatorSet.KeyAt(validator, height)
+	if !ok {
+		return nil, nil, fmt.Errorf("signed by unknown validator %q", validator)
+	}
+
+	alg := v.ledger.Params.AlgorithmAt(height)
+	if alg == "" {
+		alg = v.algorithms[validator]
+	}
+
+	verifier, err := crypto.Verifier(alg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return key, verifier, nil
+}
+
+// State returns the tracked state, or nil.
+func (v *ChainVerifier) State() *state.State {
+	return v.state
+}
+
+// Height returns the height of the last verified block.
+func (v *ChainVerifier) Height() int {
+	return v.ledger.GetLastBlock().Index
+}
+
+// Tip returns the last verified block, or the genesis anchor.
+func (v *ChainVerifier) Tip() *block.Block {
+	return v.ledger.GetLastBlock()
+}
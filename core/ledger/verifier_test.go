@@ -0,0 +1,333 @@
+package ledger
+
+import (
+	"testing"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/block"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/chain"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/consensus"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/identity"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/scheduler"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/state"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/transaction"
+)
+
+// storedChain builds count blocks on genesis, each by its scheduled
+// leader, the way a node persists them.
+func storedChain(t *testing.T, count int) (chain.Params, map[string]*identity.NodeIdentity, []*block.Block) {
+	return storedChainWith(t, 0, count)
+}
+
+// storedChainWith builds the chain of storedChain, committing the
+// state root every stateRootInterval blocks.
+func storedChainWith(t *testing.T, stateRootInterval, count int) (chain.Params, map[string]*identity.NodeIdentity, []*block.Block) {
+
+	p := chain.Default
+	p.GenesisHash = p.Hasher.Hash([]byte("genesis"))
+	p.StateRootInterval = stateRootInterval
+
+	signer := &crypto.Ed25519Signer{}
+	nodes := make(map[string]*identity.NodeIdentity)
+	vs := consensus.NewValidatorSet()
+
+	var validators []state.Validator
+
+	for _, id := range []string{"validator-1", "validator-2", "validator-3"} {
+		node, err := identity.NewNodeIdentity(id, signer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nodes[id] = node
+		if err := vs.AddValidator(id, node.PublicKey); err != nil {
+			t.Fatal(err)
+		}
+		validators = append(validators, state.Validator{NodeID: id, Algorithm: node.Algorithm(), PublicKey: node.PublicKey})
+	}
+
+	st, err := state.New(validators)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sched := scheduler.NewRoundRobinScheduler(vs)
+	prev := p.GenesisHash
+
+	var blocks []*block.Block
+
+	for h := 1; h <= count; h++ {
+
+		leaderID, err := sched.GetLeader(h, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		leader := nodes[leaderID]
+
+		// Empty blocks are valid too
+		var txs []*transaction.Transaction
+		if h%2 == 1 {
+			tx := transaction.NewTransaction(leader, "data", "meta")
+			if err := tx.SignWith(leader, p); err != nil {
+				t.Fatal(err)
+			}
+			txs = append(txs, tx)
+		}
+
+		b := block.NewBlock(h, 0, prev, txs)
+
+		if p.CommitsState(h) {
+			if b.StateRoot, err = st.RootAfter(b, p.Hasher); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := b.FinalizeWith(leader, p); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := st.Apply(b); err != nil {
+			t.Fatal(err)
+		}
+
+		blocks = append(blocks, b)
+		prev = b.Hash
+	}
+
+	return p, nodes, blocks
+}
+
+func newTestVerifier(t *testing.T, p chain.Params, nodes map[string]*identity.NodeIdentity) *ChainVerifier {
+
+	vs := consensus.NewValidatorSet()
+	algorithms := make(map[string]string)
+
+	for id, node := range nodes {
+		if err := vs.AddValidator(id, node.PublicKey); err != nil {
+			t.Fatal(err)
+		}
+		algorithms[id] = node.Algorithm()
+	}
+
+	return NewChainVerifier(p, vs, algorithms)
+}
+
+func TestChainVerifierAcceptsStoredChain(t *testing.T) {
+
+	p, nodes, blocks := storedChain(t, 7)
+
+	v := newTestVerifier(t, p, nodes)
+
+	for _, b := range blocks {
+		if err := v.Verify(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if v.Height() != 7 || string(v.Tip().Hash) != string(blocks[6].Hash) {
+		t.Fatalf("verifier stopped at height %d", v.Height())
+	}
+
+	// Only the tip is kept
+	if len(v.ledger.Blocks) != 1 {
+		t.Fatalf("verifier holds %d blocks", len(v.ledger.Blocks))
+	}
+}
+
+func TestChainVerifierRejects(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		mutate func(blocks []*block.Block)
+	}{
+		{"tampered merkle root", func(blocks []*block.Block) { blocks[2].MerkleRoot = []byte("forged") }},
+		{"tampered transaction", func(blocks []*block.Block) { blocks[2].Transactions[0].DataHash = "other" }},
+		{"broken link", func(blocks []*block.Block) { blocks[3].PreviousHash = blocks[1].Hash }},
+		{"wrong leader", func(blocks []*block.Block) { blocks[3].Validator = blocks[4].Validator }},
+		{"missing block", func(blocks []*block.Block) { blocks[3] = blocks[4] }},
+		{"forged signature", func(blocks []*block.Block) { blocks[3].Signature[0] ^= 1 }},
+		{"not anchored to genesis", func(blocks []*block.Block) { blocks[0].PreviousHash = []byte("elsewhere") }},
+	}
+
+	for _, test := range tests {
+
+		p, nodes, blocks := storedChain(t, 5)
+		test.mutate(blocks)
+
+		v := newTestVerifier(t, p, nodes)
+
+		var err error
+		for _, b := range blocks {
+			if err = v.Verify(b); err != nil {
+				break
+			}
+		}
+
+		if err == nil {
+			t.Fatalf("%s: chain should be rejected", test.name)
+		}
+	}
+}
+
+// trackingVerifier returns a verifier of the chain of storedChainWith
+// that tracks its state.
+func trackingVerifier(t *testing.T, p chain.Params, nodes map[string]*identity.NodeIdentity) *ChainVerifier {
+
+	var validators []state.Validator
+	for id, node := range nodes {
+		validators = append(validators, state.Validator{NodeID: id, Algorithm: node.Algorithm(), PublicKey: node.PublicKey})
+	}
+
+	st, err := state.New(validators)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := newTestVerifier(t, p, nodes)
+	v.TrackState(st)
+
+	return v
+}
+
+func TestChainVerifierChecksStateRoots(t *testing.T) {
+
+	p, nodes, blocks := storedChainWith(t, 3, 7)
+
+	v := trackingVerifier(t, p, nodes)
+
+	var resumed *ChainVerifier
+
+	for _, b := range blocks {
+
+		if err := v.Verify(b); err != nil {
+			t.Fatal(err)
+		}
+
+		// A verifier can start from the state at a block
+		if b.Index == 6 {
+			var err error
+			if resumed, err = NewChainVerifierAt(p, v.State().Clone(), b); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if v.State().Height() != 7 {
+		t.Fatalf("state tracked to height %d", v.State().Height())
+	}
+
+	if err := resumed.Verify(blocks[6]); err != nil {
+		t.Fatal("resumed verifier rejected the next block:", err)
+	}
+
+	if _, err := NewChainVerifierAt(p, v.State(), blocks[5]); err == nil {
+		t.Fatal("a state of another height should be rejected")
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(b *block.Block)
+	}{
+		{"missing root", func(b *block.Block) { b.StateRoot = nil }},
+		{"wrong root", func(b *block.Block) { b.StateRoot = p.Hasher.Hash([]byte("other")) }},
+		{"root off the interval", func(b *block.Block) { b.StateRoot = p.Hasher.Hash([]byte("other")) }},
+	}
+
+	for i, test := range tests {
+
+		p, nodes, blocks := storedChainWith(t, 3, 6)
+
+		// The third case alters block 4, which commits no root
+		target := blocks[2]
+		if i == 2 {
+			target = blocks[3]
+		}
+
+		test.mutate(target)
+		if err := target.FinalizeWith(nodes[target.Validator], p); err != nil {
+			t.Fatal(err)
+		}
+
+		// Re-link the blocks after the re-signed one
+		for j := target.Index; j < len(blocks); j++ {
+			blocks[j].PreviousHash = blocks[j-1].Hash
+			if err := blocks[j].FinalizeWith(nodes[blocks[j].Validator], p); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		v := trackingVerifier(t, p, nodes)
+
+		var err error
+		for _, b := range blocks {
+			if err = v.Verify(b); err != nil {
+				break
+			}
+		}
+
+		if err == nil {
+			t.Fatalf("%s: chain should be rejected", test.name)
+		}
+	}
+}
+
+func TestChainVerifierVerifyCommit(t *testing.T) {
+
+	p, nodes, blocks := storedChain(t, 2)
+	b := blocks[1]
+
+	// commit returns a certificate for b signed by the given validators
+	commit := func(ids ...string) *consensus.Certificate {
+		c := consensus.NewCertificate(b.Index, b.View, b.Hash)
+		for _, id := range ids {
+			if err := c.Sign(nodes[id], p); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return c
+	}
+
+	v := newTestVerifier(t, p, nodes)
+
+	if err := v.VerifyCommit(b, commit("validator-1", "validator-2", "validator-3")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		commit func() *consensus.Certificate
+	}{
+		{"missing", func() *consensus.Certificate { return nil }},
+		{"no votes", func() *consensus.Certificate { return commit() }},
+		{"another block", func() *consensus.Certificate {
+			c := commit("validator-1")
+			c.BlockHash = blocks[0].Hash
+			return c
+		}},
+		{"another view", func() *consensus.Certificate {
+			c := commit("validator-1")
+			c.View++
+			return c
+		}},
+		{"forged vote", func() *consensus.Certificate {
+			c := commit("validator-1")
+			c.Votes[0].Signature[0] ^= 1
+			return c
+		}},
+		{"duplicate voter", func() *consensus.Certificate {
+			c := commit("validator-1")
+			c.Votes = append(c.Votes, c.Votes[0])
+			return c
+		}},
+		{"unknown voter", func() *consensus.Certificate {
+			c := commit("validator-1")
+			c.Votes[0].ValidatorID = "validator-9"
+			return c
+		}},
+	}
+
+	for _, test := range tests {
+		if err := v.VerifyCommit(b, test.commit()); err == nil {
+			t.Fatalf("%s: certificate should be rejected", test.name)
+		}
+	}
+}
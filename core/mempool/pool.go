@@ -0,0 +1,133 @@
+package mempool
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/transaction"
+)
+
+var (
+	ErrPoolFull  = errors.New("transaction pool is full")
+	ErrDuplicate = errors.New("transaction already pending")
+)
+
+// Pool holds transactions waiting to be included in a block, in arrival
+// order. Transactions are identified by their DataHash, the key the
+// block store indexes them under.
+//
+// Signature checks belong to the caller; the pool only orders and
+// deduplicates.
+type Pool struct {
+	mu sync.Mutex
+
+	txs     []*transaction.Transaction
+	pending map[string]bool
+	size    int
+
+	// notify is signalled when a transaction arrives
+	notify chan struct{}
+}
+
+// New returns a pool holding at most size transactions; zero means
+// unbounded.
+func New(size int) *Pool {
+	return &Pool{
+		pending: make(map[string]bool),
+		size:    size,
+		notify:  make(chan struct{}, 1),
+	}
+}
+
+// Add queues tx for the next block.
+func (p *Pool) Add(tx *transaction.Transaction) error {
+
+	if tx == nil || tx.DataHash == "" {
+		return errors.New("transaction has no data hash")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.pending[tx.DataHash] {
+		return ErrDuplicate
+	}
+
+	if p.size > 0 && len(p.txs) >= p.size {
+		return ErrPoolFull
+	}
+
+	p.txs = append(p.txs, tx)
+	p.pending[tx.DataHash] = true
+
+	select {
+	case p.notify <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Len returns the number of pending transactions.
+func (p *Pool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.txs)
+}
+
+// Reap returns up to max pending transactions, oldest first, without
+// removing them; zero means all. Call Remove once they are committed.
+func (p *Pool) Reap(max int) []*transaction.Transaction {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	n := len(p.txs)
+	if max > 0 && max < n {
+		n = max
+	}
+
+	out := make([]*transaction.Transaction, n)
+	copy(out, p.txs)
+
+	return out
+}
+
+// Remove drops committed transactions from the pool.
+func (p *Pool) Remove(txs []*transaction.Transaction) {
+
+	if len(txs) == 0 {
+		return
+	}
+
+	committed := make(map[string]bool, len(txs))
+	for _, tx := range txs {
+		committed[tx.DataHash] = true
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	kept := p.txs[:0]
+
+	for _, tx := range p.txs {
+		if committed[tx.DataHash] {
+			delete(p.pending, tx.DataHash)
+			continue
+		}
+		kept = append(kept, tx)
+	}
+
+	// Clear the tail so removed transactions can be collected
+	for i := len(kept); i < len(p.txs); i++ {
+		p.txs[i] = nil
+	}
+
+	p.txs = kept
+}
+
+// Notify returns a channel that receives after a transaction is added.
+// Several additions may be coalesced into one signal.
+func (p *Pool) Notify() <-chan struct{} {
+	return p.notify
+}
@@ -0,0 +1,80 @@
+package mempool
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/transaction"
+)
+
+func testTx(i int) *transaction.Transaction {
+	return &transaction.Transaction{SenderID: "validator-1", DataHash: fmt.Sprintf("data-%d", i)}
+}
+
+func TestPoolReapRemove(t *testing.T) {
+
+	p := New(0)
+
+	for i := 0; i < 5; i++ {
+		if err := p.Add(testTx(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := p.Add(testTx(2)); err != ErrDuplicate {
+		t.Fatalf("duplicate should be rejected, got %v", err)
+	}
+
+	batch := p.Reap(3)
+	if len(batch) != 3 || batch[0].DataHash != "data-0" || batch[2].DataHash != "data-2" {
+		t.Fatalf("reap must return the oldest transactions: %v", batch)
+	}
+
+	// Reaping does not remove
+	if p.Len() != 5 {
+		t.Fatalf("pool has %d transactions, want 5", p.Len())
+	}
+
+	p.Remove(batch)
+
+	rest := p.Reap(0)
+	if len(rest) != 2 || rest[0].DataHash != "data-3" {
+		t.Fatalf("unexpected remainder: %v", rest)
+	}
+
+	// A committed transaction may be submitted again
+	if err := p.Add(testTx(0)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPoolLimitAndNotify(t *testing.T) {
+
+	p := New(2)
+
+	select {
+	case <-p.Notify():
+		t.Fatal("empty pool must not notify")
+	default:
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := p.Add(testTx(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := p.Add(testTx(2)); err != ErrPoolFull {
+		t.Fatalf("full pool should reject, got %v", err)
+	}
+
+	select {
+	case <-p.Notify():
+	default:
+		t.Fatal("adding a transaction must notify")
+	}
+
+	if err := p.Add(&transaction.Transaction{}); err == nil {
+		t.Fatal("transaction without data hash should be rejected")
+	}
+}
@@ -0,0 +1,359 @@
+// Package p2p manages the peers a node gossips with and syncs from. A
+// Manager keeps connections to the configured persistent peers and up
+// to a limit of others drawn from an address book, which it fills from
+// seed nodes and from the peers themselves. Failed peers are retried
+// with a backoff; peers that misbehave lose score and are banned for a
+// while once it runs out.
+//
+// A connection is a peer that passed the handshake: it answered with
+// the same chain and genesis. Peers are reached over HTTP, so inbound
+// peers are known by their host, and bans apply to a host.
+package p2p
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxAddrs bounds the address book.
+const maxAddrs = 1000
+
+// Where an address was learned.
+const (
+	SourceConfig = "config"
+	SourceSeed   = "seed"
+	SourcePeer   = "peer"
+)
+
+// AddrEntry is what the book knows of an address.
+type AddrEntry struct {
+	Addr   string `json:"addr"`
+	Source string `json:"source"`
+
+	// LastSeen is the last successful handshake
+	LastSeen    time.Time `json:"last_seen"`
+	LastAttempt time.Time `json:"last_attempt"`
+
+	// Failures counts the attempts failed since LastSeen
+	Failures int `json:"failures"`
+}
+
+// Ban keeps a host out until Until. Count is the number of times it
+// was banned, which lengthens the next ban.
+type Ban struct {
+	Host  string    `json:"host"`
+	Until time.Time `json:"until"`
+	Count int       `json:"count"`
+}
+
+// AddrBook is the set of known peer addresses and banned hosts,
+// persisted as JSON.
+type AddrBook struct {
+	path string
+
+	mu    sync.Mutex
+	addrs map[string]*AddrEntry
+	bans  map[string]*Ban
+	dirty bool
+}
+
+type addrBookFile struct {
+	Addrs []*AddrEntry `json:"addrs"`
+	Bans  []*Ban       `json:"bans"`
+}
+
+// LoadAddrBook reads the book at path, or starts an empty one if the
+// file does not exist. An empty path keeps the book in memory.
+func LoadAddrBook(path string) (*AddrBook, error) {
+
+	b := &AddrBook{path: path, addrs: make(map[string]*AddrEntry), bans: make(map[string]*Ban)}
+
+	if path == "" {
+		return b, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return b, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var f addrBookFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+
+	for _, e := range f.Addrs {
+		b.addrs[e.Addr] = e
+	}
+
+	for _, ban := range f.Bans {
+		b.bans[ban.Host] = ban
+	}
+
+	return b, nil
+}
+
+// Save writes the book if it changed since the last save, replacing
+// the file atomically.
+func (b *AddrBook) Save() error {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.path == "" || !b.dirty {
+		return nil
+	}
+
+	f := addrBookFile{Addrs: b.sortedEntries(), Bans: b.sortedBans()}
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := b.path + ".tmp"
+
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, b.path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	b.dirty = false
+	return nil
+}
+
+// Add records addr, learned from source, and reports whether it was
+// new. A full book makes room by forgetting its least useful address
+// learned from elsewhere than the configuration.
+func (b *AddrBook) Add(addr, source string) bool {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if e, ok := b.addrs[addr]; ok {
+		// The configuration outranks what peers say
+		if source == SourceConfig && e.Source != SourceConfig {
+			e.Source = SourceConfig
+			b.dirty = true
+		}
+		return false
+	}
+
+	if len(b.addrs) >= maxAddrs && !b.evict() {
+		return false
+	}
+
+	b.addrs[addr] = &AddrEntry{Addr: addr, Source: source}
+	b.dirty = true
+
+	return true
+}
+
+// evict drops the address that failed most, then the one seen least
+// recently. The caller holds mu.
+func (b *AddrBook) evict() bool {
+
+	var worst *AddrEntry
+
+	for _, e := range b.addrs {
+
+		if e.Source == SourceConfig {
+			continue
+		}
+
+		if worst == nil || e.Failures > worst.Failures ||
+			e.Failures == worst.Failures && e.LastSeen.Before(worst.LastSeen) {
+			worst = e
+		}
+	}
+
+	if worst == nil {
+		return false
+	}
+
+	delete(b.addrs, worst.Addr)
+	return true
+}
+
+// Remove forgets addr.
+func (b *AddrBook) Remove(addr string) {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.addrs[addr]; ok {
+		delete(b.addrs, addr)
+		b.dirty = true
+	}
+}
+
+// Entry returns what the book knows of addr.
+func (b *AddrBook) Entry(addr string) (AddrEntry, bool) {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	e, ok := b.addrs[addr]
+	if !ok {
+		return AddrEntry{}, false
+	}
+
+	return *e, true
+}
+
+// Entries returns every address, sorted.
+func (b *AddrBook) Entries() []AddrEntry {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var list []AddrEntry
+	for _, e := range b.sortedEntries() {
+		list = append(list, *e)
+	}
+
+	return list
+}
+
+// Attempt records a connection attempt to addr.
+func (b *AddrBook) Attempt(addr string, now time.Time) {
+	b.update(addr, func(e *AddrEntry) { e.LastAttempt = now })
+}
+
+// Good records a successful handshake with addr.
+func (b *AddrBook) Good(addr string, now time.Time) {
+	b.update(addr, func(e *AddrEntry) {
+		e.LastSeen = now
+		e.Failures = 0
+	})
+}
+
+// Failed records a failed attempt or a lost connection.
+func (b *AddrBook) Failed(addr string) {
+	b.update(addr, func(e *AddrEntry) { e.Failures++ })
+}
+
+func (b *AddrBook) update(addr string, f func(e *AddrEntry)) {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if e, ok := b.addrs[addr]; ok {
+		f(e)
+		b.dirty = true
+	}
+}
+
+// Ban keeps host out until until and returns the number of times it
+// has been banned.
+func (b *AddrBook) Ban(host string, until time.Time) int {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ban := b.bans[host]
+	if ban == nil {
+		ban = &Ban{Host: host}
+		b.bans[host] = ban
+	}
+
+	ban.Until = until
+	ban.Count++
+	b.dirty = true
+
+	return ban.Count
+}
+
+// BanCount returns the number of times host has been banned.
+func (b *AddrBook) BanCount(host string) int {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ban := b.bans[host]; ban != nil {
+		return ban.Count
+	}
+
+	return 0
+}
+
+// Banned reports whether host is banned at now.
+func (b *AddrBook) Banned(host string, now time.Time) bool {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ban := b.bans[host]
+	return ban != nil && now.Before(ban.Until)
+}
+
+// Bans returns the hosts banned at now.
+func (b *AddrBook) Bans(now time.Time) []Ban {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var list []Ban
+	for _, ban := range b.sortedBans() {
+		if now.Before(ban.Until) {
+			list = append(list, *ban)
+		}
+	}
+
+	return list
+}
+
+// sortedEntries returns the entries in address order. The caller
+// holds mu.
+func (b *AddrBook) sortedEntries() []*AddrEntry {
+
+	list := make([]*AddrEntry, 0, len(b.addrs))
+	for _, e := range b.addrs {
+		list = append(list, e)
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Addr < list[j].Addr })
+	return list
+}
+
+// sortedBans returns the bans in host order. The caller holds mu.
+func (b *AddrBook) sortedBans() []*Ban {
+
+	list := make([]*Ban, 0, len(b.bans))
+	for _, ban := range b.bans {
+		list = append(list, ban)
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Host < list[j].Host })
+	return list
+}
+
+// HostOf returns the host of a peer address, a host:port or URL, or
+// the address itself if it has no port.
+func HostOf(addr string) string {
+
+	if i := strings.Index(addr, "://"); i >= 0 {
+		addr = addr[i+3:]
+	}
+	addr = strings.TrimRight(addr, "/")
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+
+	return host
+}
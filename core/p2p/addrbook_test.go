@@ -0,0 +1,104 @@
+package p2p
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAddrBookPersists(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "addrbook.json")
+	now := time.Unix(1000, 0).UTC()
+
+	book, err := LoadAddrBook(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	book.Add("10.0.0.1:9000", SourceConfig)
+	book.Add("10.0.0.2:9000", SourcePeer)
+	book.Good("10.0.0.1:9000", now)
+	book.Failed("10.0.0.2:9000")
+	book.Ban("10.0.0.3", now.Add(time.Hour))
+
+	if err := book.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadAddrBook(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e, ok := loaded.Entry("10.0.0.1:9000"); !ok || e.Source != SourceConfig || !e.LastSeen.Equal(now) {
+		t.Fatalf("reloaded entry %+v", e)
+	}
+
+	if e, _ := loaded.Entry("10.0.0.2:9000"); e.Failures != 1 {
+		t.Fatalf("reloaded %d failures, want 1", e.Failures)
+	}
+
+	if !loaded.Banned("10.0.0.3", now) || loaded.Banned("10.0.0.3", now.Add(time.Hour)) {
+		t.Fatal("ban did not survive the reload")
+	}
+}
+
+func TestAddrBookEvictsWorst(t *testing.T) {
+
+	book, _ := LoadAddrBook("")
+
+	book.Add("10.0.0.1:9000", SourceConfig)
+	for i := 0; i < maxAddrs-1; i++ {
+		book.Add(fmt.Sprintf("10.1.%d.%d:9000", i/256, i%256), SourcePeer)
+	}
+
+	// The configured address is never evicted, and the most failed
+	// one goes first
+	book.Failed("10.1.0.7:9000")
+	book.Failed("10.0.0.1:9000")
+
+	if !book.Add("10.2.0.1:9000", SourceSeed) {
+		t.Fatal("full book refused a new address")
+	}
+
+	if n := len(book.Entries()); n != maxAddrs {
+		t.Fatalf("book holds %d addresses, want %d", n, maxAddrs)
+	}
+
+	if _, ok := book.Entry("10.1.0.7:9000"); ok {
+		t.Fatal("most failed address was kept")
+	}
+
+	if _, ok := book.Entry("10.0.0.1:9000"); !ok {
+		t.Fatal("configured address was evicted")
+	}
+}
+
+func TestBanDurationDoubles(t *testing.T) {
+
+	for count, want := range []time.Duration{10 * time.Minute, 20 * time.Minute, 40 * time.Minute} {
+		if d := banDuration(10*time.Minute, count); d != want {
+			t.Fatalf("ban after %d bans lasts %s, want %s", count, d, want)
+		}
+	}
+
+	if d := banDuration(10*time.Minute, 100); d != maxBan {
+		t.Fatalf("ban lasts %s, want at most %s", d, maxBan)
+	}
+}
+
+func TestHostOf(t *testing.T) {
+
+	for addr, want := range map[string]string{
+		"10.0.0.1:9000":       "10.0.0.1",
+		"http://node-1:9000/": "node-1",
+		"[::1]:9000":          "::1",
+		"10.0.0.1":            "10.0.0.1",
+	} {
+		if host := HostOf(addr); host != want {
+			t.Errorf("HostOf(%q) = %q, want %q", addr, host, want)
+		}
+	}
+}
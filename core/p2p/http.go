@@ -0,0 +1,150 @@
+package p2p
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+const (
+	// maxResponseBytes bounds a response read from a peer
+	maxResponseBytes = 1 << 20
+
+	// maxInfoBytes bounds the Info a dialling node sends
+	maxInfoBytes = 64 << 10
+)
+
+// Handler serves the handshake and address exchange of m:
+//
+//	GET  /p2p/info     this node's Info
+//	POST /p2p/info     the handshake: the caller's Info for this node's
+//	GET  /p2p/addrs    addresses of this node and its peers
+func Handler(m *Manager) http.Handler {
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/p2p/info", func(w http.ResponseWriter, r *http.Request) {
+
+		if r.Method == http.MethodPost {
+
+			var info Info
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxInfoBytes)).Decode(&info); err != nil {
+				http.Error(w, "invalid info: "+err.Error(), 400)
+				return
+			}
+
+			host, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				host = r.RemoteAddr
+			}
+
+			m.Introduce(host, info)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(m.Info())
+	})
+
+	mux.HandleFunc("/p2p/addrs", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(m.Addrs())
+	})
+
+	return mux
+}
+
+// Guard serves next to hosts AllowInbound admits: a banned host is
+// refused with 403, and a new host beyond MaxInbound with 503.
+func (m *Manager) Guard(next http.Handler) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+
+		if err := m.AllowInbound(host); err != nil {
+
+			code := 503
+			if errors.Is(err, ErrBanned) {
+				code = 403
+			}
+
+			http.Error(w, err.Error(), code)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// httpTransport reaches nodes serving Handler.
+type httpTransport struct {
+	http *http.Client
+}
+
+// NewHTTPTransport returns a Transport to nodes serving Handler at
+// addresses that are a host:port or URL. Requests are bounded by the
+// manager's Timeout.
+func NewHTTPTransport() Transport {
+	return &httpTransport{http: &http.Client{}}
+}
+
+func (t *httpTransport) Handshake(ctx context.Context, addr string, self Info) (Info, error) {
+
+	data, err := json.Marshal(self)
+	if err != nil {
+		return Info{}, err
+	}
+
+	var info Info
+	err = t.do(ctx, http.MethodPost, addr, "/p2p/info", data, &info)
+	return info, err
+}
+
+func (t *httpTransport) Addrs(ctx context.Context, addr string) ([]string, error) {
+	var addrs []string
+	err := t.do(ctx, http.MethodGet, addr, "/p2p/addrs", nil, &addrs)
+	return addrs, err
+}
+
+// do sends data, if any, to path at addr and decodes the answer into
+// v.
+func (t *httpTransport) do(ctx context.Context, method, addr, path string, data []byte, v interface{}) error {
+
+	base := addr
+	if !strings.Contains(base, "://") {
+		base = "http://" + base
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(base, "/")+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := t.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body := io.LimitReader(resp.Body, maxResponseBytes)
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(body, 1024))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return json.NewDecoder(body).Decode(v)
+}
@@ -0,0 +1,685 @@
+package p2p
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrBanned       = errors.New("peer is banned")
+	ErrTooManyPeers = errors.New("too many inbound peers")
+
+	errOtherChain = errors.New("peer is on another chain")
+	errSelf       = errors.New("address is this node")
+)
+
+const (
+	// maxShared bounds the addresses given to, and taken from, one
+	// peer
+	maxShared = 100
+
+	// discoveryInterval is the pause between asks for addresses
+	discoveryInterval = 30 * time.Second
+)
+
+// Info identifies a node in the handshake.
+type Info struct {
+	ChainID     string `json:"chain_id"`
+	GenesisHash []byte `json:"genesis_hash"`
+
+	// Addr is the address the node is reached at, if it knows it
+	Addr string `json:"addr,omitempty"`
+
+	// Nonce is drawn at every start, so a node that dials itself
+	// notices
+	Nonce string `json:"nonce"`
+}
+
+// Transport reaches the peer management endpoints of other nodes.
+// Handshake sends self to the node at addr and returns its Info.
+type Transport interface {
+	Handshake(ctx context.Context, addr string, self Info) (Info, error)
+	Addrs(ctx context.Context, addr string) ([]string, error)
+}
+
+// Config tunes a Manager.
+type Config struct {
+	// Persistent peers are always connected, and reconnected after
+	// any failure.
+	Persistent []string
+
+	// Seeds are asked for addresses when the book runs short.
+	Seeds []string
+
+	// MaxInbound bounds the hosts served at once, and MaxOutbound the
+	// peers connected from the book; persistent peers count for
+	// neither.
+	MaxInbound  int
+	MaxOutbound int
+
+	// ExternalAddress is the address this node is reached at, shared
+	// with other nodes.
+	ExternalAddress string
+
+	// BanDuration is the length of a first ban.
+	BanDuration time.Duration
+
+	// DialInterval is the time between rounds of connecting.
+	DialInterval time.Duration
+
+	// A failed address is retried after BackoffBase, doubling with
+	// every failure up to BackoffMax.
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+
+	// InboundIdle is the time after which a silent inbound host no
+	// longer counts against MaxInbound.
+	InboundIdle time.Duration
+
+	// Timeout bounds one request to a peer.
+	Timeout time.Duration
+}
+
+// DefaultConfig returns the settings a node manages its peers with.
+func DefaultConfig() Config {
+	return Config{
+		MaxInbound:   40,
+		MaxOutbound:  10,
+		BanDuration:  10 * time.Minute,
+		DialInterval: time.Second,
+		BackoffBase:  time.Second,
+		BackoffMax:   5 * time.Minute,
+		InboundIdle:  time.Minute,
+		Timeout:      5 * time.Second,
+	}
+}
+
+// Manager keeps the node connected to its peers.
+type Manager struct {
+	cfg       Config
+	self      Info
+	book      *AddrBook
+	transport Transport
+	now       func() time.Time
+
+	persistent map[string]bool
+	ready      chan struct{}
+
+	mu            sync.Mutex
+	connected     map[string]bool
+	dialing       map[string]bool
+	inbound       map[string]time.Time
+	scores        map[string]*score
+	own           map[string]bool
+	lastDiscovery time.Time
+
+	// OnConnect and OnDisconnect, if set, are told of every peer that
+	// passed the handshake and of every peer lost or banned. They are
+	// called with the manager locked, so they must not call it.
+	OnConnect    func(addr string)
+	OnDisconnect func(addr string)
+
+	// Logf reports connections and misbehaving peers; log.Printf by
+	// default.
+	Logf func(format string, args ...interface{})
+}
+
+// NewManager manages the peers of the node self, recording what it
+// learns in book and reaching peers through t. Zero settings in cfg
+// take their default, but for the peer limits. Run starts it.
+func NewManager(cfg Config, self Info, book *AddrBook, t Transport) *Manager {
+
+	def := DefaultConfig()
+
+	if cfg.BanDuration <= 0 {
+		cfg.BanDuration = def.BanDuration
+	}
+	if cfg.DialInterval <= 0 {
+		cfg.DialInterval = def.DialInterval
+	}
+	if cfg.BackoffBase <= 0 {
+		cfg.BackoffBase = def.BackoffBase
+	}
+	if cfg.BackoffMax <= 0 {
+		cfg.BackoffMax = def.BackoffMax
+	}
+	if cfg.InboundIdle <= 0 {
+		cfg.InboundIdle = def.InboundIdle
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = def.Timeout
+	}
+
+	if self.Addr == "" {
+		self.Addr = cfg.ExternalAddress
+	}
+
+	if self.Nonce == "" {
+		nonce := make([]byte, 8)
+		rand.Read(nonce)
+		self.Nonce = hex.EncodeToString(nonce)
+	}
+
+	m := &Manager{
+		cfg:        cfg,
+		self:       self,
+		book:       book,
+		transport:  t,
+		now:        time.Now,
+		persistent: make(map[string]bool),
+		ready:      make(chan struct{}),
+		connected:  make(map[string]bool),
+		dialing:    make(map[string]bool),
+		inbound:    make(map[string]time.Time),
+		scores:     make(map[string]*score),
+		own:        make(map[string]bool),
+		Logf:       log.Printf,
+	}
+
+	for _, addr := range cfg.Persistent {
+		m.persistent[addr] = true
+		book.Add(addr, SourceConfig)
+	}
+
+	if self.Addr != "" {
+		m.own[self.Addr] = true
+	}
+
+	return m
+}
+
+// Info returns this node's handshake.
+func (m *Manager) Info() Info {
+	return m.self
+}
+
+// Ready is closed once the first round of connecting has finished.
+func (m *Manager) Ready() <-chan struct{} {
+	return m.ready
+}
+
+// Run connects to peers until ctx is cancelled, then saves the
+// address book.
+func (m *Manager) Run(ctx context.Context) error {
+
+	m.dial(ctx, true)
+	close(m.ready)
+
+	ticker := time.NewTicker(m.cfg.DialInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+
+		case <-ctx.Done():
+			return m.book.Save()
+
+		case <-ticker.C:
+			m.dial(ctx, false)
+
+			if err := m.book.Save(); err != nil {
+				m.Logf("Address book: %v", err)
+			}
+		}
+	}
+}
+
+// Connected returns the connected peers, sorted.
+func (m *Manager) Connected() []string {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list := make([]string, 0, len(m.connected))
+	for addr := range m.connected {
+		list = append(list, addr)
+	}
+	sort.Strings(list)
+
+	return list
+}
+
+// Score returns the score of host, or of the host of a peer address.
+func (m *Manager) Score(peer string) int {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if s := m.scores[HostOf(peer)]; s != nil {
+		return s.current(m.now())
+	}
+
+	return 0
+}
+
+// dial starts connecting to the persistent peers that are due and to
+// enough others from the book to fill the outbound slots. With wait
+// set it returns once the attempts are over.
+func (m *Manager) dial(ctx context.Context, wait bool) {
+
+	now := m.now()
+
+	var targets []string
+	for _, addr := range m.cfg.Persistent {
+		if m.due(addr, now) {
+			targets = append(targets, addr)
+		}
+	}
+
+	m.mu.Lock()
+	need := m.cfg.MaxOutbound
+	for _, set := range []map[string]bool{m.connected, m.dialing} {
+		for addr := range set {
+			if !m.persistent[addr] {
+				need--
+			}
+		}
+	}
+	m.mu.Unlock()
+
+	if need > 0 {
+
+		candidates := m.candidates(now, need)
+
+		if len(candidates) < need && now.Sub(m.lastDiscovery) >= discoveryInterval {
+			m.discover(ctx)
+			candidates = m.candidates(now, need)
+		}
+
+		targets = append(targets, candidates...)
+	}
+
+	var wg sync.WaitGroup
+
+	for _, addr := range targets {
+
+		m.mu.Lock()
+		m.dialing[addr] = true
+		m.mu.Unlock()
+
+		wg.Add(1)
+		go func(addr string) {
+			defer wg.Done()
+			m.connect(ctx, addr)
+		}(addr)
+	}
+
+	if wait {
+		wg.Wait()
+	}
+}
+
+// due reports whether addr may be dialled at now: it is not connected
+// or being dialled, not banned, not this node, and its backoff is
+// over.
+func (m *Manager) due(addr string, now time.Time) bool {
+
+	if m.book.Banned(HostOf(addr), now) {
+		return false
+	}
+
+	m.mu.Lock()
+	busy := m.connected[addr] || m.dialing[addr] || m.own[addr]
+	m.mu.Unlock()
+
+	if busy {
+		return false
+	}
+
+	e, ok := m.book.Entry(addr)
+	return ok && !now.Before(e.LastAttempt.Add(m.backoff(e.Failures)))
+}
+
+// backoff returns the pause before retrying an address that failed
+// failures times in a row.
+func (m *Manager) backoff(failures int) time.Duration {
+
+	if failures == 0 {
+		return 0
+	}
+
+	d := m.cfg.BackoffBase
+	for i := 1; i < failures && d < m.cfg.BackoffMax; i++ {
+		d *= 2
+	}
+
+	if d > m.cfg.BackoffMax {
+		d = m.cfg.BackoffMax
+	}
+
+	return d
+}
+
+// candidates returns up to n addresses from the book to dial: those
+// seen before first, most recently seen first, then those that failed
+// least.
+func (m *Manager) candidates(now time.Time, n int) []string {
+
+	var list []AddrEntry
+
+	for _, e := range m.book.Entries() {
+		if !m.persistent[e.Addr] && m.due(e.Addr, now) {
+			list = append(list, e)
+		}
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if !a.LastSeen.Equal(b.LastSeen) {
+			return a.LastSeen.After(b.LastSeen)
+		}
+		return a.Failures < b.Failures
+	})
+
+	if len(list) > n {
+		list = list[:n]
+	}
+
+	addrs := make([]string, len(list))
+	for i, e := range list {
+		addrs[i] = e.Addr
+	}
+
+	return addrs
+}
+
+// discover asks the seeds and the connected peers for addresses.
+func (m *Manager) discover(ctx context.Context) {
+
+	m.lastDiscovery = m.now()
+
+	type source struct {
+		addr, kind string
+	}
+
+	var sources []source
+	for _, seed := range m.cfg.Seeds {
+		sources = append(sources, source{seed, SourceSeed})
+	}
+	for _, peer := range m.Connected() {
+		sources = append(sources, source{peer, SourcePeer})
+	}
+
+	for _, src := range sources {
+
+		reqCtx, cancel := context.WithTimeout(ctx, m.cfg.Timeout)
+		addrs, err := m.transport.Addrs(reqCtx, src.addr)
+		cancel()
+
+		if err != nil {
+			if src.kind == SourceSeed {
+				m.Logf("Seed %s: %v", src.addr, err)
+			}
+			continue
+		}
+
+		if len(addrs) > maxShared {
+			addrs = addrs[:maxShared]
+		}
+
+		m.mu.Lock()
+		for _, addr := range addrs {
+			if _, _, err := net.SplitHostPort(addr); err == nil && !m.own[addr] {
+				m.book.Add(addr, src.kind)
+			}
+		}
+		m.mu.Unlock()
+	}
+}
+
+// connect runs the handshake with addr and, if it is a node of the
+// same chain, connects it.
+func (m *Manager) connect(ctx context.Context, addr string) {
+
+	now := m.now()
+	m.book.Attempt(addr, now)
+
+	reqCtx, cancel := context.WithTimeout(ctx, m.cfg.Timeout)
+	info, err := m.transport.Handshake(reqCtx, addr, m.self)
+	cancel()
+
+	if err == nil {
+		err = m.check(info)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.dialing, addr)
+
+	if err != nil {
+
+		m.book.Failed(addr)
+
+		// Addresses of this node are remembered, so they are not
+		// learned again
+		if errors.Is(err, errSelf) {
+			m.own[addr] = true
+		}
+
+		switch {
+		case errors.Is(err, errSelf), errors.Is(err, errOtherChain) && !m.persistent[addr]:
+			m.book.Remove(addr)
+		}
+
+		if e, _ := m.book.Entry(addr); e.Failures == 1 || errors.Is(err, errOtherChain) {
+			m.Logf("Peer %s: %v", addr, err)
+		}
+
+		return
+	}
+
+	if ctx.Err() != nil || m.book.Banned(HostOf(addr), m.now()) {
+		return
+	}
+
+	m.connected[addr] = true
+	m.book.Good(addr, now)
+
+	m.Logf("Connected to peer %s", addr)
+
+	if m.OnConnect != nil {
+		m.OnConnect(addr)
+	}
+}
+
+// check accepts a handshake from a node of this chain.
+func (m *Manager) check(info Info) error {
+
+	if info.Nonce == m.self.Nonce {
+		return errSelf
+	}
+
+	if info.ChainID != m.self.ChainID || !bytes.Equal(info.GenesisHash, m.self.GenesisHash) {
+		return fmt.Errorf("%w: chain %q, genesis %x", errOtherChain, info.ChainID, info.GenesisHash)
+	}
+
+	return nil
+}
+
+// Introduce learns the address a node dialling this one announced in
+// its handshake, if the node is of this chain and the address is on
+// the host the handshake came from, so the node is dialled back.
+func (m *Manager) Introduce(host string, info Info) {
+
+	if m.check(info) != nil || HostOf(info.Addr) != host {
+		return
+	}
+
+	if _, port, err := net.SplitHostPort(info.Addr); err != nil || port == "" {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.own[info.Addr] {
+		m.book.Add(info.Addr, SourcePeer)
+	}
+}
+
+// Failed disconnects a peer that stopped answering, so it is retried
+// after a backoff.
+func (m *Manager) Failed(addr string, err error) {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.connected[addr] {
+		return
+	}
+
+	m.disconnect(addr)
+	m.book.Failed(addr)
+	m.book.Attempt(addr, m.now())
+
+	m.Logf("Disconnected from peer %s: %v", addr, err)
+}
+
+// Report lowers the score of a peer, known by its address or host,
+// for an offense, and bans its host once the score reaches BanScore.
+func (m *Manager) Report(peer string, o Offense, err error) {
+
+	host := HostOf(peer)
+	now := m.now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := m.scores[host]
+	if s == nil {
+		s = &score{at: now}
+		m.scores[host] = s
+	}
+
+	v := s.add(-o.Penalty(), now)
+	m.Logf("Peer %s: %s (score %d): %v", host, o, v, err)
+
+	if v > BanScore {
+		return
+	}
+
+	delete(m.scores, host)
+	delete(m.inbound, host)
+
+	until := now.Add(banDuration(m.cfg.BanDuration, m.book.BanCount(host)))
+	m.book.Ban(host, until)
+
+	for addr := range m.connected {
+		if HostOf(addr) == host {
+			m.disconnect(addr)
+		}
+	}
+
+	m.Logf("Banned %s until %s", host, until.Format(time.RFC3339))
+}
+
+// disconnect drops a connected peer. The caller holds mu.
+func (m *Manager) disconnect(addr string) {
+
+	delete(m.connected, addr)
+
+	if m.OnDisconnect != nil {
+		m.OnDisconnect(addr)
+	}
+}
+
+// AllowInbound admits a request from host: it is refused with
+// ErrBanned while the host is banned, and with ErrTooManyPeers when it
+// is new and MaxInbound hosts are active.
+func (m *Manager) AllowInbound(host string) error {
+
+	now := m.now()
+
+	if m.book.Banned(host, now) {
+		return ErrBanned
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.inbound[host]; !ok && !m.persistentHost(host) {
+
+		for h, seen := range m.inbound {
+			if now.Sub(seen) >= m.cfg.InboundIdle {
+				delete(m.inbound, h)
+			}
+		}
+
+		if len(m.inbound) >= m.cfg.MaxInbound {
+			return ErrTooManyPeers
+		}
+	}
+
+	m.inbound[host] = now
+	return nil
+}
+
+// Inbound returns the hosts served recently, sorted.
+func (m *Manager) Inbound() []string {
+
+	now := m.now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var list []string
+	for host, seen := range m.inbound {
+		if now.Sub(seen) < m.cfg.InboundIdle {
+			list = append(list, host)
+		}
+	}
+	sort.Strings(list)
+
+	return list
+}
+
+func (m *Manager) persistentHost(host string) bool {
+
+	for addr := range m.persistent {
+		if HostOf(addr) == host {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Addrs returns the addresses shared with other nodes: this node's,
+// then those it connected to, most recently first.
+func (m *Manager) Addrs() []string {
+
+	now := m.now()
+
+	var seen []AddrEntry
+	for _, e := range m.book.Entries() {
+		if !e.LastSeen.IsZero() && !m.book.Banned(HostOf(e.Addr), now) {
+			seen = append(seen, e)
+		}
+	}
+
+	sort.SliceStable(seen, func(i, j int) bool { return seen[i].LastSeen.After(seen[j].LastSeen) })
+
+	var addrs []string
+	if m.self.Addr != "" {
+		addrs = append(addrs, m.self.Addr)
+	}
+
+	for _, e := range seen {
+		if len(addrs) == maxShared {
+			break
+		}
+		addrs = append(addrs, e.Addr)
+	}
+
+	return addrs
+}
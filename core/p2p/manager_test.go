@@ -0,0 +1,435 @@
+package p2p
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testChain = Info{ChainID: "test", GenesisHash: []byte{1, 2, 3}}
+
+// fakeNet is a Transport to nodes that answer with their Info, or are
+// down, counting the handshakes each one got.
+type fakeNet struct {
+	mu       sync.Mutex
+	nodes    map[string]Info
+	addrs    map[string][]string
+	attempts map[string]int
+}
+
+func newFakeNet() *fakeNet {
+	return &fakeNet{nodes: make(map[string]Info), addrs: make(map[string][]string), attempts: make(map[string]int)}
+}
+
+// up makes a node of the test chain answer at addr.
+func (n *fakeNet) up(addr string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	info := testChain
+	info.Nonce = addr
+	n.nodes[addr] = info
+}
+
+func (n *fakeNet) down(addr string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	delete(n.nodes, addr)
+}
+
+func (n *fakeNet) tries(addr string) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.attempts[addr]
+}
+
+func (n *fakeNet) Handshake(ctx context.Context, addr string, self Info) (Info, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.attempts[addr]++
+
+	info, ok := n.nodes[addr]
+	if !ok {
+		return Info{}, errors.New("connection refused")
+	}
+	return info, nil
+}
+
+func (n *fakeNet) Addrs(ctx context.Context, addr string) ([]string, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	addrs, ok := n.addrs[addr]
+	if !ok {
+		return nil, errors.New("connection refused")
+	}
+	return addrs, nil
+}
+
+// testManager is a Manager on a fake clock, recording the peers its
+// callbacks were told of.
+type testManager struct {
+	*Manager
+	clock time.Time
+	peers map[string]bool
+}
+
+func newTestManager(t *testing.T, cfg Config, n *fakeNet) *testManager {
+
+	book, err := LoadAddrBook("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	self := testChain
+	self.Nonce = "self"
+
+	tm := &testManager{clock: time.Unix(1000, 0), peers: make(map[string]bool)}
+
+	tm.Manager = NewManager(cfg, self, book, n)
+	tm.now = func() time.Time { return tm.clock }
+	tm.OnConnect = func(addr string) { tm.peers[addr] = true }
+	tm.OnDisconnect = func(addr string) { delete(tm.peers, addr) }
+	tm.Logf = t.Logf
+
+	return tm
+}
+
+func (tm *testManager) advance(d time.Duration) {
+	tm.clock = tm.clock.Add(d)
+	tm.dial(context.Background(), true)
+}
+
+func TestManagerBacksOffFailedPeers(t *testing.T) {
+
+	n := newFakeNet()
+	n.up("10.0.0.1:9000")
+
+	cfg := DefaultConfig()
+	cfg.Persistent = []string{"10.0.0.1:9000", "10.0.0.2:9000"}
+
+	m := newTestManager(t, cfg, n)
+	m.advance(0)
+
+	if !m.peers["10.0.0.1:9000"] || m.peers["10.0.0.2:9000"] {
+		t.Fatalf("connected to %v", m.Connected())
+	}
+
+	// Retries wait 1s, 2s, 4s...
+	for _, wait := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+
+		tries := n.tries("10.0.0.2:9000")
+
+		m.advance(wait - time.Millisecond)
+		if n.tries("10.0.0.2:9000") != tries {
+			t.Fatalf("retried before the %s backoff", wait)
+		}
+
+		m.advance(time.Millisecond)
+		if n.tries("10.0.0.2:9000") != tries+1 {
+			t.Fatalf("not retried after the %s backoff", wait)
+		}
+	}
+
+	// A persistent peer is reconnected once it is back
+	n.up("10.0.0.2:9000")
+	m.advance(8 * time.Second)
+
+	if !m.peers["10.0.0.2:9000"] {
+		t.Fatal("persistent peer was not reconnected")
+	}
+
+	if e, _ := m.book.Entry("10.0.0.2:9000"); e.Failures != 0 {
+		t.Fatalf("%d failures after connecting, want 0", e.Failures)
+	}
+
+	// A lost peer too, after its backoff
+	m.Failed("10.0.0.1:9000", errors.New("timeout"))
+	if m.peers["10.0.0.1:9000"] {
+		t.Fatal("failed peer still connected")
+	}
+
+	m.advance(time.Second)
+	if !m.peers["10.0.0.1:9000"] {
+		t.Fatal("lost peer was not reconnected")
+	}
+}
+
+func TestManagerDiscoversUpToLimit(t *testing.T) {
+
+	n := newFakeNet()
+
+	var found []string
+	for i := 1; i <= 5; i++ {
+		addr := fmt.Sprintf("10.0.0.%d:9000", i)
+		n.up(addr)
+		found = append(found, addr)
+	}
+
+	// A node of another chain, and this node under another address
+	other := Info{ChainID: "other", GenesisHash: testChain.GenesisHash, Nonce: "other"}
+	n.nodes["10.0.0.0:9000"] = other
+	n.nodes["10.0.0.0:9001"] = Info{ChainID: testChain.ChainID, GenesisHash: testChain.GenesisHash, Nonce: "self"}
+
+	n.addrs["seed:9000"] = append([]string{"10.0.0.0:9000", "10.0.0.0:9001", "no port"}, found...)
+
+	cfg := DefaultConfig()
+	cfg.Seeds = []string{"seed:9000"}
+	cfg.MaxOutbound = 3
+
+	m := newTestManager(t, cfg, n)
+
+	// Until three peers connect, the wrong nodes get dialled too
+	for i := 0; i < 5 && len(m.Connected()) < 3; i++ {
+		m.advance(time.Second)
+	}
+
+	if got := len(m.Connected()); got != 3 {
+		t.Fatalf("connected to %d peers, want 3", got)
+	}
+
+	for _, addr := range []string{"10.0.0.0:9001", "no port"} {
+		if _, ok := m.book.Entry(addr); ok {
+			t.Fatalf("%s kept in the book", addr)
+		}
+	}
+
+	// A lost peer is retried first, having been seen, then replaced
+	lost := m.Connected()[0]
+	n.down(lost)
+	m.Failed(lost, errors.New("timeout"))
+	m.advance(time.Second)
+	m.advance(time.Second)
+
+	if n.tries(lost) != 2 {
+		t.Fatalf("lost peer dialled %d times, want 2", n.tries(lost))
+	}
+
+	if got := m.Connected(); len(got) != 3 || m.peers[lost] {
+		t.Fatalf("connected to %v after losing %s", got, lost)
+	}
+}
+
+func TestManagerLearnsDiallingNodes(t *testing.T) {
+
+	n := newFakeNet()
+	n.up("10.0.0.1:9000")
+
+	m := newTestManager(t, DefaultConfig(), n)
+
+	node := testChain
+	node.Nonce = "node"
+
+	// Only an address on the dialling host, of a node of this chain
+	for _, c := range []struct {
+		host, addr, chain string
+	}{
+		{"10.0.0.2", "10.0.0.1:9000", "test"},
+		{"10.0.0.1", "10.0.0.1:9000", "other"},
+		{"10.0.0.1", "10.0.0.1", "test"},
+	} {
+		node.Addr, node.ChainID = c.addr, c.chain
+		m.Introduce(c.host, node)
+	}
+
+	if n := len(m.book.Entries()); n != 0 {
+		t.Fatalf("learned %d addresses, want none", n)
+	}
+
+	node.Addr, node.ChainID = "10.0.0.1:9000", "test"
+	m.Introduce("10.0.0.1", node)
+	m.advance(time.Second)
+
+	if !m.peers["10.0.0.1:9000"] {
+		t.Fatal("dialling node was not dialled back")
+	}
+}
+
+func TestManagerLimitsInbound(t *testing.T) {
+
+	cfg := DefaultConfig()
+	cfg.MaxInbound = 2
+	cfg.Persistent = []string{"10.0.0.9:9000"}
+
+	m := newTestManager(t, cfg, newFakeNet())
+
+	for _, host := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.1", "10.0.0.9"} {
+		if err := m.AllowInbound(host); err != nil {
+			t.Fatalf("%s refused: %v", host, err)
+		}
+	}
+
+	if err := m.AllowInbound("10.0.0.3"); !errors.Is(err, ErrTooManyPeers) {
+		t.Fatalf("third host: %v, want ErrTooManyPeers", err)
+	}
+
+	// Idle hosts free their slot
+	m.clock = m.clock.Add(cfg.InboundIdle)
+
+	if err := m.AllowInbound("10.0.0.3"); err != nil {
+		t.Fatalf("host refused after the others went idle: %v", err)
+	}
+}
+
+func TestScoreRecoversByMinute(t *testing.T) {
+
+	start := time.Unix(1000, 0)
+	s := &score{at: start}
+
+	// Offenses moments apart reach the ban score exactly
+	var v int
+	for i := 0; i < 5; i++ {
+		v = s.add(-MalformedMessage.Penalty(), start.Add(time.Duration(i)*time.Millisecond))
+	}
+
+	if v != BanScore {
+		t.Fatalf("score %d, want %d", v, BanScore)
+	}
+
+	// Partial minutes carry over to the next offense
+	s.add(0, start.Add(90*time.Second))
+
+	if v := s.current(start.Add(120 * time.Second)); v != BanScore+2 {
+		t.Fatalf("score %d after two minutes, want %d", v, BanScore+2)
+	}
+
+	if v := s.current(start.Add(24 * time.Hour)); v != 0 {
+		t.Fatalf("score %d after a day, want 0", v)
+	}
+}
+
+func TestManagerBansMisbehavingPeers(t *testing.T) {
+
+	n := newFakeNet()
+	n.up("10.0.0.1:9000")
+	n.up("10.0.0.1:9001")
+
+	cfg := DefaultConfig()
+	cfg.Persistent = []string{"10.0.0.1:9000", "10.0.0.1:9001"}
+
+	m := newTestManager(t, cfg, n)
+	m.advance(0)
+
+	// Malformed messages cost 20, recovering 1 a minute
+	for i := 0; i < 4; i++ {
+		m.Report("10.0.0.1", MalformedMessage, errors.New("bad json"))
+	}
+
+	if s := m.Score("10.0.0.1:9000"); s != -80 {
+		t.Fatalf("score %d, want -80", s)
+	}
+
+	m.clock = m.clock.Add(30 * time.Minute)
+
+	if s := m.Score("10.0.0.1"); s != -50 {
+		t.Fatalf("score %d after 30 minutes, want -50", s)
+	}
+
+	// Equivocation bans the host, for every address on it
+	m.Report("10.0.0.1:9001", Equivocation, errors.New("conflicting block"))
+
+	if len(m.peers) != 0 {
+		t.Fatalf("banned host still connected at %v", m.Connected())
+	}
+
+	if err := m.AllowInbound("10.0.0.1"); !errors.Is(err, ErrBanned) {
+		t.Fatalf("banned host: %v, want ErrBanned", err)
+	}
+
+	m.advance(cfg.BanDuration - time.Second)
+	if len(m.peers) != 0 {
+		t.Fatal("banned host reconnected before the ban ended")
+	}
+
+	m.advance(time.Second)
+	if len(m.peers) != 2 {
+		t.Fatalf("connected to %v after the ban", m.Connected())
+	}
+
+	// The score starts over, and the next ban lasts twice as long
+	if s := m.Score("10.0.0.1"); s != 0 {
+		t.Fatalf("score %d after the ban, want 0", s)
+	}
+
+	m.Report("10.0.0.1", Equivocation, errors.New("conflicting block"))
+	m.advance(cfg.BanDuration)
+
+	if len(m.peers) != 0 {
+		t.Fatal("second ban was not longer")
+	}
+}
+
+// A conflicting block without a valid certificate is an invalid block,
+// which a peer survives once: only proven equivocation bans at once.
+func TestManagerDoesNotBanUnprovenConflicts(t *testing.T) {
+
+	n := newFakeNet()
+	n.up("10.0.0.1:9000")
+
+	cfg := DefaultConfig()
+	cfg.Persistent = []string{"10.0.0.1:9000"}
+
+	m := newTestManager(t, cfg, n)
+	m.advance(0)
+
+	m.Report("10.0.0.1:9000", InvalidBlock, errors.New("conflicting block without a quorum"))
+
+	if s := m.Score("10.0.0.1"); s != -50 {
+		t.Fatalf("score %d, want -50", s)
+	}
+
+	if len(m.peers) != 1 {
+		t.Fatalf("peer disconnected after one unproven conflict: %v", m.Connected())
+	}
+
+	if err := m.AllowInbound("10.0.0.1"); err != nil {
+		t.Fatal("host refused after one unproven conflict:", err)
+	}
+
+	m.Report("10.0.0.1:9000", Equivocation, errors.New("certified conflicting block"))
+
+	if err := m.AllowInbound("10.0.0.1"); !errors.Is(err, ErrBanned) {
+		t.Fatalf("equivocating host: %v, want ErrBanned", err)
+	}
+}
+
+func TestHTTPTransport(t *testing.T) {
+
+	book, _ := LoadAddrBook("")
+	server := NewManager(DefaultConfig(), Info{ChainID: "test", GenesisHash: []byte{1}}, book, NewHTTPTransport())
+	server.Logf = t.Logf
+
+	ts := httptest.NewServer(server.Guard(Handler(server)))
+	defer ts.Close()
+
+	addr := strings.TrimPrefix(ts.URL, "http://")
+
+	book, _ = LoadAddrBook("")
+	client := NewManager(Config{Persistent: []string{addr}}, Info{ChainID: "test", GenesisHash: []byte{1}, Addr: "127.0.0.1:9000"}, book, NewHTTPTransport())
+	client.Logf = t.Logf
+	client.dial(context.Background(), true)
+
+	if got := client.Connected(); len(got) != 1 || got[0] != addr {
+		t.Fatalf("connected to %v, want %s", got, addr)
+	}
+
+	if _, ok := server.book.Entry("127.0.0.1:9000"); !ok {
+		t.Fatal("server did not learn the client's address")
+	}
+
+	// A banned host is refused
+	server.Report("127.0.0.1", Equivocation, errors.New("conflicting block"))
+
+	_, err := NewHTTPTransport().Handshake(context.Background(), addr, client.Info())
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Fatalf("banned handshake: %v, want 403", err)
+	}
+}
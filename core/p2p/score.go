@@ -0,0 +1,121 @@
+package p2p
+
+import (
+	"fmt"
+	"time"
+)
+
+// Offense is peer misbehaviour that costs score.
+type Offense int
+
+const (
+	// MalformedMessage is a message that could not be decoded.
+	MalformedMessage Offense = iota
+
+	// InvalidSignature is a transaction or vote whose signature does
+	// not verify.
+	InvalidSignature
+
+	// InvalidBlock is a block or certificate that fails validation.
+	InvalidBlock
+
+	// Equivocation is a block conflicting with one already committed
+	// at its height, proven by a valid commit certificate. A
+	// conflicting block without one is an InvalidBlock.
+	Equivocation
+
+	// FalseHeight is a height reported for sync that the peer did not
+	// serve blocks up to.
+	FalseHeight
+)
+
+var offenses = []struct {
+	name    string
+	penalty int
+}{
+	MalformedMessage: {"malformed message", 20},
+	InvalidSignature: {"invalid signature", 50},
+	InvalidBlock:     {"invalid block", 50},
+	Equivocation:     {"equivocation", 100},
+	FalseHeight:      {"false height", 20},
+}
+
+func (o Offense) String() string {
+	if int(o) < len(offenses) {
+		return offenses[o].name
+	}
+	return fmt.Sprintf("offense %d", int(o))
+}
+
+// Penalty is the score o costs.
+func (o Offense) Penalty() int {
+	if int(o) < len(offenses) {
+		return offenses[o].penalty
+	}
+	return 0
+}
+
+const (
+	// BanScore is the score at which a peer is banned. Every peer
+	// starts at zero.
+	BanScore = -100
+
+	// recoveryPerMinute is the score a peer regains every minute, up
+	// to zero, so old offenses are forgiven
+	recoveryPerMinute = 1
+
+	// maxBan bounds a ban, which doubles with every repeat.
+	maxBan = 24 * time.Hour
+)
+
+// score is a peer's standing, recovering a point for every whole
+// minute since at.
+type score struct {
+	value int
+	at    time.Time
+}
+
+// current returns the score at now.
+func (s *score) current(now time.Time) int {
+
+	v := s.value + int(now.Sub(s.at)/time.Minute)*recoveryPerMinute
+	if v > 0 {
+		v = 0
+	}
+
+	return v
+}
+
+// add changes the score by delta at now and returns it. The minute in
+// progress still counts towards recovery.
+func (s *score) add(delta int, now time.Time) int {
+
+	minutes := now.Sub(s.at) / time.Minute
+
+	s.value += int(minutes) * recoveryPerMinute
+	s.at = s.at.Add(minutes * time.Minute)
+
+	if s.value > 0 {
+		s.value = 0
+		s.at = now
+	}
+
+	s.value += delta
+	return s.value
+}
+
+// banDuration returns how long a host banned count times before is
+// banned for: base, doubled with every earlier ban.
+func banDuration(base time.Duration, count int) time.Duration {
+
+	d := base
+	for i := 0; i < count && d < maxBan; i++ {
+		d *= 2
+	}
+
+	if d > maxBan {
+		d = maxBan
+	}
+
+	return d
+}
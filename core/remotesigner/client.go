@@ -0,0 +1,274 @@
+package remotesigner
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/identity"
+)
+
+const defaultTimeout = 5 * time.Second
+
+// Client talks to a remote signer over a single connection.
+// It is safe for concurrent use; requests are serialized. A request
+// that fails drops the connection, so a late reply can never be read
+// as the answer to a later request, and the next request dials again.
+type Client struct {
+	mu      sync.Mutex
+	network string
+	addr    string
+	conn    net.Conn
+	reader  *bufio.Reader
+	timeout time.Duration
+	lastID  uint64
+
+	// secret authenticates each connection, keying its session
+	secret  []byte
+	session []byte
+
+	nodeID    string
+	publicKey []byte
+	verifier  crypto.Signer
+}
+
+// Dial connects to a signer at an address accepted by ParseAddress,
+// authenticating with secret when it is set. A TCP signer requires
+// one.
+func Dial(address string, secret []byte) (*Client, error) {
+
+	network, addr, err := ParseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	if network != "unix" && len(secret) == 0 {
+		return nil, errors.New("a " + network + " signer needs a shared secret")
+	}
+
+	c := &Client{network: network, addr: addr, timeout: defaultTimeout, secret: secret}
+
+	if err := c.connect(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *Client) connect() error {
+
+	conn, err := net.DialTimeout(c.network, c.addr, c.timeout)
+	if err != nil {
+		return err
+	}
+
+	c.conn = conn
+	c.reader = bufio.NewReaderSize(conn, maxLineSize)
+	c.session = nil
+
+	if len(c.secret) == 0 {
+		return nil
+	}
+
+	if err := c.hello(); err != nil {
+		c.drop()
+		return err
+	}
+
+	return nil
+}
+
+// hello opens an authenticated session on a new connection.
+func (c *Client) hello() error {
+
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	c.lastID++
+
+	resp, err := c.exchange(Request{ID: c.lastID, Type: RequestHello, Nonce: nonce})
+	if err != nil {
+		return err
+	}
+
+	if resp.Error != "" {
+		return errors.New("remote signer: " + resp.Error)
+	}
+
+	if len(resp.Nonce) != nonceSize || !hmac.Equal(resp.MAC, helloMAC(c.secret, nonce, resp.Nonce)) {
+		return errors.New("remote signer failed to authenticate")
+	}
+
+	c.session = sessionKey(c.secret, nonce, resp.Nonce)
+	return nil
+}
+
+// drop closes a connection left in an unknown state.
+func (c *Client) drop() {
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn, c.reader, c.session = nil, nil, nil
+	}
+}
+
+func (c *Client) Close() error {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		return nil
+	}
+
+	err := c.conn.Close()
+	c.conn, c.reader = nil, nil
+	return err
+}
+
+func (c *Client) roundTrip(req Request) (*Response, error) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		if err := c.connect(); err != nil {
+			return nil, err
+		}
+	}
+
+	c.lastID++
+	req.ID = c.lastID
+
+	if c.session != nil {
+		req.MAC = requestMAC(c.session, req)
+	}
+
+	resp, err := c.exchange(req)
+	if err != nil {
+		c.drop()
+		return nil, err
+	}
+
+	if resp.Error != "" {
+		return nil, errors.New("remote signer: " + resp.Error)
+	}
+
+	return resp, nil
+}
+
+// exchange sends req and reads its response. Any error leaves the
+// connection unusable.
+func (c *Client) exchange(req Request) (*Response, error) {
+
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+	defer c.conn.SetDeadline(time.Time{})
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := c.conn.Write(append(data, '\n')); err != nil {
+		return nil, err
+	}
+
+	line, err := c.reader.ReadSlice('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	var resp Response
+	if err := json.Unmarshal(line, &resp); err != nil {
+		return nil, err
+	}
+
+	if resp.ID != req.ID {
+		return nil, fmt.Errorf("remote signer answered request %d to request %d", resp.ID, req.ID)
+	}
+
+	return &resp, nil
+}
+
+// Sign asks the signer to sign message, a block or vote for height and
+// view, in domain with its validator key, and checks the signature
+// against the signer's public key. It implements identity.KeyBackend.
+func (c *Client) Sign(domain crypto.Domain, height int, view int, message []byte) ([]byte, error) {
+
+	if len(message) == 0 || len(message) > MaxMessageSize {
+		return nil, errors.New("invalid message size")
+	}
+
+	ctx, err := domain.Context()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	nodeID, publicKey, verifier := c.nodeID, c.publicKey, c.verifier
+	c.mu.Unlock()
+
+	if nodeID == "" {
+		return nil, errors.New("remote signer identity not loaded")
+	}
+
+	resp, err := c.roundTrip(Request{
+		Type:    RequestSign,
+		NodeID:  nodeID,
+		Domain:  &domain,
+		Height:  height,
+		View:    view,
+		Message: message,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Signature) == 0 {
+		return nil, errors.New("remote signer returned empty signature")
+	}
+
+	if !crypto.VerifyInContext(verifier, publicKey, message, resp.Signature, ctx) {
+		return nil, errors.New("remote signer returned a signature that does not verify")
+	}
+
+	return resp.Signature, nil
+}
+
+// Identity fetches the signer's public identity and returns a
+// NodeIdentity that signs through this client. The signer's algorithm
+// must match the local verifier.
+func (c *Client) Identity(verifier crypto.Signer) (*identity.NodeIdentity, error) {
+
+	resp, err := c.roundTrip(Request{Type: RequestInfo})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.NodeID == "" || len(resp.PublicKey) == 0 {
+		return nil, errors.New("remote signer returned incomplete identity")
+	}
+
+	if resp.Algorithm != verifier.Algorithm() {
+		return nil, fmt.Errorf(
+			"remote signer uses %s, node expects %s",
+			resp.Algorithm, verifier.Algorithm(),
+		)
+	}
+
+	c.mu.Lock()
+	c.nodeID = resp.NodeID
+	c.publicKey = resp.PublicKey
+	c.verifier = verifier
+	c.mu.Unlock()
+
+	return identity.NewRemoteNodeIdentity(resp.NodeID, resp.PublicKey, verifier, c), nil
+}
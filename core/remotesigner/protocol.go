@@ -0,0 +1,135 @@
+package remotesigner
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
+)
+
+/*
+Remote signer protocol.
+
+A validator node connects to a signer process over a Unix socket or
+TCP and exchanges newline-delimited JSON messages. Each request gets
+exactly one response on the same connection, in order, echoing the
+request ID.
+
+Request types:
+
+✔ hello — starts an authenticated session (see below)
+✔ info — returns node ID, algorithm and public key held by the signer
+✔ sign — signs Message, a block hash or commit vote digest for Height
+  and View, in the signature Domain (chain ID + message type)
+
+The signer only signs blocks and votes of its own chain, and never two
+different messages of one type at the same height and view, or at an
+earlier one (see SignState). It never returns private key material.
+
+A signer with a shared secret requires every connection to open with
+hello: the client sends a nonce, the signer answers with its own and
+an HMAC of both under the secret, and every later request carries an
+HMAC under a session key derived from the secret and both nonces,
+with increasing IDs. Without a secret the signer only listens on a
+Unix socket, protected by its file mode.
+*/
+
+const (
+	RequestHello = "hello"
+	RequestInfo  = "info"
+	RequestSign  = "sign"
+
+	// MaxMessageSize bounds the payload accepted for signing.
+	// Everything the node signs today is a 32-byte digest.
+	MaxMessageSize = 4096
+
+	// maxLineSize bounds a single encoded request or response.
+	maxLineSize = 64 * 1024
+
+	// MinSecretSize is the shortest shared secret accepted.
+	MinSecretSize = 16
+
+	// SecretEnvVar holds the shared secret of the signer and the node.
+	SecretEnvVar = "AEGISQ_SIGNER_SECRET"
+
+	nonceSize = 32
+)
+
+type Request struct {
+	ID      uint64         `json:"id"`
+	Type    string         `json:"type"`
+	NodeID  string         `json:"node_id,omitempty"`
+	Domain  *crypto.Domain `json:"domain,omitempty"`
+	Height  int            `json:"height,omitempty"`
+	View    int            `json:"view,omitempty"`
+	Message []byte         `json:"message,omitempty"`
+	Nonce   []byte         `json:"nonce,omitempty"`
+	MAC     []byte         `json:"mac,omitempty"`
+}
+
+type Response struct {
+	ID        uint64 `json:"id"`
+	NodeID    string `json:"node_id,omitempty"`
+	Algorithm string `json:"algorithm,omitempty"`
+	PublicKey []byte `json:"public_key,omitempty"`
+	Signature []byte `json:"signature,omitempty"`
+	Nonce     []byte `json:"nonce,omitempty"`
+	MAC       []byte `json:"mac,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// helloMAC proves the signer knows secret, for the client's nonce.
+func helloMAC(secret, clientNonce, serverNonce []byte) []byte {
+	return macOf(secret, []byte("aegisq-signer/hello"), clientNonce, serverNonce)
+}
+
+// sessionKey keys the request MACs of one connection.
+func sessionKey(secret, clientNonce, serverNonce []byte) []byte {
+	return macOf(secret, []byte("aegisq-signer/session"), clientNonce, serverNonce)
+}
+
+// requestMAC authenticates req, its ID included, under key.
+func requestMAC(key []byte, req Request) []byte {
+
+	req.MAC = nil
+
+	// A struct of strings, integers and byte slices always encodes
+	data, _ := json.Marshal(req)
+
+	return macOf(key, data)
+}
+
+func macOf(key []byte, parts ...[]byte) []byte {
+
+	mac := hmac.New(sha256.New, key)
+
+	for _, p := range parts {
+		var n [4]byte
+		binary.BigEndian.PutUint32(n[:], uint32(len(p)))
+		mac.Write(n[:])
+		mac.Write(p)
+	}
+
+	return mac.Sum(nil)
+}
+
+// ParseAddress splits "unix:/path/to.sock" or "tcp:host:port" into a
+// network and address suitable for net.Dial and net.Listen.
+func ParseAddress(s string) (string, string, error) {
+
+	network, addr, ok := strings.Cut(s, ":")
+	if !ok || addr == "" {
+		return "", "", errors.New("signer address must be unix:<path> or tcp:<host:port>")
+	}
+
+	switch network {
+	case "unix", "tcp":
+		return network, addr, nil
+	default:
+		return "", "", errors.New("unsupported signer network: " + network)
+	}
+}
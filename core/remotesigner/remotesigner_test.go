@@ -0,0 +1,395 @@
+package remotesigner
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/block"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/chain"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/identity"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/transaction"
+)
+
+var testDomain = chain.Default.Domain(crypto.MessageVote)
+
+var testConfig = ServerConfig{ChainID: chain.DefaultID}
+
+func startSigner(t *testing.T, node *identity.NodeIdentity) string {
+
+	sock := filepath.Join(t.TempDir(), "signer.sock")
+
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := NewServer(node, testConfig)
+	go srv.Serve(l)
+	t.Cleanup(func() { srv.Close() })
+
+	return "unix:" + sock
+}
+
+func TestRemoteSignedBlockVerifies(t *testing.T) {
+
+	signer, err := crypto.NewDilithiumSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer signer.Close()
+
+	local, err := identity.NewNodeIdentity("validator-1", signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := Dial(startSigner(t, local), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	remote, err := client.Identity(signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if remote.PrivateKey != nil {
+		t.Fatal("remote identity must not hold a private key")
+	}
+
+	// The signer signs blocks and votes only
+	tx := transaction.NewTransaction(remote, "payload", "data")
+	if err := tx.SignWithIdentity(remote); err == nil {
+		t.Fatal("remote signer signed a transaction")
+	}
+
+	if err := tx.SignWithIdentity(local); err != nil {
+		t.Fatal(err)
+	}
+
+	b := block.NewBlock(1, 0, []byte("prev_hash"), []*transaction.Transaction{tx})
+	if err := b.Finalize(remote); err != nil {
+		t.Fatal(err)
+	}
+
+	valid, err := b.Verify(signer, local.PublicKey)
+	if err != nil || !valid {
+		t.Fatal("block signed through remote signer failed verification")
+	}
+}
+
+func TestRemoteSignerAlgorithmMismatch(t *testing.T) {
+
+	local, _ := identity.NewNodeIdentity("validator-1", &crypto.Ed25519Signer{})
+
+	client, err := Dial(startSigner(t, local), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ecdsa, _ := crypto.NewECDSASigner()
+
+	if _, err := client.Identity(ecdsa); err == nil {
+		t.Fatal("algorithm mismatch should be rejected")
+	}
+}
+
+func TestRemoteSignerRejectsForeignNodeID(t *testing.T) {
+
+	local, _ := identity.NewNodeIdentity("validator-1", &crypto.Ed25519Signer{})
+
+	srv := NewServer(local, testConfig)
+
+	resp := srv.process(Request{
+		Type:    RequestSign,
+		NodeID:  "validator-2",
+		Domain:  &testDomain,
+		Height:  1,
+		Message: []byte("digest"),
+	})
+
+	if resp.Error == "" || resp.Signature != nil {
+		t.Fatal("signer must refuse requests for another node")
+	}
+}
+
+// slowSigner answers the first sign request only after delay, on the
+// same connection, as a signer stuck behind a slow disk would.
+func slowSigner(t *testing.T, srv *Server, delay time.Duration) string {
+
+	sock := filepath.Join(t.TempDir(), "slow.sock")
+
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	slow := make(chan struct{}, 1)
+	slow <- struct{}{}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+
+				scanner := bufio.NewScanner(conn)
+				enc := json.NewEncoder(conn)
+
+				for scanner.Scan() {
+
+					var req Request
+					json.Unmarshal(scanner.Bytes(), &req)
+
+					if req.Type == RequestSign {
+						select {
+						case <-slow:
+							time.Sleep(delay)
+						default:
+						}
+					}
+
+					resp := srv.process(req)
+					resp.ID = req.ID
+					enc.Encode(resp)
+				}
+			}()
+		}
+	}()
+
+	return "unix:" + sock
+}
+
+func TestTimedOutRequestDoesNotAnswerTheNext(t *testing.T) {
+
+	signer := &crypto.Ed25519Signer{}
+	local, _ := identity.NewNodeIdentity("validator-1", signer)
+
+	client, err := Dial(slowSigner(t, NewServer(local, testConfig), 300*time.Millisecond), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	remote, err := client.Identity(signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client.timeout = 100 * time.Millisecond
+
+	if _, err := remote.SignAt(testDomain, 1, 0, []byte("first")); err == nil {
+		t.Fatal("expected the first request to time out")
+	}
+
+	// The late reply to "first" arrives while "second" waits
+	time.Sleep(300 * time.Millisecond)
+
+	second := []byte("second")
+
+	sig, err := remote.SignAt(testDomain, 2, 0, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !local.Verify(testDomain, second, sig) {
+		t.Fatal("second request got a signature over another message")
+	}
+}
+
+func TestParseAddress(t *testing.T) {
+
+	tests := []struct {
+		in      string
+		network string
+		addr    string
+		ok      bool
+	}{
+		{"unix:/tmp/signer.sock", "unix", "/tmp/signer.sock", true},
+		{"tcp:127.0.0.1:7100", "tcp", "127.0.0.1:7100", true},
+		{"udp:127.0.0.1:7100", "", "", false},
+		{"127.0.0.1", "", "", false},
+	}
+
+	for _, test := range tests {
+
+		network, addr, err := ParseAddress(test.in)
+
+		if (err == nil) != test.ok {
+			t.Fatalf("%s: unexpected error state: %v", test.in, err)
+		}
+
+		if network != test.network || addr != test.addr {
+			t.Fatalf("%s: got %s %s", test.in, network, addr)
+		}
+	}
+}
+
+func signAt(srv *Server, t crypto.MessageType, height, view int, msg string) Response {
+
+	domain := chain.Default.Domain(t)
+
+	return srv.process(Request{
+		Type:    RequestSign,
+		NodeID:  srv.node.NodeID,
+		Domain:  &domain,
+		Height:  height,
+		View:    view,
+		Message: []byte(msg),
+	})
+}
+
+func TestSignerRefusesDoubleSigning(t *testing.T) {
+
+	local, _ := identity.NewNodeIdentity("validator-1", &crypto.Ed25519Signer{})
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	state, err := LoadSignState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := NewServer(local, ServerConfig{ChainID: chain.DefaultID, State: state})
+
+	steps := []struct {
+		typ          crypto.MessageType
+		height, view int
+		msg          string
+		ok           bool
+	}{
+		{crypto.MessageBlock, 5, 0, "a", true},
+		{crypto.MessageBlock, 5, 0, "a", true}, // a lost answer asked again
+		{crypto.MessageBlock, 5, 0, "b", false},
+		{crypto.MessageBlock, 4, 3, "c", false},
+		{crypto.MessageVote, 5, 0, "vote a", true},
+		{crypto.MessageBlock, 5, 1, "b", true},
+		{crypto.MessageVote, 5, 0, "vote b", false},
+	}
+
+	for i, s := range steps {
+		if resp := signAt(srv, s.typ, s.height, s.view, s.msg); (resp.Error == "") != s.ok {
+			t.Fatalf("step %d: signed %v, want %v (%s)", i, resp.Error == "", s.ok, resp.Error)
+		}
+	}
+
+	// A restarted signer remembers
+	state, err = LoadSignState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv = NewServer(local, ServerConfig{ChainID: chain.DefaultID, State: state})
+
+	if resp := signAt(srv, crypto.MessageBlock, 5, 1, "c"); resp.Error == "" {
+		t.Fatal("restarted signer signed a conflicting block")
+	}
+
+	if resp := signAt(srv, crypto.MessageBlock, 6, 0, "d"); resp.Error != "" {
+		t.Fatal(resp.Error)
+	}
+}
+
+func TestSignerRefusesOtherDomains(t *testing.T) {
+
+	local, _ := identity.NewNodeIdentity("validator-1", &crypto.Ed25519Signer{})
+	srv := NewServer(local, testConfig)
+
+	for _, typ := range []crypto.MessageType{crypto.MessageTx, crypto.MessageKeyRotation} {
+		if resp := signAt(srv, typ, 1, 0, "digest"); resp.Error == "" {
+			t.Fatalf("signed a %s message", typ)
+		}
+	}
+
+	other := crypto.Domain{ChainID: "other-chain", Type: crypto.MessageBlock}
+
+	resp := srv.process(Request{Type: RequestSign, NodeID: "validator-1", Domain: &other, Height: 1, Message: []byte("digest")})
+	if resp.Error == "" {
+		t.Fatal("signed for another chain")
+	}
+
+	if resp := signAt(srv, crypto.MessageBlock, 0, 0, "digest"); resp.Error == "" {
+		t.Fatal("signed without a height")
+	}
+}
+
+func TestTCPSignerRequiresSecret(t *testing.T) {
+
+	signer := &crypto.Ed25519Signer{}
+	local, _ := identity.NewNodeIdentity("validator-1", signer)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	if err := NewServer(local, testConfig).Serve(l); err == nil {
+		t.Fatal("served TCP without a secret")
+	}
+
+	addr := "tcp:" + l.Addr().String()
+
+	if _, err := Dial(addr, nil); err == nil {
+		t.Fatal("dialled TCP without a secret")
+	}
+
+	secret := []byte("0123456789abcdef-shared")
+
+	cfg := testConfig
+	cfg.Secret = secret
+
+	srv := NewServer(local, cfg)
+	go srv.Serve(l)
+	t.Cleanup(func() { srv.Close() })
+
+	// A wrong secret fails the handshake
+	if _, err := Dial(addr, []byte("0123456789abcdef-wrong!")); err == nil {
+		t.Fatal("signer accepted a client with the wrong secret")
+	}
+
+	// Requests without a session are refused
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	json.NewEncoder(conn).Encode(signRequest(local.NodeID))
+
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil || resp.Error == "" || resp.Signature != nil {
+		t.Fatal("unauthenticated request was answered:", resp.Error, err)
+	}
+
+	client, err := Dial(addr, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	remote, err := client.Identity(signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sig, err := remote.SignAt(testDomain, 1, 0, []byte("digest"))
+	if err != nil || !local.Verify(testDomain, []byte("digest"), sig) {
+		t.Fatal("authenticated request failed:", err)
+	}
+}
+
+func signRequest(nodeID string) Request {
+	return Request{ID: 1, Type: RequestSign, NodeID: nodeID, Domain: &testDomain, Height: 1, Message: []byte("digest")}
+}
@@ -0,0 +1,253 @@
+package remotesigner
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net"
+	"sync"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/identity"
+)
+
+// ServerConfig restricts what a Server signs and who may ask.
+type ServerConfig struct {
+	// ChainID is the only chain blocks and votes are signed for.
+	ChainID string
+
+	// State records the last block and vote signed. Nil keeps the
+	// record in memory only.
+	State *SignState
+
+	// Secret, at least MinSecretSize bytes, makes every connection
+	// authenticate; it is required to serve anything but a Unix socket.
+	Secret []byte
+}
+
+// Server holds a single validator identity and signs on request.
+type Server struct {
+	node    *identity.NodeIdentity
+	chainID string
+	state   *SignState
+	secret  []byte
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	wg       sync.WaitGroup
+	closed   bool
+}
+
+func NewServer(node *identity.NodeIdentity, cfg ServerConfig) *Server {
+
+	state := cfg.State
+	if state == nil {
+		state, _ = LoadSignState("")
+	}
+
+	return &Server{
+		node:    node,
+		chainID: cfg.ChainID,
+		state:   state,
+		secret:  cfg.Secret,
+		conns:   make(map[net.Conn]struct{}),
+	}
+}
+
+// Serve accepts connections until the listener is closed.
+func (s *Server) Serve(l net.Listener) error {
+
+	if s.chainID == "" {
+		return errors.New("signer needs a chain ID")
+	}
+
+	if len(s.secret) > 0 && len(s.secret) < MinSecretSize {
+		return errors.New("signer secret too short")
+	}
+
+	if _, unix := l.Addr().(*net.UnixAddr); !unix && len(s.secret) == 0 {
+		return errors.New("a signer listening on " + l.Addr().Network() + " needs a shared secret")
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return errors.New("signer server closed")
+	}
+	s.listener = l
+	s.mu.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+
+			if closed {
+				return nil
+			}
+			return err
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return nil
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go s.handle(conn)
+	}
+}
+
+// Close stops accepting connections and drops existing ones.
+func (s *Server) Close() error {
+
+	s.mu.Lock()
+	s.closed = true
+
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) handle(conn net.Conn) {
+
+	defer func() {
+		conn.Close()
+
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+
+		s.wg.Done()
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 4096), maxLineSize)
+
+	enc := json.NewEncoder(conn)
+
+	// With a secret, session keys the requests after hello
+	var session []byte
+	var lastID uint64
+
+	for scanner.Scan() {
+
+		var req Request
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			enc.Encode(Response{Error: "malformed request"})
+			return
+		}
+
+		var resp Response
+
+		switch {
+
+		case len(s.secret) == 0:
+			resp = s.process(req)
+
+		case session == nil:
+			if req.Type != RequestHello || len(req.Nonce) != nonceSize {
+				enc.Encode(Response{ID: req.ID, Error: "authentication required"})
+				return
+			}
+
+			nonce := make([]byte, nonceSize)
+			if _, err := rand.Read(nonce); err != nil {
+				return
+			}
+
+			session = sessionKey(s.secret, req.Nonce, nonce)
+			lastID = req.ID
+			resp = Response{Nonce: nonce, MAC: helloMAC(s.secret, req.Nonce, nonce)}
+
+		default:
+			if req.ID <= lastID || !hmac.Equal(req.MAC, requestMAC(session, req)) {
+				enc.Encode(Response{ID: req.ID, Error: "authentication failed"})
+				return
+			}
+
+			lastID = req.ID
+			resp = s.process(req)
+		}
+
+		resp.ID = req.ID
+
+		if err := enc.Encode(resp); err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) process(req Request) Response {
+
+	switch req.Type {
+
+	case RequestInfo:
+		return Response{
+			NodeID:    s.node.NodeID,
+			Algorithm: s.node.Algorithm(),
+			PublicKey: s.node.PublicKey,
+		}
+
+	case RequestSign:
+		if req.NodeID != s.node.NodeID {
+			return Response{Error: "signer does not hold key for " + req.NodeID}
+		}
+
+		if len(req.Message) == 0 || len(req.Message) > MaxMessageSize {
+			return Response{Error: "invalid message size"}
+		}
+
+		if req.Domain == nil {
+			return Response{Error: "missing signature domain"}
+		}
+
+		// Only consensus messages of this chain, which carry a position
+		if req.Domain.ChainID != s.chainID {
+			return Response{Error: "signer does not sign for chain " + req.Domain.ChainID}
+		}
+
+		if req.Domain.Type != crypto.MessageBlock && req.Domain.Type != crypto.MessageVote {
+			return Response{Error: "signer does not sign " + string(req.Domain.Type) + " messages"}
+		}
+
+		if req.Height < 1 || req.View < 0 {
+			return Response{Error: "invalid height or view"}
+		}
+
+		if err := s.state.record(req.Domain.Type, req.Height, req.View, req.Message); err != nil {
+			return Response{Error: err.Error()}
+		}
+
+		sig, err := s.node.Sign(*req.Domain, req.Message)
+		if err != nil {
+			return Response{Error: err.Error()}
+		}
+
+		return Response{Signature: sig}
+
+	case RequestHello:
+		return Response{Error: "signer has no shared secret"}
+
+	default:
+		return Response{Error: "unknown request type: " + req.Type}
+	}
+}
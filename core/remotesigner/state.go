@@ -0,0 +1,154 @@
+package remotesigner
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
+)
+
+// ErrDoubleSign is returned for a request that conflicts with a message
+// signed before.
+var ErrDoubleSign = errors.New("conflicts with a message signed before")
+
+// LastSigned is the position and digest of the last message of one
+// type the signer signed.
+type LastSigned struct {
+	Height int    `json:"height"`
+	View   int    `json:"view"`
+	Digest []byte `json:"digest"`
+}
+
+// SignState remembers, per message type, the last message signed, so
+// a signer never signs two different blocks or votes at one height and
+// view, nor goes back to an earlier one. A position is recorded on
+// disk before the signature leaves the signer, so the record survives
+// a crash or restart.
+type SignState struct {
+	mu   sync.Mutex
+	path string
+	last map[crypto.MessageType]LastSigned
+}
+
+// LoadSignState reads the record at path, starting an empty one when
+// the file does not exist yet. An empty path keeps the record in
+// memory only.
+func LoadSignState(path string) (*SignState, error) {
+
+	s := &SignState{path: path, last: make(map[crypto.MessageType]LastSigned)}
+
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &s.last); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return s, nil
+}
+
+// Last returns the last message of type t signed, if any.
+func (s *SignState) Last(t crypto.MessageType) (LastSigned, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.last[t]
+	return l, ok
+}
+
+// record admits message of type t at height and view, and saves the
+// position. Signing the same message at the last position again is
+// allowed, so a node can repeat a request whose answer it lost.
+func (s *SignState) record(t crypto.MessageType, height, view int, message []byte) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	digest := sha256.Sum256(message)
+
+	if l, ok := s.last[t]; ok {
+
+		switch {
+		case height < l.Height || (height == l.Height && view < l.View):
+			return fmt.Errorf("%s at height %d view %d: %w at height %d view %d", t, height, view, ErrDoubleSign, l.Height, l.View)
+
+		case height == l.Height && view == l.View:
+			if !bytes.Equal(digest[:], l.Digest) {
+				return fmt.Errorf("%s at height %d view %d: %w", t, height, view, ErrDoubleSign)
+			}
+			return nil
+		}
+	}
+
+	prev, had := s.last[t]
+	s.last[t] = LastSigned{Height: height, View: view, Digest: digest[:]}
+
+	if err := s.save(); err != nil {
+		if had {
+			s.last[t] = prev
+		} else {
+			delete(s.last, t)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// save replaces the file atomically, synced to disk.
+func (s *SignState) save() error {
+
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.last, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return nil
+}
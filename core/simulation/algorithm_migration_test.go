@@ -0,0 +1,240 @@
+package simulation
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/block"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/chain"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/consensus"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/identity"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/ledger"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/transaction"
+)
+
+// TestAlgorithmMigrationECDSAToDilithium runs a 4-validator chain that
+// starts on ECDSA and switches to Dilithium at a scheduled height.
+// Validators announce their Dilithium keys through key rotations
+// before the boundary; afterwards ECDSA blocks and transactions are
+// rejected, while the whole chain still validates.
+func TestAlgorithmMigrationECDSAToDilithium(t *testing.T) {
+
+	const switchHeight = 4
+
+	params := chain.Params{
+		ID:     "aegisq-migration",
+		Hasher: crypto.DefaultHasher,
+		Algorithms: []chain.AlgorithmEpoch{
+			{Height: 0, Algorithm: "ECDSA_P256"},
+			{Height: switchHeight, Algorithm: "dilithium2"},
+		},
+	}
+
+	if err := params.ValidateSchedule(); err != nil {
+		t.Fatal(err)
+	}
+
+	ecdsa, err := crypto.NewECDSASigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dilithium, err := crypto.NewDilithiumSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dilithium.Close()
+
+	// -------------------------
+	// 4 validators, each with its current ECDSA key and the
+	// Dilithium key it will migrate to
+	// -------------------------
+	classical := make(map[string]*identity.NodeIdentity)
+	postQuantum := make(map[string]*identity.NodeIdentity)
+	vs := consensus.NewValidatorSet()
+
+	for i := 1; i <= 4; i++ {
+		id := fmt.Sprintf("v%d", i)
+
+		classical[id], err = identity.NewNodeIdentity(id, ecdsa)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		postQuantum[id], err = identity.NewNodeIdentity(id, dilithium)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		vs.AddValidator(id, classical[id].PublicKey)
+	}
+
+	// -------------------------
+	// Genesis Block
+	// -------------------------
+	genTxs, err := GenerateSyntheticDataset(1, classical["v1"], params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	genesis := block.NewBlock(0, 0, []byte("genesis"), genTxs)
+	if err := genesis.FinalizeWith(classical["v1"], params); err != nil {
+		t.Fatal(err)
+	}
+
+	ldg := ledger.NewLedger(genesis, vs)
+	ldg.Params = params
+
+	propose := func(leader *identity.NodeIdentity, extra ...*transaction.Transaction) *block.Block {
+
+		txs, err := GenerateSyntheticDataset(10, leader, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		last := ldg.GetLastBlock()
+
+		b := block.NewBlock(last.Index+1, 0, last.Hash, append(txs, extra...))
+		if err := b.FinalizeWith(leader, params); err != nil {
+			t.Fatal(err)
+		}
+
+		return b
+	}
+
+	leaderAt := func(height int) string {
+		id, err := ldg.Scheduler.GetLeader(height, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+
+	// -------------------------
+	// Height 1: every validator schedules its Dilithium key
+	// -------------------------
+	var rotations []*transaction.Transaction
+
+	for id, current := range classical {
+		next := postQuantum[id]
+
+		tx := transaction.NewKeyRotation(current, next.Algorithm(), next.PublicKey, switchHeight)
+		if err := tx.SignRotation(current, next, params); err != nil {
+			t.Fatal(err)
+		}
+
+		rotations = append(rotations, tx)
+	}
+
+	leader := classical[leaderAt(1)]
+	if err := ldg.AddBlock(propose(leader, rotations...), ecdsa, leader.PublicKey); err != nil {
+		t.Fatal("rotation block rejected:", err)
+	}
+
+	// -------------------------
+	// Heights 2-3: still ECDSA; Dilithium is not accepted early
+	// -------------------------
+	for height := 2; height < switchHeight; height++ {
+		id := leaderAt(height)
+
+		early := propose(postQuantum[id])
+		if err := ldg.AddBlock(early, dilithium, postQuantum[id].PublicKey); err == nil {
+			t.Fatalf("Dilithium block accepted at height %d, before the switch", height)
+		}
+
+		leader := classical[id]
+		if err := ldg.AddBlock(propose(leader), ecdsa, leader.PublicKey); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// -------------------------
+	// Height 4 onwards: Dilithium only
+	// -------------------------
+	for height := switchHeight; height < switchHeight+4; height++ {
+		id := leaderAt(height)
+
+		stale := propose(classical[id])
+		if err := ldg.AddBlock(stale, ecdsa, classical[id].PublicKey); err == nil {
+			t.Fatalf("ECDSA block accepted at height %d, after the switch", height)
+		}
+
+		// An ECDSA transaction smuggled into a Dilithium block
+		legacyTx, err := GenerateSyntheticDataset(1, classical[id], params)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		mixed := propose(postQuantum[id], legacyTx...)
+		if err := ldg.AddBlock(mixed, dilithium, postQuantum[id].PublicKey); err == nil {
+			t.Fatalf("ECDSA transaction accepted at height %d, after the switch", height)
+		}
+
+		leader := postQuantum[id]
+		if err := ldg.AddBlock(propose(leader), dilithium, leader.PublicKey); err != nil {
+			t.Fatalf("height %d: %v", height, err)
+		}
+	}
+
+	// -------------------------
+	// The chain validates across the boundary with either signer
+	// -------------------------
+	for _, signer := range []crypto.Signer{dilithium, ecdsa} {
+		if err := ldg.ValidateChain(signer); err != nil {
+			t.Fatalf("chain validation with %s failed: %v", signer.Algorithm(), err)
+		}
+	}
+
+	t.Logf("Migrated from %s to %s at height %d, chain height %d",
+		ecdsa.Algorithm(), dilithium.Algorithm(), switchHeight, ldg.GetLastBlock().Index)
+}
+
+func TestAlgorithmMigrationRejectsMismatchedRotation(t *testing.T) {
+
+	params := chain.Params{
+		ID:     "aegisq-migration",
+		Hasher: crypto.DefaultHasher,
+		Algorithms: []chain.AlgorithmEpoch{
+			{Height: 0, Algorithm: "ed25519"},
+			{Height: 10, Algorithm: "ECDSA_P256"},
+		},
+	}
+
+	ed := &crypto.Ed25519Signer{}
+	ecdsa, _ := crypto.NewECDSASigner()
+
+	node, _ := identity.NewNodeIdentity("v1", ed)
+	next, _ := identity.NewNodeIdentity("v1", ecdsa)
+
+	vs := consensus.NewValidatorSet()
+	vs.AddValidator("v1", node.PublicKey)
+
+	genTxs, err := GenerateSyntheticDataset(1, node, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	genesis := block.NewBlock(0, 0, []byte("genesis"), genTxs)
+	if err := genesis.FinalizeWith(node, params); err != nil {
+		t.Fatal(err)
+	}
+
+	ldg := ledger.NewLedger(genesis, vs)
+	ldg.Params = params
+
+	// The ECDSA key would activate at 5, where ed25519 is still required
+	tx := transaction.NewKeyRotation(node, next.Algorithm(), next.PublicKey, 5)
+	if err := tx.SignRotation(node, next, params); err != nil {
+		t.Fatal(err)
+	}
+
+	b := block.NewBlock(1, 0, genesis.Hash, []*transaction.Transaction{tx})
+	if err := b.FinalizeWith(node, params); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ldg.AddBlock(b, ed, node.PublicKey); err == nil {
+		t.Fatal("rotation activating under the wrong algorithm should be rejected")
+	}
+}
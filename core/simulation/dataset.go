@@ -4,16 +4,18 @@ import (
 	"crypto/rand"
 	"fmt"
 
-	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/chain"
 	"github.com/Sai-shashank-2005/aegisq-protocol/core/identity"
 	"github.com/Sai-shashank-2005/aegisq-protocol/core/transaction"
 )
 
 // GenerateSyntheticDataset generates N realistic storage transactions.
 // Each transaction simulates hashing a random file-like input.
+// Data hashes and signatures use the chain parameters p.
 func GenerateSyntheticDataset(
 	count int,
 	node *identity.NodeIdentity,
+	p chain.Params,
 ) ([]*transaction.Transaction, error) {
 
 	var txs []*transaction.Transaction
@@ -28,7 +30,7 @@ func GenerateSyntheticDataset(
 		}
 
 		// Hash raw input (this is what blockchain stores)
-		dataHash := crypto.Hash(rawData)
+		dataHash := p.Hasher.Hash(rawData)
 
 		tx := transaction.NewTransaction(
 			node,
@@ -36,7 +38,7 @@ func GenerateSyntheticDataset(
 			fmt.Sprintf("Synthetic File Upload #%d", i),
 		)
 
-		err = tx.SignWithIdentity(node)
+		err = tx.SignWith(node, p)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,325 @@
+package simulation
+
+import (
+	"container/heap"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/consensus"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/scheduler"
+)
+
+// Latency draws the delay of one message.
+type Latency func(r *rand.Rand) time.Duration
+
+// FixedLatency delays every message by d.
+func FixedLatency(d time.Duration) Latency {
+	return func(*rand.Rand) time.Duration { return d }
+}
+
+// UniformLatency delays messages uniformly between min and max, so
+// messages sent in order may arrive out of order.
+func UniformLatency(min, max time.Duration) Latency {
+	return func(r *rand.Rand) time.Duration {
+		return min + time.Duration(r.Int63n(int64(max-min)+1))
+	}
+}
+
+// ExponentialLatency delays messages by min plus an exponential tail
+// of the given mean, bounded at 20 means.
+func ExponentialLatency(min, mean time.Duration) Latency {
+	return func(r *rand.Rand) time.Duration {
+		tail := r.ExpFloat64()
+		if tail > 20 {
+			tail = 20
+		}
+		return min + time.Duration(tail*float64(mean))
+	}
+}
+
+// Partition splits the network from Start until End: messages between
+// nodes of different groups are lost. Nodes in no group are cut off
+// from every other.
+type Partition struct {
+	Start, End time.Duration
+	Groups     [][]int
+}
+
+// NetConfig describes a simulated network of validators and the
+// faults it suffers. Its runs are deterministic: the same
+// configuration always delivers the same messages in the same order.
+type NetConfig struct {
+	Seed  int64
+	Nodes int
+
+	// Crashed validators, the last of the nodes, never start.
+	Crashed int
+
+	Latency    Latency
+	DropRate   float64
+	Partitions []Partition
+
+	// Heights is the number of heights to finalize.
+	Heights int
+
+	// BaseTimeout is the timeout of the first view at a height; it
+	// doubles with every view.
+	BaseTimeout time.Duration
+
+	// MaxTime bounds the run in simulated time.
+	MaxTime time.Duration
+}
+
+func (c NetConfig) String() string {
+	return fmt.Sprintf("seed=%d nodes=%d crashed=%d drop=%.2f partitions=%d", c.Seed, c.Nodes, c.Crashed, c.DropRate, len(c.Partitions))
+}
+
+// NetResult is the outcome of a simulated run.
+type NetResult struct {
+	// Finalized holds, per node, the hash finalized at every height
+	// from 1, in order.
+	Finalized [][]string
+
+	// Live flags the nodes that were not crashed.
+	Live []bool
+
+	// Time is the simulated time the run ended at.
+	Time time.Duration
+
+	Sent, Delivered, Dropped int
+
+	// MaxView is the highest view any height was finalized in.
+	MaxView int
+}
+
+// CheckSafety returns an error if two nodes finalized different hashes
+// at the same height.
+func (r *NetResult) CheckSafety() error {
+
+	final := make(map[int]string)
+	by := make(map[int]int)
+
+	for i, hashes := range r.Finalized {
+		for h, hash := range hashes {
+
+			first, ok := final[h]
+			if !ok {
+				final[h], by[h] = hash, i
+				continue
+			}
+
+			if hash != first {
+				return fmt.Errorf("height %d finalized as %s by node %d and as %s by node %d", h+1, first, by[h], hash, i)
+			}
+		}
+	}
+
+	return nil
+}
+
+// CheckLiveness returns an error unless every live node finalized
+// heights heights.
+func (r *NetResult) CheckLiveness(heights int) error {
+
+	for i, hashes := range r.Finalized {
+		if r.Live[i] && len(hashes) < heights {
+			return fmt.Errorf("node %d finalized %d of %d heights by %s", i, len(hashes), heights, r.Time)
+		}
+	}
+
+	return nil
+}
+
+// SimulateNetwork runs cfg.Nodes validators over a simulated network
+// until every live one has finalized cfg.Heights heights or cfg.MaxTime
+// has passed. Time is simulated, so a run takes no longer than its
+// events take to process.
+func SimulateNetwork(cfg NetConfig) *NetResult {
+
+	if cfg.Latency == nil {
+		cfg.Latency = FixedLatency(10 * time.Millisecond)
+	}
+
+	if cfg.BaseTimeout <= 0 {
+		cfg.BaseTimeout = time.Second
+	}
+
+	vs := consensus.NewValidatorSet()
+	ids := make([]string, cfg.Nodes)
+
+	for i := range ids {
+		ids[i] = fmt.Sprintf("v%d", i+1)
+		vs.AddValidator(ids[i], []byte(ids[i]))
+	}
+
+	net := &network{
+		cfg:   cfg,
+		rng:   rand.New(rand.NewSource(cfg.Seed)),
+		vs:    vs,
+		sched: scheduler.NewRoundRobinScheduler(vs),
+		index: make(map[string]int),
+	}
+
+	for i, id := range ids {
+		net.index[id] = i
+		net.nodes = append(net.nodes, newNode(net, i, id, i < cfg.Nodes-cfg.Crashed))
+	}
+
+	for _, n := range net.nodes {
+		if n.live {
+			n.enterHeight(1)
+		}
+	}
+
+	for net.queue.Len() > 0 && !net.done() {
+
+		ev := heap.Pop(&net.queue).(*event)
+		if ev.at > cfg.MaxTime {
+			break
+		}
+
+		net.now = ev.at
+		n := net.nodes[ev.node]
+
+		if ev.msg != nil {
+			net.result.Delivered++
+			n.receive(ev.msg)
+		} else {
+			n.timeout(ev.height, ev.view)
+		}
+	}
+
+	res := &net.result
+	res.Time = net.now
+
+	for _, n := range net.nodes {
+		res.Finalized = append(res.Finalized, n.finalized)
+		res.Live = append(res.Live, n.live)
+	}
+
+	return res
+}
+
+// network delivers messages between simulated nodes in simulated
+// time, on a single goroutine.
+type network struct {
+	cfg   NetConfig
+	rng   *rand.Rand
+	vs    *consensus.ValidatorSet
+	sched *scheduler.RoundRobinScheduler
+
+	nodes []*node
+	index map[string]int
+
+	now    time.Duration
+	queue  eventQueue
+	seq    uint64
+	result NetResult
+}
+
+// send delivers m to node to after a drawn latency, unless it is lost
+// to a drop or a partition. Messages to self always arrive, at once.
+func (net *network) send(to int, m *message) {
+
+	net.result.Sent++
+
+	if to == m.From {
+		net.schedule(&event{at: net.now, node: to, msg: m})
+		return
+	}
+
+	if !net.nodes[to].live || net.partitioned(m.From, to) || net.rng.Float64() < net.cfg.DropRate {
+		net.result.Dropped++
+		return
+	}
+
+	net.schedule(&event{at: net.now + net.cfg.Latency(net.rng), node: to, msg: m})
+}
+
+// broadcast sends m to every node, the sender included.
+func (net *network) broadcast(m *message) {
+	for to := range net.nodes {
+		net.send(to, m)
+	}
+}
+
+// timer fires the timeout of node's view at height after d.
+func (net *network) timer(node, height, view int, d time.Duration) {
+	net.schedule(&event{at: net.now + d, node: node, height: height, view: view})
+}
+
+func (net *network) schedule(ev *event) {
+	net.seq++
+	ev.seq = net.seq
+	heap.Push(&net.queue, ev)
+}
+
+func (net *network) partitioned(a, b int) bool {
+
+	for _, p := range net.cfg.Partitions {
+		if net.now >= p.Start && net.now < p.End && group(p, a) != group(p, b) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// group returns the group of node i in p, or a group of its own.
+func group(p Partition, i int) int {
+
+	for g, members := range p.Groups {
+		for _, m := range members {
+			if m == i {
+				return g
+			}
+		}
+	}
+
+	return -1 - i
+}
+
+func (net *network) done() bool {
+
+	for _, n := range net.nodes {
+		if n.live && len(n.finalized) < net.cfg.Heights {
+			return false
+		}
+	}
+
+	return true
+}
+
+// event is a message delivery or, without msg, a timeout. Events at
+// the same time happen in the order they were scheduled.
+type event struct {
+	at   time.Duration
+	seq  uint64
+	node int
+
+	msg          *message
+	height, view int
+}
+
+type eventQueue []*event
+
+func (q eventQueue) Len() int { return len(q) }
+
+func (q eventQueue) Less(i, j int) bool {
+	if q[i].at != q[j].at {
+		return q[i].at < q[j].at
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q eventQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *eventQueue) Push(x interface{}) { *q = append(*q, x.(*event)) }
+
+func (q *eventQueue) Pop() interface{} {
+	old := *q
+	ev := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return ev
+}
@@ -0,0 +1,154 @@
+package simulation
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// randomNet draws a network of 4 to 7 validators, up to f of them
+// crashed, with a random latency distribution, up to 20% of messages
+// dropped and up to two partitions that heal within 30 seconds.
+func randomNet(seed int64) NetConfig {
+
+	r := rand.New(rand.NewSource(seed))
+
+	nodes := 4 + r.Intn(4)
+
+	cfg := NetConfig{
+		Seed:        seed,
+		Nodes:       nodes,
+		Crashed:     r.Intn((nodes-1)/3 + 1),
+		DropRate:    r.Float64() * 0.2,
+		Heights:     5,
+		BaseTimeout: time.Second,
+		MaxTime:     time.Hour,
+	}
+
+	switch r.Intn(3) {
+	case 0:
+		cfg.Latency = FixedLatency(time.Duration(1+r.Intn(200)) * time.Millisecond)
+	case 1:
+		cfg.Latency = UniformLatency(time.Millisecond, time.Duration(1+r.Intn(800))*time.Millisecond)
+	case 2:
+		cfg.Latency = ExponentialLatency(5*time.Millisecond, time.Duration(1+r.Intn(200))*time.Millisecond)
+	}
+
+	for i := r.Intn(3); i > 0; i-- {
+
+		start := time.Duration(r.Intn(10000)) * time.Millisecond
+		p := Partition{Start: start, End: start + time.Duration(1+r.Intn(20000))*time.Millisecond, Groups: make([][]int, 2)}
+
+		for node := 0; node < nodes; node++ {
+			g := r.Intn(2)
+			p.Groups[g] = append(p.Groups[g], node)
+		}
+
+		cfg.Partitions = append(cfg.Partitions, p)
+	}
+
+	return cfg
+}
+
+func TestSimulatedNetworkSafetyAndLiveness(t *testing.T) {
+
+	runs := 2000
+	if testing.Short() {
+		runs = 200
+	}
+
+	var sent, dropped, maxView int
+	var slowest time.Duration
+
+	for seed := int64(1); seed <= int64(runs); seed++ {
+
+		cfg := randomNet(seed)
+		res := SimulateNetwork(cfg)
+
+		if err := res.CheckSafety(); err != nil {
+			t.Fatalf("%s: safety: %v", cfg, err)
+		}
+
+		if err := res.CheckLiveness(cfg.Heights); err != nil {
+			t.Fatalf("%s: liveness: %v", cfg, err)
+		}
+
+		sent += res.Sent
+		dropped += res.Dropped
+
+		if res.MaxView > maxView {
+			maxView = res.MaxView
+		}
+		if res.Time > slowest {
+			slowest = res.Time
+		}
+	}
+
+	t.Logf("%d runs: %d messages, %d lost, finalized by view %d at the latest, slowest run %s", runs, sent, dropped, maxView, slowest)
+}
+
+func TestSimulatedNetworkIsDeterministic(t *testing.T) {
+
+	for seed := int64(1); seed <= 20; seed++ {
+
+		a := SimulateNetwork(randomNet(seed))
+		b := SimulateNetwork(randomNet(seed))
+
+		if !reflect.DeepEqual(a, b) {
+			t.Fatalf("seed %d: runs differ", seed)
+		}
+	}
+}
+
+func TestSimulatedPartitionWithoutQuorumStalls(t *testing.T) {
+
+	cfg := NetConfig{
+		Seed:        1,
+		Nodes:       4,
+		Latency:     UniformLatency(time.Millisecond, 50*time.Millisecond),
+		Heights:     3,
+		BaseTimeout: time.Second,
+		MaxTime:     5 * time.Minute,
+		Partitions:  []Partition{{Start: 0, End: time.Minute, Groups: [][]int{{0, 1}, {2, 3}}}},
+	}
+
+	// Neither half holds a quorum of 3 until the partition heals
+	res := SimulateNetwork(cfg)
+
+	if err := res.CheckSafety(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := res.CheckLiveness(cfg.Heights); err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Time < time.Minute {
+		t.Fatalf("finalized at %s, before the partition healed", res.Time)
+	}
+
+	cfg.Partitions[0].End = cfg.MaxTime
+
+	for i, hashes := range SimulateNetwork(cfg).Finalized {
+		if len(hashes) != 0 {
+			t.Fatalf("node %d finalized %d heights without a quorum", i, len(hashes))
+		}
+	}
+}
+
+func TestSafetyCheckCatchesConflicts(t *testing.T) {
+
+	res := &NetResult{
+		Finalized: [][]string{{"1/0/v2", "2/0/v3"}, {"1/0/v2"}, {"1/0/v2", "2/1/v4"}},
+		Live:      []bool{true, true, true},
+	}
+
+	if res.CheckSafety() == nil {
+		t.Fatal("conflicting hashes at height 2 passed")
+	}
+
+	if res.CheckLiveness(2) == nil {
+		t.Fatal("node 1 finalizing one of two heights passed")
+	}
+}
@@ -0,0 +1,468 @@
+package simulation
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/consensus"
+)
+
+// maxTimeoutDoublings bounds the growth of view timeouts.
+const maxTimeoutDoublings = 6
+
+type msgKind int
+
+const (
+	msgPropose msgKind = iota
+	msgVote
+	msgViewChange
+	msgDecide
+	msgSync
+)
+
+// message is a consensus message between simulated nodes.
+type message struct {
+	Kind   msgKind
+	From   int
+	Height int
+	View   int
+	Hash   string
+
+	// Vote is the vote of a msgVote.
+	Vote consensus.Vote
+
+	// PreparedHash and PreparedView are the sender's lock in a view
+	// change, and the lock a proposal carries on; PreparedView is -1
+	// without one.
+	PreparedHash string
+	PreparedView int
+
+	// Commits are the validators whose commit votes finalized Hash,
+	// in a msgDecide.
+	Commits []string
+}
+
+// node is an honest validator. At every height the leader of a view
+// proposes a block, and the validators prepare and commit it through
+// a consensus.VotePool and FinalityEngine. A validator that saw its
+// proposal prepared is locked on it. A view that times out moves to
+// the next, whose leader proposes the highest prepared block among the
+// view changes of a quorum, so a block committed anywhere is never
+// replaced. Finalized heights are announced with their commit votes,
+// which lets lagging validators catch up. Messages of the current
+// view are sent again every base timeout, so lost ones are made up
+// for.
+type node struct {
+	net   *network
+	index int
+	id    string
+	live  bool
+
+	height int
+	view   int
+
+	// viewStart is when the view began, and sent what this node sent
+	// in it
+	viewStart time.Duration
+	sent      []*message
+
+	pool *consensus.VotePool
+	fe   *consensus.FinalityEngine
+
+	accepted   map[int]string
+	commitSent map[int]bool
+	commits    map[string][]string
+	lockedHash string
+	lockedView int
+
+	// changes are the view changes received per view, and latest the
+	// highest view each validator moved to
+	changes  map[int]map[int]*message
+	latest   map[int]int
+	proposed map[int]bool
+
+	finalized []string
+	certs     [][]string
+
+	// future holds messages for heights not reached yet
+	future    map[int][]*message
+	requested map[int]bool
+	replied   map[[2]int]time.Duration
+}
+
+func newNode(net *network, index int, id string, live bool) *node {
+	return &node{
+		net:     net,
+		index:   index,
+		id:      id,
+		live:    live,
+		future:  make(map[int][]*message),
+		replied: make(map[[2]int]time.Duration),
+	}
+}
+
+// enterHeight starts consensus on height h, unless every height is
+// finalized.
+func (n *node) enterHeight(h int) {
+
+	n.height = h
+	n.view = 0
+
+	if h > n.net.cfg.Heights {
+		return
+	}
+
+	n.pool = consensus.NewVotePool(n.net.vs)
+	n.fe = consensus.NewFinalityEngine(n.pool)
+
+	n.accepted = make(map[int]string)
+	n.commitSent = make(map[int]bool)
+	n.commits = make(map[string][]string)
+	n.lockedHash, n.lockedView = "", -1
+
+	n.changes = make(map[int]map[int]*message)
+	n.latest = make(map[int]int)
+	n.proposed = make(map[int]bool)
+	n.requested = make(map[int]bool)
+
+	n.startView(0)
+
+	buffered := n.future[h]
+	delete(n.future, h)
+
+	for _, m := range buffered {
+		n.receive(m)
+	}
+}
+
+// startView moves to view v, announcing the lock in a view change.
+func (n *node) startView(v int) {
+
+	n.view = v
+	n.viewStart = n.net.now
+	n.sent = nil
+
+	n.net.timer(n.index, n.height, v, n.net.cfg.BaseTimeout)
+
+	if v == 0 {
+		if n.leader(0) {
+			n.propose(0, n.fresh(0), -1)
+		}
+		return
+	}
+
+	n.broadcast(&message{
+		Kind:         msgViewChange,
+		From:         n.index,
+		Height:       n.height,
+		View:         v,
+		PreparedHash: n.lockedHash,
+		PreparedView: n.lockedView,
+	})
+}
+
+// timeout fires every base timeout of a view: it sends the messages
+// of the view again, or gives up on the view once its timeout, the
+// base timeout doubled with every view, has passed.
+func (n *node) timeout(height, view int) {
+
+	if height != n.height || view != n.view || height > n.net.cfg.Heights {
+		return
+	}
+
+	doublings := view
+	if doublings > maxTimeoutDoublings {
+		doublings = maxTimeoutDoublings
+	}
+
+	if n.net.now-n.viewStart < n.net.cfg.BaseTimeout<<doublings {
+
+		for _, m := range n.sent {
+			for to := range n.net.nodes {
+				if to != n.index {
+					n.net.send(to, m)
+				}
+			}
+		}
+
+		n.net.timer(n.index, height, view, n.net.cfg.BaseTimeout)
+		return
+	}
+
+	// Others may have moved on while messages were lost
+	for h := n.height + 1; h <= n.net.cfg.Heights; h++ {
+		if buffered := n.future[h]; len(buffered) > 0 {
+			n.sync(buffered[0].From)
+			break
+		}
+	}
+
+	n.startView(view + 1)
+}
+
+func (n *node) receive(m *message) {
+
+	switch {
+
+	case m.Kind == msgSync:
+		n.decision(m.From, m.Height)
+		return
+
+	case m.Height > n.height:
+		if m.Height <= n.net.cfg.Heights {
+			n.future[m.Height] = append(n.future[m.Height], m)
+			if !n.requested[n.height] {
+				n.requested[n.height] = true
+				n.sync(m.From)
+			}
+		}
+		return
+
+	case m.Height < n.height || n.height > n.net.cfg.Heights:
+		// The sender lags behind
+		if m.Kind != msgDecide {
+			n.decision(m.From, m.Height)
+		}
+		return
+	}
+
+	switch m.Kind {
+	case msgPropose:
+		n.onPropose(m)
+	case msgVote:
+		n.onVote(m)
+	case msgViewChange:
+		n.onViewChange(m)
+	case msgDecide:
+		n.onDecide(m)
+	}
+}
+
+func (n *node) onPropose(m *message) {
+
+	if m.View < n.view || !n.isLeader(m.From, m.View) {
+		return
+	}
+
+	if m.View > n.view {
+		n.startView(m.View)
+	}
+
+	if _, ok := n.accepted[m.View]; ok {
+		return
+	}
+
+	// A locked validator only accepts another block carrying a lock
+	// at least as recent
+	if n.lockedView >= 0 && m.Hash != n.lockedHash && m.PreparedView < n.lockedView {
+		return
+	}
+
+	n.accepted[m.View] = m.Hash
+	n.vote(consensus.Prepare, m.Hash, m.View)
+	n.check(m.Hash, m.View)
+}
+
+func (n *node) onVote(m *message) {
+
+	v := m.Vote
+
+	if err := n.pool.AddVote(v); err != nil {
+		return
+	}
+
+	if v.Type == consensus.Commit {
+		key := fmt.Sprintf("%d/%s", v.View, v.BlockHash)
+		n.commits[key] = append(n.commits[key], v.ValidatorID)
+	}
+
+	n.check(v.BlockHash, v.View)
+}
+
+// check commits to the accepted proposal of the current view once it
+// is prepared, and finalizes a hash once it is committed.
+func (n *node) check(hash string, view int) {
+
+	prepared := n.fe.TryPrepare(n.height, hash, view)
+
+	if prepared && view == n.view && n.accepted[view] == hash && !n.commitSent[view] {
+		n.commitSent[view] = true
+		n.lockedHash, n.lockedView = hash, view
+		n.vote(consensus.Commit, hash, view)
+	}
+
+	if err := n.fe.TryCommit(n.height, hash, view); err == nil {
+		n.finalize(hash, view, n.commits[fmt.Sprintf("%d/%s", view, hash)])
+	}
+}
+
+func (n *node) onViewChange(m *message) {
+
+	if n.changes[m.View] == nil {
+		n.changes[m.View] = make(map[int]*message)
+	}
+	n.changes[m.View][m.From] = m
+
+	if m.View > n.latest[m.From] {
+		n.latest[m.From] = m.View
+	}
+
+	// Follow f+1 validators to a later view: at least one of them is
+	// honest
+	var ahead []int
+	for _, v := range n.latest {
+		if v > n.view {
+			ahead = append(ahead, v)
+		}
+	}
+
+	if f := (n.net.cfg.Nodes - 1) / 3; len(ahead) > f {
+		sort.Sort(sort.Reverse(sort.IntSlice(ahead)))
+		n.startView(ahead[f])
+	}
+
+	n.tryPropose()
+}
+
+// tryPropose proposes in the current view once its leader holds the
+// view changes of a quorum: the most recently prepared block among
+// them, or a new one.
+func (n *node) tryPropose() {
+
+	v := n.view
+
+	if v == 0 || !n.leader(v) || n.proposed[v] || len(n.changes[v]) < consensus.Quorum(n.net.cfg.Nodes) {
+		return
+	}
+
+	hash, prepared := "", -1
+
+	for i := range n.net.nodes {
+		if m := n.changes[v][i]; m != nil && m.PreparedView > prepared {
+			hash, prepared = m.PreparedHash, m.PreparedView
+		}
+	}
+
+	if prepared < 0 {
+		hash = n.fresh(v)
+	}
+
+	n.propose(v, hash, prepared)
+}
+
+func (n *node) propose(view int, hash string, prepared int) {
+
+	n.proposed[view] = true
+
+	n.broadcast(&message{
+		Kind:         msgPropose,
+		From:         n.index,
+		Height:       n.height,
+		View:         view,
+		Hash:         hash,
+		PreparedHash: hash,
+		PreparedView: prepared,
+	})
+}
+
+func (n *node) vote(t consensus.VoteType, hash string, view int) {
+	n.broadcast(&message{
+		Kind:   msgVote,
+		From:   n.index,
+		Height: n.height,
+		View:   view,
+		Hash:   hash,
+		Vote:   consensus.Vote{ValidatorID: n.id, BlockHash: hash, View: view, Type: t},
+	})
+}
+
+// broadcast sends m to every node, and again on every timeout of the
+// view.
+func (n *node) broadcast(m *message) {
+	n.sent = append(n.sent, m)
+	n.net.broadcast(m)
+}
+
+// onDecide finalizes a height on the commit votes of a quorum.
+func (n *node) onDecide(m *message) {
+
+	seen := make(map[string]bool)
+
+	for _, id := range m.Commits {
+		if _, ok := n.net.vs.GetValidator(id); ok {
+			seen[id] = true
+		}
+	}
+
+	if len(seen) >= consensus.Quorum(n.net.cfg.Nodes) {
+		n.finalize(m.Hash, m.View, m.Commits)
+	}
+}
+
+// finalize records hash at the current height, announces it and
+// moves to the next height.
+func (n *node) finalize(hash string, view int, commits []string) {
+
+	commits = append([]string(nil), commits...)
+	sort.Strings(commits)
+
+	n.finalized = append(n.finalized, hash)
+	n.certs = append(n.certs, commits)
+
+	if view > n.net.result.MaxView {
+		n.net.result.MaxView = view
+	}
+
+	decide := &message{Kind: msgDecide, From: n.index, Height: n.height, View: view, Hash: hash, Commits: commits}
+
+	for to := range n.net.nodes {
+		if to != n.index {
+			n.net.send(to, decide)
+		}
+	}
+
+	n.enterHeight(n.height + 1)
+}
+
+// decision sends node to the finalized block at height, at most once
+// per base timeout.
+func (n *node) decision(to, height int) {
+
+	if height < 1 || height > len(n.finalized) {
+		return
+	}
+
+	key := [2]int{to, height}
+	if last, ok := n.replied[key]; ok && n.net.now-last < n.net.cfg.BaseTimeout {
+		return
+	}
+	n.replied[key] = n.net.now
+
+	n.net.send(to, &message{
+		Kind:    msgDecide,
+		From:    n.index,
+		Height:  height,
+		Hash:    n.finalized[height-1],
+		Commits: n.certs[height-1],
+	})
+}
+
+// sync asks node to for the block finalized at the current height.
+func (n *node) sync(to int) {
+	n.net.send(to, &message{Kind: msgSync, From: n.index, Height: n.height})
+}
+
+func (n *node) leader(view int) bool {
+	return n.isLeader(n.index, view)
+}
+
+func (n *node) isLeader(index, view int) bool {
+	id, err := n.net.sched.GetLeader(n.height, view)
+	return err == nil && n.net.index[id] == index
+}
+
+// fresh names a new block proposed by this node.
+func (n *node) fresh(view int) string {
+	return fmt.Sprintf("%d/%d/%s", n.height, view, n.id)
+}
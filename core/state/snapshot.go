@@ -0,0 +1,213 @@
+package state
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
+)
+
+// SnapshotFormat is the chunk layout written: entries in key order as
+// JSON lines.
+const SnapshotFormat = 1
+
+// DefaultChunkSize is the size a chunk is cut at.
+const DefaultChunkSize = 1 << 20
+
+// MaxChunkSize bounds a chunk accepted from a peer.
+const MaxChunkSize = 16 << 20
+
+// Manifest describes a snapshot of the state after the block at
+// Height. Chunks holds the chain hash of every chunk, so each chunk
+// can be checked as it arrives; StateRoot is checked once the state
+// is rebuilt and must match the root committed in the block header.
+type Manifest struct {
+	Format    int      `json:"format"`
+	Height    uint64   `json:"height"`
+	BlockHash []byte   `json:"block_hash"`
+	StateRoot []byte   `json:"state_root"`
+	Entries   int      `json:"entries"`
+	Chunks    [][]byte `json:"chunks"`
+}
+
+// Snapshot cuts the state into chunks of about chunkSize bytes. The
+// caller fills in the manifest's BlockHash.
+func (s *State) Snapshot(h crypto.Hasher, chunkSize int) (*Manifest, [][]byte) {
+
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+
+	height := s.Height()
+	entries := s.entries()
+
+	m := &Manifest{
+		Format:    SnapshotFormat,
+		Height:    height,
+		StateRoot: root(h, height, entries),
+		Entries:   len(entries),
+	}
+
+	var chunks [][]byte
+	var buf bytes.Buffer
+
+	cut := func() {
+		chunk := append([]byte(nil), buf.Bytes()...)
+		chunks = append(chunks, chunk)
+		m.Chunks = append(m.Chunks, h.Hash(chunk))
+		buf.Reset()
+	}
+
+	for _, e := range entries {
+
+		line := append(e.encode(), '\n')
+
+		if buf.Len() > 0 && buf.Len()+len(line) > chunkSize {
+			cut()
+		}
+
+		buf.Write(line)
+	}
+
+	if buf.Len() > 0 {
+		cut()
+	}
+
+	return m, chunks
+}
+
+// Restorer rebuilds a state from the chunks of a manifest, which may
+// arrive from untrusted peers in any order.
+type Restorer struct {
+	manifest *Manifest
+	hasher   crypto.Hasher
+	chunks   [][]byte
+	missing  int
+}
+
+// NewRestorer starts restoring the snapshot m under the chain hash h.
+func NewRestorer(m *Manifest, h crypto.Hasher) (*Restorer, error) {
+
+	if m.Format != SnapshotFormat {
+		return nil, fmt.Errorf("snapshot format %d is not supported (want %d)", m.Format, SnapshotFormat)
+	}
+
+	if m.Height == 0 {
+		return nil, errors.New("snapshot of genesis")
+	}
+
+	for i, sum := range m.Chunks {
+		if len(sum) != h.Size() {
+			return nil, fmt.Errorf("chunk %d: hash is not a %s digest", i, h.Algorithm())
+		}
+	}
+
+	return &Restorer{
+		manifest: m,
+		hasher:   h,
+		chunks:   make([][]byte, len(m.Chunks)),
+		missing:  len(m.Chunks),
+	}, nil
+}
+
+// Add checks chunk index against the manifest and keeps it. A chunk
+// that does not match should be fetched again from another peer.
+func (r *Restorer) Add(index int, chunk []byte) error {
+
+	if index < 0 || index >= len(r.chunks) {
+		return fmt.Errorf("chunk %d: snapshot has %d chunks", index, len(r.chunks))
+	}
+
+	if string(r.hasher.Hash(chunk)) != string(r.manifest.Chunks[index]) {
+		return fmt.Errorf("chunk %d: hash mismatch", index)
+	}
+
+	if r.chunks[index] == nil {
+		r.missing--
+	}
+
+	r.chunks[index] = chunk
+	return nil
+}
+
+// Missing returns the indexes of the chunks not added yet.
+func (r *Restorer) Missing() []int {
+
+	var missing []int
+
+	for i, c := range r.chunks {
+		if c == nil {
+			missing = append(missing, i)
+		}
+	}
+
+	return missing
+}
+
+// Finish rebuilds the state from every chunk and checks it against
+// the manifest's state root.
+func (r *Restorer) Finish() (*State, error) {
+
+	if r.missing > 0 {
+		return nil, fmt.Errorf("%d of %d chunks missing", r.missing, len(r.chunks))
+	}
+
+	s := &State{
+		height:     r.manifest.Height,
+		records:    make(map[string]*Record),
+		validators: make(map[string]*Validator),
+	}
+
+	count := 0
+	last := ""
+
+	for i, chunk := range r.chunks {
+
+		scan := bufio.NewScanner(bytes.NewReader(chunk))
+		scan.Buffer(nil, MaxChunkSize)
+
+		for scan.Scan() {
+
+			var e entry
+			if err := json.Unmarshal(scan.Bytes(), &e); err != nil {
+				return nil, fmt.Errorf("chunk %d: %w", i, err)
+			}
+
+			// Strict key order rules out duplicates and makes the
+			// rebuilt root independent of how the snapshot was cut
+			if count > 0 && e.Key <= last {
+				return nil, fmt.Errorf("chunk %d: entry %q out of order", i, e.Key)
+			}
+			last = e.Key
+
+			switch {
+			case e.Record != nil && e.Validator == nil && e.Key == "r/"+e.Record.DataHash:
+				s.records[e.Record.DataHash] = e.Record
+			case e.Validator != nil && e.Record == nil && e.Key == "v/"+e.Validator.NodeID:
+				s.validators[e.Validator.NodeID] = e.Validator
+			default:
+				return nil, fmt.Errorf("chunk %d: malformed entry %q", i, e.Key)
+			}
+
+			count++
+		}
+
+		if err := scan.Err(); err != nil {
+			return nil, fmt.Errorf("chunk %d: %w", i, err)
+		}
+	}
+
+	if count != r.manifest.Entries {
+		return nil, fmt.Errorf("snapshot holds %d entries, manifest announces %d", count, r.manifest.Entries)
+	}
+
+	// Entries are re-encoded, so only their content is committed to
+	if string(root(r.hasher, s.height, s.entries())) != string(r.manifest.StateRoot) {
+		return nil, errors.New("restored state does not match the snapshot's state root")
+	}
+
+	return s, nil
+}
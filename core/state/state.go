@@ -0,0 +1,307 @@
+// Package state holds the application state the chain carries: the
+// notarization records of committed transactions and the validator
+// keys, including scheduled rotations.
+//
+// State is a deterministic function of the blocks applied to it, so
+// every node computes the same Root at a height. Chains with a state
+// root interval commit that root in the header of every block at a
+// multiple of the interval (see chain.Params.CommitsState), which lets
+// a node restore a snapshot from an untrusted peer.
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/block"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/consensus"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
+)
+
+// Record notarizes a data hash: the first committed transaction that
+// carried it.
+type Record struct {
+	DataHash  string `json:"data_hash"`
+	SenderID  string `json:"sender_id"`
+	Metadata  string `json:"metadata"`
+	Timestamp int64  `json:"timestamp"`
+	Height    uint64 `json:"height"`
+	Index     int    `json:"index"`
+}
+
+// Validator is a validator key active at the state height, with the
+// rotation it has scheduled above that height, if any.
+type Validator struct {
+	NodeID    string    `json:"node_id"`
+	Algorithm string    `json:"algorithm"`
+	PublicKey []byte    `json:"public_key"`
+	Pending   *Rotation `json:"pending,omitempty"`
+}
+
+// Rotation is a validator key that activates at From.
+type Rotation struct {
+	From      int    `json:"from"`
+	Algorithm string `json:"algorithm"`
+	PublicKey []byte `json:"public_key"`
+}
+
+// State is the application state after the block at Height. It is
+// safe for concurrent use.
+type State struct {
+	mu         sync.RWMutex
+	height     uint64
+	records    map[string]*Record
+	validators map[string]*Validator
+}
+
+// New returns the state at genesis, holding only the genesis
+// validators.
+func New(validators []Validator) (*State, error) {
+
+	s := &State{
+		records:    make(map[string]*Record),
+		validators: make(map[string]*Validator),
+	}
+
+	for _, v := range validators {
+
+		if _, exists := s.validators[v.NodeID]; exists {
+			return nil, fmt.Errorf("validator %s listed twice", v.NodeID)
+		}
+
+		v := v
+		s.validators[v.NodeID] = &v
+	}
+
+	return s, nil
+}
+
+// Height returns the height of the last applied block.
+func (s *State) Height() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.height
+}
+
+// Record returns the record of dataHash.
+func (s *State) Record(dataHash string) (Record, bool) {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r, ok := s.records[dataHash]
+	if !ok {
+		return Record{}, false
+	}
+
+	return *r, true
+}
+
+// Records returns the number of notarized data hashes.
+func (s *State) Records() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.records)
+}
+
+// Apply advances the state by b, the block after Height. Blocks must
+// have been validated: Apply only records their effects.
+func (s *State) Apply(b *block.Block) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.apply(b)
+}
+
+func (s *State) apply(b *block.Block) error {
+
+	height := uint64(b.Index)
+
+	if height != s.height+1 {
+		return fmt.Errorf("block %d applied to state at height %d", b.Index, s.height)
+	}
+
+	// Rotations scheduled for this height take effect
+	for _, v := range s.validators {
+		if v.Pending != nil && v.Pending.From <= b.Index {
+			v.Algorithm = v.Pending.Algorithm
+			v.PublicKey = v.Pending.PublicKey
+			v.Pending = nil
+		}
+	}
+
+	for i, tx := range b.Transactions {
+
+		if tx.IsKeyRotation() {
+
+			v, ok := s.validators[tx.SenderID]
+			if !ok {
+				return fmt.Errorf("block %d: key rotation by unknown validator %s", b.Index, tx.SenderID)
+			}
+
+			v.Pending = &Rotation{
+				From:      tx.Rotation.ActivationHeight,
+				Algorithm: tx.NewKeyAlgorithm(),
+				PublicKey: tx.Rotation.NewPublicKey,
+			}
+			continue
+		}
+
+		// The first notarization of a data hash stands
+		if _, exists := s.records[tx.DataHash]; exists {
+			continue
+		}
+
+		s.records[tx.DataHash] = &Record{
+			DataHash:  tx.DataHash,
+			SenderID:  tx.SenderID,
+			Metadata:  tx.Metadata,
+			Timestamp: tx.Timestamp,
+			Height:    height,
+			Index:     i,
+		}
+	}
+
+	s.height = height
+	return nil
+}
+
+// RootAfter returns the root the state would have after b, without
+// applying it. Producers use it to commit the root in b's header.
+func (s *State) RootAfter(b *block.Block, h crypto.Hasher) ([]byte, error) {
+
+	next := s.Clone()
+
+	if err := next.apply(b); err != nil {
+		return nil, err
+	}
+
+	return next.Root(h), nil
+}
+
+// Clone returns an independent copy of the state.
+func (s *State) Clone() *State {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c := &State{
+		height:     s.height,
+		records:    make(map[string]*Record, len(s.records)),
+		validators: make(map[string]*Validator, len(s.validators)),
+	}
+
+	for k, r := range s.records {
+		r := *r
+		c.records[k] = &r
+	}
+
+	for k, v := range s.validators {
+		v := *v
+		if v.Pending != nil {
+			p := *v.Pending
+			v.Pending = &p
+		}
+		c.validators[k] = &v
+	}
+
+	return c
+}
+
+// ValidatorSet returns the validator keys for verifying the blocks
+// above the state height. Keys of earlier heights are not kept.
+func (s *State) ValidatorSet() (*consensus.ValidatorSet, map[string]string, error) {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	vs := consensus.NewValidatorSet()
+	algorithms := make(map[string]string)
+
+	for id, v := range s.validators {
+
+		if err := vs.AddValidator(id, v.PublicKey); err != nil {
+			return nil, nil, err
+		}
+
+		if v.Pending != nil {
+			if err := vs.RotateKey(id, v.Pending.PublicKey, v.Pending.From); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		algorithms[id] = v.Algorithm
+	}
+
+	return vs, algorithms, nil
+}
+
+//
+// ==============================
+// ROOT
+// ==============================
+//
+
+// entry is one state item in its canonical encoding. Keys order
+// entries: "r/" and a data hash for records, "v/" and a node ID for
+// validators.
+type entry struct {
+	Key       string     `json:"key"`
+	Record    *Record    `json:"record,omitempty"`
+	Validator *Validator `json:"validator,omitempty"`
+}
+
+// entries returns every item in key order.
+func (s *State) entries() []entry {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]entry, 0, len(s.records)+len(s.validators))
+
+	for k, r := range s.records {
+		list = append(list, entry{Key: "r/" + k, Record: r})
+	}
+
+	for k, v := range s.validators {
+		list = append(list, entry{Key: "v/" + k, Validator: v})
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Key < list[j].Key })
+
+	return list
+}
+
+// encode returns the canonical encoding of e.
+func (e entry) encode() []byte {
+
+	// Entries hold only strings, integers and byte slices
+	data, err := json.Marshal(e)
+	if err != nil {
+		panic(err)
+	}
+
+	return data
+}
+
+// Root commits to the whole state: the Merkle root, under the chain
+// hash h, of its entries' encodings in key order, with the height as
+// the first leaf.
+func (s *State) Root(h crypto.Hasher) []byte {
+	return root(h, s.Height(), s.entries())
+}
+
+func root(h crypto.Hasher, height uint64, entries []entry) []byte {
+
+	leaves := make([][]byte, 0, len(entries)+1)
+	leaves = append(leaves, h.Hash([]byte(fmt.Sprintf("height/%d", height))))
+
+	for _, e := range entries {
+		leaves = append(leaves, h.Hash(e.encode()))
+	}
+
+	return block.ComputeMerkleRootWith(h, leaves)
+}
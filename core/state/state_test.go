@@ -0,0 +1,295 @@
+package state
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/block"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/chain"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/identity"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/transaction"
+)
+
+var hasher = chain.Default.Hasher
+
+// testState returns the genesis state of two validators, with the
+// first one's identity.
+func testState(t *testing.T) (*State, *identity.NodeIdentity) {
+
+	var validators []Validator
+	var first *identity.NodeIdentity
+
+	for _, id := range []string{"validator-1", "validator-2"} {
+
+		node, err := identity.NewNodeIdentity(id, &crypto.Ed25519Signer{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if first == nil {
+			first = node
+		}
+
+		validators = append(validators, Validator{NodeID: id, Algorithm: node.Algorithm(), PublicKey: node.PublicKey})
+	}
+
+	s, err := New(validators)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s, first
+}
+
+// applyBlocks applies count blocks of two transactions each on the
+// state height. Block validity is not checked by the state.
+func applyBlocks(t *testing.T, s *State, node *identity.NodeIdentity, count int) {
+
+	for i := 0; i < count; i++ {
+
+		h := int(s.Height()) + 1
+
+		b := block.NewBlock(h, 0, nil, []*transaction.Transaction{
+			transaction.NewTransaction(node, fmt.Sprintf("data-%d-0", h), "meta"),
+			transaction.NewTransaction(node, fmt.Sprintf("data-%d-1", h), ""),
+		})
+
+		if err := s.Apply(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestApplyRecordsFirstNotarization(t *testing.T) {
+
+	s, node := testState(t)
+	applyBlocks(t, s, node, 2)
+
+	// A data hash notarized again keeps its first record
+	b := block.NewBlock(3, 0, nil, []*transaction.Transaction{
+		transaction.NewTransaction(node, "data-1-0", "again"),
+	})
+
+	if err := s.Apply(b); err != nil {
+		t.Fatal(err)
+	}
+
+	r, ok := s.Record("data-1-0")
+	if !ok || r.Height != 1 || r.Index != 0 || r.Metadata != "meta" {
+		t.Fatalf("unexpected record: %+v", r)
+	}
+
+	if s.Records() != 4 || s.Height() != 3 {
+		t.Fatalf("state holds %d records at height %d", s.Records(), s.Height())
+	}
+
+	if err := s.Apply(block.NewBlock(5, 0, nil, nil)); err == nil {
+		t.Fatal("a block skipping a height should be rejected")
+	}
+}
+
+func TestRootIsDeterministic(t *testing.T) {
+
+	s, node := testState(t)
+	applyBlocks(t, s, node, 3)
+
+	root := s.Root(hasher)
+
+	if len(root) != hasher.Size() {
+		t.Fatalf("root is %d bytes", len(root))
+	}
+
+	c := s.Clone()
+	if string(c.Root(hasher)) != string(root) {
+		t.Fatal("a clone should have the same root")
+	}
+
+	next := block.NewBlock(4, 0, nil, []*transaction.Transaction{
+		transaction.NewTransaction(node, "data-4-0", ""),
+	})
+
+	after, err := s.RootAfter(next, hasher)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// RootAfter leaves the state as it was
+	if string(s.Root(hasher)) != string(root) || s.Height() != 3 {
+		t.Fatal("RootAfter changed the state")
+	}
+
+	if err := c.Apply(next); err != nil {
+		t.Fatal(err)
+	}
+
+	if string(c.Root(hasher)) != string(after) {
+		t.Fatal("RootAfter differs from the root after applying")
+	}
+
+	// The height is committed, even by an empty block
+	if err := s.Apply(next); err != nil {
+		t.Fatal(err)
+	}
+	empty, _ := s.RootAfter(block.NewBlock(5, 0, nil, nil), hasher)
+
+	if string(empty) == string(s.Root(hasher)) {
+		t.Fatal("root should commit to the height")
+	}
+}
+
+func TestApplyPromotesRotation(t *testing.T) {
+
+	s, node := testState(t)
+
+	next, err := identity.NewNodeIdentity("validator-1", &crypto.Ed25519Signer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotation := transaction.NewKeyRotation(node, next.Algorithm(), next.PublicKey, 3)
+
+	if err := s.Apply(block.NewBlock(1, 0, nil, []*transaction.Transaction{rotation})); err != nil {
+		t.Fatal(err)
+	}
+
+	vs, _, err := s.ValidatorSet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if key, _ := vs.KeyAt("validator-1", 2); string(key) != string(node.PublicKey) {
+		t.Fatal("old key should hold below the activation height")
+	}
+
+	if key, _ := vs.KeyAt("validator-1", 3); string(key) != string(next.PublicKey) {
+		t.Fatal("new key should hold from the activation height")
+	}
+
+	applyBlocks(t, s, node, 2)
+
+	if v := s.validators["validator-1"]; v.Pending != nil || string(v.PublicKey) != string(next.PublicKey) {
+		t.Fatalf("rotation not promoted at its height: %+v", v)
+	}
+}
+
+func TestSnapshotRestore(t *testing.T) {
+
+	s, node := testState(t)
+	applyBlocks(t, s, node, 20)
+
+	// Small chunks, so the snapshot spans several
+	m, chunks := s.Snapshot(hasher, 512)
+
+	if len(chunks) < 3 || m.Entries != 42 {
+		t.Fatalf("snapshot of %d entries in %d chunks", m.Entries, len(chunks))
+	}
+
+	if string(m.StateRoot) != string(s.Root(hasher)) {
+		t.Fatal("manifest root differs from the state root")
+	}
+
+	r, err := NewRestorer(m, hasher)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Chunks may arrive in any order
+	for i := len(chunks) - 1; i >= 0; i-- {
+
+		if err := r.Add(i, append([]byte("x"), chunks[i]...)); err == nil {
+			t.Fatal("a tampered chunk should be rejected")
+		}
+
+		if _, err := r.Finish(); err == nil {
+			t.Fatal("finishing with missing chunks should fail")
+		}
+
+		if err := r.Add(i, chunks[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(r.Missing()) != 0 {
+		t.Fatalf("chunks %v still missing", r.Missing())
+	}
+
+	restored, err := r.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if restored.Height() != 20 || restored.Records() != 40 {
+		t.Fatalf("restored %d records at height %d", restored.Records(), restored.Height())
+	}
+
+	if string(restored.Root(hasher)) != string(m.StateRoot) {
+		t.Fatal("restored state has another root")
+	}
+}
+
+func TestRestoreRejects(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		mutate func(m *Manifest, chunks [][]byte) [][]byte
+	}{
+		{"wrong root", func(m *Manifest, chunks [][]byte) [][]byte {
+			m.StateRoot = hasher.Hash([]byte("other"))
+			return chunks
+		}},
+		{"wrong height", func(m *Manifest, chunks [][]byte) [][]byte {
+			m.Height++
+			return chunks
+		}},
+		{"entries out of order", func(m *Manifest, chunks [][]byte) [][]byte {
+			chunks[0], chunks[1] = chunks[1], chunks[0]
+			return chunks
+		}},
+		{"entry missing", func(m *Manifest, chunks [][]byte) [][]byte {
+			return chunks[1:]
+		}},
+		{"entry count", func(m *Manifest, chunks [][]byte) [][]byte {
+			m.Entries++
+			return chunks
+		}},
+		{"malformed entry", func(m *Manifest, chunks [][]byte) [][]byte {
+			chunks[0] = append([]byte(`{"key":"x/1"}`+"\n"), chunks[0]...)
+			return chunks
+		}},
+	}
+
+	for _, test := range tests {
+
+		s, node := testState(t)
+		applyBlocks(t, s, node, 10)
+
+		m, chunks := s.Snapshot(hasher, 256)
+		chunks = test.mutate(m, chunks)
+
+		// The chunk hashes match, so only Finish can tell
+		m.Chunks = nil
+		for _, chunk := range chunks {
+			m.Chunks = append(m.Chunks, hasher.Hash(chunk))
+		}
+
+		r, err := NewRestorer(m, hasher)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i, chunk := range chunks {
+			if err := r.Add(i, chunk); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if _, err := r.Finish(); err == nil {
+			t.Fatalf("%s: snapshot should be rejected", test.name)
+		}
+	}
+
+	if _, err := NewRestorer(&Manifest{Format: SnapshotFormat + 1, Height: 1}, hasher); err == nil {
+		t.Fatal("an unknown snapshot format should be rejected")
+	}
+}
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/block"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/consensus"
+	"go.etcd.io/bbolt"
+)
+
+// ErrCommitNotFound is returned for a block stored without its commit
+// certificate: blocks from before certificates were kept, or the base
+// of a state-synced database.
+var ErrCommitNotFound = errors.New("commit certificate not found")
+
+//
+// ==============================
+// COMMITTED BLOCKS
+// ==============================
+//
+
+// SaveCommittedBlock stores b as the new tip together with c, the
+// certificate that committed it, in one transaction.
+func (db *DB) SaveCommittedBlock(b *block.Block, c *consensus.Certificate) error {
+
+	if c.Height != b.Index || string(c.BlockHash) != string(b.Hash) {
+		return fmt.Errorf("certificate for height %d does not commit block %d", c.Height, b.Index)
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	return db.conn.Update(func(tx *bbolt.Tx) error {
+
+		if err := db.putBlock(tx, b); err != nil {
+			return err
+		}
+
+		return tx.Bucket(CommitsBucket).Put(uint64ToBytes(uint64(b.Index)), data)
+	})
+}
+
+// Commit returns the commit certificate of the block at height.
+func (db *DB) Commit(height uint64) (*consensus.Certificate, error) {
+
+	var c consensus.Certificate
+
+	err := db.conn.View(func(tx *bbolt.Tx) error {
+
+		data := tx.Bucket(CommitsBucket).Get(uint64ToBytes(height))
+		if data == nil {
+			return ErrCommitNotFound
+		}
+
+		return json.Unmarshal(data, &c)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
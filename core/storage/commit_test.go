@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/consensus"
+)
+
+func TestCommittedBlocks(t *testing.T) {
+
+	src, blocks := openTestChain(t, 4)
+
+	// Blocks saved without a certificate have none
+	if _, err := src.Commit(2); !errors.Is(err, ErrCommitNotFound) {
+		t.Fatal("expected no certificate:", err)
+	}
+
+	// Both databases share the test genesis. Storage does not check
+	// the votes, so empty certificates do.
+	db, _ := openTestChain(t, 0)
+
+	wrong := consensus.NewCertificate(1, 0, blocks[1].Hash)
+	if err := db.SaveCommittedBlock(blocks[0], wrong); err == nil {
+		t.Fatal("certificate of another block should be rejected")
+	}
+
+	if h, _ := db.GetLatestHeight(); h != 0 {
+		t.Fatal("rejected block was stored")
+	}
+
+	for _, b := range blocks {
+		if err := db.SaveCommittedBlock(b, consensus.NewCertificate(b.Index, b.View, b.Hash)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c, err := db.Commit(3)
+	if err != nil || c.Height != 3 || string(c.BlockHash) != string(blocks[2].Hash) {
+		t.Fatalf("unexpected certificate %v (%v)", c, err)
+	}
+
+	// Rolling back drops the certificates of the removed blocks
+	if _, err := db.Rollback(2); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.Commit(3); !errors.Is(err, ErrCommitNotFound) {
+		t.Fatal("certificate of a rolled back block should be gone:", err)
+	}
+
+	if _, err := db.Commit(2); err != nil {
+		t.Fatal(err)
+	}
+}
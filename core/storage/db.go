@@ -4,9 +4,11 @@ import (
 	"encoding/binary"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/Sai-shashank-2005/aegisq-protocol/core/block"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
 	"go.etcd.io/bbolt"
 )
 
@@ -15,10 +17,20 @@ var (
 	BlocksBucket    = []byte("blocks")
 	HashIndexBucket = []byte("block_hash_index")
 	TxIndexBucket   = []byte("tx_index")
+	CommitsBucket   = []byte("commits")
+
+	SnapshotsBucket      = []byte("snapshots")
+	SnapshotChunksBucket = []byte("snapshot_chunks")
 )
 
+// txIndexFirstKey marks a database whose transaction index always
+// points at the first block including a transaction.
+var txIndexFirstKey = []byte("tx_index_first")
+
 type DB struct {
-	conn *bbolt.DB
+	conn        *bbolt.DB
+	hasher      crypto.Hasher
+	genesisHash []byte
 }
 
 func Open(path string) (*DB, error) {
@@ -26,6 +38,9 @@ func Open(path string) (*DB, error) {
 	db, err := bbolt.Open(path, 0600, &bbolt.Options{
 		Timeout: 1 * time.Second,
 	})
+	if errors.Is(err, bbolt.ErrTimeout) {
+		return nil, fmt.Errorf("%s is locked by another process, e.g. a running node: %w", path, err)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -37,6 +52,9 @@ func Open(path string) (*DB, error) {
 			BlocksBucket,
 			HashIndexBucket,
 			TxIndexBucket,
+			CommitsBucket,
+			SnapshotsBucket,
+			SnapshotChunksBucket,
 		}
 
 		for _, b := range buckets {
@@ -46,6 +64,13 @@ func Open(path string) (*DB, error) {
 			}
 		}
 
+		// A database started empty indexes every transaction at its
+		// first block; older ones may point at a later repeat
+		meta := tx.Bucket(MetaBucket)
+		if meta.Get([]byte("latest_height")) == nil && meta.Get(txIndexFirstKey) == nil {
+			return meta.Put(txIndexFirstKey, []byte{1})
+		}
+
 		return nil
 	})
 
@@ -72,75 +97,160 @@ func bytesToUint64(b []byte) uint64 {
 
 //
 // ==============================
-// SAVE BLOCK
+// CHAIN HASH
 // ==============================
 //
 
-func (db *DB) SaveBlock(b *block.Block) error {
+// UseHasher binds the database to the chain hash h. A fresh database
+// records h; an existing one must have been written with the same
+// algorithm. Databases that predate hash agility are SHA3-256.
+func (db *DB) UseHasher(h crypto.Hasher) error {
 
-	return db.conn.Update(func(tx *bbolt.Tx) error {
+	err := db.conn.Update(func(tx *bbolt.Tx) error {
 
-		blocks := tx.Bucket(BlocksBucket)
-		hashIndex := tx.Bucket(HashIndexBucket)
 		meta := tx.Bucket(MetaBucket)
-		txIndex := tx.Bucket(TxIndexBucket)
 
-		// Prevent duplicate block
-		if hashIndex.Get(b.Hash) != nil {
-			return errors.New("block already exists")
+		stored := meta.Get([]byte("hash_algorithm"))
+
+		if stored == nil && meta.Get([]byte("latest_height")) != nil {
+			stored = []byte(crypto.DefaultHashAlgorithm)
 		}
 
-		data, err := json.Marshal(b)
-		if err != nil {
-			return err
+		if stored != nil && string(stored) != h.Algorithm() {
+			return fmt.Errorf("database uses %s, chain uses %s", stored, h.Algorithm())
 		}
 
-		heightKey := uint64ToBytes(uint64(b.Index))
+		return meta.Put([]byte("hash_algorithm"), []byte(h.Algorithm()))
+	})
+
+	if err != nil {
+		return err
+	}
 
-		// Store block by height
-		if err := blocks.Put(heightKey, data); err != nil {
-			return err
+	db.hasher = h
+	return nil
+}
+
+// UseGenesis anchors the database to the genesis document with the
+// canonical hash genesisHash. A fresh database records it; an existing
+// one must have been created from the same genesis. Databases written
+// before genesis anchoring cannot be anchored after the fact.
+func (db *DB) UseGenesis(genesisHash []byte) error {
+
+	err := db.conn.Update(func(tx *bbolt.Tx) error {
+
+		meta := tx.Bucket(MetaBucket)
+
+		stored := meta.Get([]byte("genesis_hash"))
+
+		if stored == nil && meta.Get([]byte("latest_height")) != nil {
+			return errors.New("database predates genesis anchoring; start from a fresh database")
 		}
 
-		// Index block hash → height
-		if err := hashIndex.Put(b.Hash, heightKey); err != nil {
-			return err
+		if stored != nil && string(stored) != string(genesisHash) {
+			return fmt.Errorf("database belongs to genesis %x, not %x", stored, genesisHash)
 		}
 
-		// Index transactions
-		for i, txObj := range b.Transactions {
+		return meta.Put([]byte("genesis_hash"), genesisHash)
+	})
 
-			txKey := []byte(txObj.DataHash)
+	if err != nil {
+		return err
+	}
 
-			indexData := struct {
-				Height uint64
-				Index  int
-			}{
-				Height: uint64(b.Index),
-				Index:  i,
-			}
+	db.genesisHash = genesisHash
+	return nil
+}
 
-			indexBytes, err := json.Marshal(indexData)
-			if err != nil {
-				return err
-			}
+//
+// ==============================
+// SAVE BLOCK
+// ==============================
+//
 
-			if err := txIndex.Put(txKey, indexBytes); err != nil {
-				return err
-			}
+func (db *DB) SaveBlock(b *block.Block) error {
+
+	return db.conn.Update(func(tx *bbolt.Tx) error {
+		return db.putBlock(tx, b)
+	})
+}
+
+// putBlock stores b as the new tip with its index entries.
+func (db *DB) putBlock(tx *bbolt.Tx, b *block.Block) error {
+
+	blocks := tx.Bucket(BlocksBucket)
+	hashIndex := tx.Bucket(HashIndexBucket)
+	meta := tx.Bucket(MetaBucket)
+	txIndex := tx.Bucket(TxIndexBucket)
+
+	if db.hasher != nil && len(b.Hash) != db.hasher.Size() {
+		return fmt.Errorf("block hash is not a %s digest", db.hasher.Algorithm())
+	}
+
+	if db.genesisHash != nil && b.Index == 1 && string(b.PreviousHash) != string(db.genesisHash) {
+		return errors.New("first block is not anchored to genesis")
+	}
+
+	// Prevent duplicate block
+	if hashIndex.Get(b.Hash) != nil {
+		return errors.New("block already exists")
+	}
+
+	data, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+
+	heightKey := uint64ToBytes(uint64(b.Index))
+
+	// Store block by height
+	if err := blocks.Put(heightKey, data); err != nil {
+		return err
+	}
+
+	// Index block hash → height
+	if err := hashIndex.Put(b.Hash, heightKey); err != nil {
+		return err
+	}
+
+	// Index transactions. The first block to include a DataHash keeps
+	// the entry, as it does in the state
+	for i, txObj := range b.Transactions {
+
+		txKey := []byte(txObj.DataHash)
+
+		if txIndex.Get(txKey) != nil {
+			continue
 		}
 
-		// Update metadata
-		if err := meta.Put([]byte("latest_height"), heightKey); err != nil {
+		indexData := struct {
+			Height uint64
+			Index  int
+		}{
+			Height: uint64(b.Index),
+			Index:  i,
+		}
+
+		indexBytes, err := json.Marshal(indexData)
+		if err != nil {
 			return err
 		}
 
-		if err := meta.Put([]byte("latest_hash"), b.Hash); err != nil {
+		if err := txIndex.Put(txKey, indexBytes); err != nil {
 			return err
 		}
+	}
 
-		return nil
-	})
+	// Update metadata
+	if err := meta.Put([]byte("latest_height"), heightKey); err != nil {
+		return err
+	}
+
+	if err := meta.Put([]byte("latest_hash"), b.Hash); err != nil {
+		return err
+	}
+
+	return nil
 }
 
 //
@@ -0,0 +1,338 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/block"
+	"go.etcd.io/bbolt"
+)
+
+//
+// ==============================
+// GENESIS DOCUMENT
+// ==============================
+//
+
+// SaveGenesisDocument keeps the genesis document the database is
+// anchored to, so offline tools can validate the chain without the
+// node's genesis file or keys. The first document stored is kept.
+func (db *DB) SaveGenesisDocument(doc []byte) error {
+
+	return db.conn.Update(func(tx *bbolt.Tx) error {
+
+		meta := tx.Bucket(MetaBucket)
+
+		if meta.Get([]byte("genesis")) != nil {
+			return nil
+		}
+
+		return meta.Put([]byte("genesis"), doc)
+	})
+}
+
+// GenesisDocument returns the stored genesis document and the genesis
+// hash the database is anchored to; either is nil when not recorded.
+func (db *DB) GenesisDocument() (doc []byte, genesisHash []byte, err error) {
+
+	err = db.conn.View(func(tx *bbolt.Tx) error {
+
+		meta := tx.Bucket(MetaBucket)
+
+		// Values are only valid inside the transaction
+		doc = append([]byte(nil), meta.Get([]byte("genesis"))...)
+		genesisHash = append([]byte(nil), meta.Get([]byte("genesis_hash"))...)
+
+		return nil
+	})
+
+	if len(doc) == 0 {
+		doc = nil
+	}
+
+	if len(genesisHash) == 0 {
+		genesisHash = nil
+	}
+
+	return doc, genesisHash, err
+}
+
+//
+// ==============================
+// ROLLBACK
+// ==============================
+//
+
+// Rollback deletes every block above height together with its hash and
+// transaction index entries and the snapshots taken above it, and moves
+// the tip back to height, all in one transaction. Index entries are
+// found by the height they point at, so blocks that no longer decode
+// are removed cleanly too. It returns the number of blocks removed.
+func (db *DB) Rollback(height uint64) (int, error) {
+
+	removed := 0
+
+	err := db.conn.Update(func(tx *bbolt.Tx) error {
+
+		blocks := tx.Bucket(BlocksBucket)
+		hashIndex := tx.Bucket(HashIndexBucket)
+		txIndex := tx.Bucket(TxIndexBucket)
+		meta := tx.Bucket(MetaBucket)
+
+		var tip uint64
+		if v := meta.Get([]byte("latest_height")); v != nil {
+			tip = bytesToUint64(v)
+		}
+
+		if height > tip {
+			return fmt.Errorf("height %d is above the tip %d", height, tip)
+		}
+
+		if base := baseHeight(tx); height < base {
+			return fmt.Errorf("height %d is below %d, where the state-synced database starts", height, base)
+		}
+
+		var tipHash []byte
+
+		if height > 0 {
+
+			data := blocks.Get(uint64ToBytes(height))
+			if data == nil {
+				return fmt.Errorf("block %d is not stored", height)
+			}
+
+			var b block.Block
+			if err := json.Unmarshal(data, &b); err != nil {
+				return fmt.Errorf("block %d does not decode: %w", height, err)
+			}
+
+			tipHash = b.Hash
+		}
+
+		// Collect copies first: deleting while a cursor advances skips
+		// keys, and deletes may move the pages keys point into
+		var blockKeys [][]byte
+		c := blocks.Cursor()
+		for k, _ := c.Seek(uint64ToBytes(height + 1)); k != nil; k, _ = c.Next() {
+			blockKeys = append(blockKeys, append([]byte(nil), k...))
+		}
+
+		commits := tx.Bucket(CommitsBucket)
+
+		var commitKeys [][]byte
+		c = commits.Cursor()
+		for k, _ := c.Seek(uint64ToBytes(height + 1)); k != nil; k, _ = c.Next() {
+			commitKeys = append(commitKeys, append([]byte(nil), k...))
+		}
+
+		var hashKeys [][]byte
+		err := hashIndex.ForEach(func(k, v []byte) error {
+			if len(v) == 8 && bytesToUint64(v) > height {
+				hashKeys = append(hashKeys, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		var txKeys [][]byte
+		err = txIndex.ForEach(func(k, v []byte) error {
+
+			var entry struct {
+				Height uint64
+			}
+
+			if err := json.Unmarshal(v, &entry); err != nil || entry.Height > height {
+				txKeys = append(txKeys, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// In a database written before the index kept the first
+		// occurrence, a DataHash indexed at a removed height may also
+		// be in a kept block, which is then searched for it
+		var repoint map[string][]byte
+		if meta.Get(txIndexFirstKey) == nil {
+			if repoint, err = firstOccurrences(blocks, txKeys, height); err != nil {
+				return err
+			}
+		}
+
+		for _, del := range []struct {
+			bucket *bbolt.Bucket
+			keys   [][]byte
+		}{
+			{blocks, blockKeys},
+			{commits, commitKeys},
+			{hashIndex, hashKeys},
+			{txIndex, txKeys},
+		} {
+			for _, k := range del.keys {
+				if err := del.bucket.Delete(k); err != nil {
+					return err
+				}
+			}
+		}
+
+		for k, entry := range repoint {
+			if err := txIndex.Put([]byte(k), entry); err != nil {
+				return err
+			}
+		}
+
+		// Snapshots above the new tip describe removed blocks
+		var snapshots []uint64
+		c = tx.Bucket(SnapshotsBucket).Cursor()
+		for k, _ := c.Seek(uint64ToBytes(height + 1)); k != nil; k, _ = c.Next() {
+			snapshots = append(snapshots, bytesToUint64(k))
+		}
+
+		for _, h := range snapshots {
+			if err := deleteSnapshot(tx, h); err != nil {
+				return err
+			}
+		}
+
+		removed = len(blockKeys)
+
+		if height == 0 {
+			if err := meta.Delete([]byte("latest_height")); err != nil {
+				return err
+			}
+			return meta.Delete([]byte("latest_hash"))
+		}
+
+		if err := meta.Put([]byte("latest_height"), uint64ToBytes(height)); err != nil {
+			return err
+		}
+
+		return meta.Put([]byte("latest_hash"), tipHash)
+	})
+
+	return removed, err
+}
+
+//
+// ==============================
+// STATS
+// ==============================
+//
+
+// BucketStats describes one bucket: its keys, the bytes of its keys and
+// values, and the bytes of pages allocated to it.
+type BucketStats struct {
+	Name      string
+	Keys      int
+	Bytes     int64
+	Allocated int
+}
+
+// Stats summarizes the database.
+type Stats struct {
+	Height        uint64
+	Base          uint64
+	HashAlgorithm string
+	GenesisHash   []byte
+	HasGenesis    bool
+	Buckets       []BucketStats
+}
+
+// Stats reports the chain the database holds and the size of every
+// bucket.
+func (db *DB) Stats() (*Stats, error) {
+
+	s := &Stats{}
+
+	err := db.conn.View(func(tx *bbolt.Tx) error {
+
+		meta := tx.Bucket(MetaBucket)
+
+		if v := meta.Get([]byte("latest_height")); v != nil {
+			s.Height = bytesToUint64(v)
+		}
+
+		s.Base = baseHeight(tx)
+
+		s.HashAlgorithm = string(meta.Get([]byte("hash_algorithm")))
+		s.GenesisHash = append([]byte(nil), meta.Get([]byte("genesis_hash"))...)
+		s.HasGenesis = meta.Get([]byte("genesis")) != nil
+
+		return tx.ForEach(func(name []byte, b *bbolt.Bucket) error {
+
+			bs := BucketStats{Name: string(name)}
+
+			err := b.ForEach(func(k, v []byte) error {
+				bs.Keys++
+				bs.Bytes += int64(len(k) + len(v))
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			stats := b.Stats()
+			bs.Allocated = stats.BranchAlloc + stats.LeafAlloc
+
+			s.Buckets = append(s.Buckets, bs)
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// firstOccurrences finds, in the blocks up to height, the first
+// inclusion of each DataHash in keys, and returns its index entry.
+func firstOccurrences(blocks *bbolt.Bucket, keys [][]byte, height uint64) (map[string][]byte, error) {
+
+	found := make(map[string][]byte)
+
+	if len(keys) == 0 {
+		return found, nil
+	}
+
+	wanted := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		wanted[string(k)] = true
+	}
+
+	c := blocks.Cursor()
+	for k, v := c.First(); k != nil && bytesToUint64(k) <= height && len(wanted) > 0; k, v = c.Next() {
+
+		var b block.Block
+		if err := json.Unmarshal(v, &b); err != nil {
+			return nil, fmt.Errorf("block %d does not decode: %w", bytesToUint64(k), err)
+		}
+
+		for i, txObj := range b.Transactions {
+
+			if !wanted[txObj.DataHash] {
+				continue
+			}
+
+			entry, err := json.Marshal(struct {
+				Height uint64
+				Index  int
+			}{
+				Height: uint64(b.Index),
+				Index:  i,
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			found[txObj.DataHash] = entry
+			delete(wanted, txObj.DataHash)
+		}
+	}
+
+	return found, nil
+}
@@ -0,0 +1,229 @@
+package storage
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/block"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/chain"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/identity"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/transaction"
+	"go.etcd.io/bbolt"
+)
+
+// openTestChain stores count blocks of two transactions each.
+func openTestChain(t *testing.T, count int) (*DB, []*block.Block) {
+
+	db, err := Open(filepath.Join(t.TempDir(), "aegisq.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	p := chain.Default
+	p.GenesisHash = p.Hasher.Hash([]byte("genesis"))
+
+	if err := db.UseHasher(p.Hasher); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.UseGenesis(p.GenesisHash); err != nil {
+		t.Fatal(err)
+	}
+
+	node, err := identity.NewNodeIdentity("validator-1", &crypto.Ed25519Signer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prev := p.GenesisHash
+	var blocks []*block.Block
+
+	for h := 1; h <= count; h++ {
+
+		var txs []*transaction.Transaction
+		for i := 0; i < 2; i++ {
+			tx := transaction.NewTransaction(node, fmt.Sprintf("data-%d-%d", h, i), "")
+			if err := tx.SignWith(node, p); err != nil {
+				t.Fatal(err)
+			}
+			txs = append(txs, tx)
+		}
+
+		b := block.NewBlock(h, 0, prev, txs)
+		if err := b.FinalizeWith(node, p); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := db.SaveBlock(b); err != nil {
+			t.Fatal(err)
+		}
+
+		blocks = append(blocks, b)
+		prev = b.Hash
+	}
+
+	return db, blocks
+}
+
+func TestRollback(t *testing.T) {
+
+	db, blocks := openTestChain(t, 5)
+
+	if _, err := db.Rollback(6); err == nil {
+		t.Fatal("rollback above the tip should fail")
+	}
+
+	removed, err := db.Rollback(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if removed != 2 {
+		t.Fatalf("removed %d blocks, want 2", removed)
+	}
+
+	height, err := db.GetLatestHeight()
+	if err != nil || height != 3 {
+		t.Fatalf("tip is %d after rollback (%v)", height, err)
+	}
+
+	if _, err := db.GetBlock(4); err == nil {
+		t.Fatal("block 4 should be gone")
+	}
+
+	if _, _, err := db.GetTransactionByHash("data-4-0"); err == nil {
+		t.Fatal("transactions of removed blocks should be unindexed")
+	}
+
+	if _, _, err := db.GetTransactionByHash("data-3-1"); err != nil {
+		t.Fatal("transactions below the rollback height must stay indexed")
+	}
+
+	// The removed blocks can be stored again on the new tip
+	if err := db.SaveBlock(blocks[3]); err != nil {
+		t.Fatal("re-adding a rolled-back block failed:", err)
+	}
+
+	stats, err := db.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.Height != 4 || stats.HashAlgorithm != crypto.DefaultHashAlgorithm {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	for _, b := range stats.Buckets {
+		switch b.Name {
+		case string(BlocksBucket), string(HashIndexBucket):
+			if b.Keys != 4 {
+				t.Fatalf("%s has %d keys, want 4", b.Name, b.Keys)
+			}
+		case string(TxIndexBucket):
+			if b.Keys != 8 {
+				t.Fatalf("%s has %d keys, want 8", b.Name, b.Keys)
+			}
+		}
+	}
+
+	if _, err := db.Rollback(0); err != nil {
+		t.Fatal(err)
+	}
+
+	if height, _ := db.GetLatestHeight(); height != 0 {
+		t.Fatalf("tip is %d after rollback to genesis", height)
+	}
+}
+
+// A transaction included again in a later block stays indexed at its
+// first block, and a rollback past the later one leaves it findable.
+func TestRollbackKeepsRepeatedTransactions(t *testing.T) {
+
+	db, blocks := openTestChain(t, 3)
+
+	node, err := identity.NewNodeIdentity("validator-1", &crypto.Ed25519Signer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repeated := blocks[1].Transactions[0]
+
+	prev := blocks[2].Hash
+	for h := 4; h <= 5; h++ {
+
+		b := block.NewBlock(h, 0, prev, []*transaction.Transaction{repeated})
+		if err := b.FinalizeWith(node, chain.Default); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := db.SaveBlock(b); err != nil {
+			t.Fatal(err)
+		}
+
+		prev = b.Hash
+	}
+
+	if b, _, err := db.GetTransactionByHash(repeated.DataHash); err != nil || b.Index != 2 {
+		t.Fatalf("repeated transaction is not indexed at its first block (%v)", err)
+	}
+
+	// Point the entry at block 5, as databases written before the
+	// first occurrence kept its entry did
+	err = db.conn.Update(func(tx *bbolt.Tx) error {
+		if err := tx.Bucket(MetaBucket).Delete(txIndexFirstKey); err != nil {
+			return err
+		}
+		return tx.Bucket(TxIndexBucket).Put([]byte(repeated.DataHash), []byte(`{"Height":5,"Index":0}`))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.Rollback(4); err != nil {
+		t.Fatal(err)
+	}
+
+	b, index, err := db.GetTransactionByHash(repeated.DataHash)
+	if err != nil {
+		t.Fatal("repeated transaction lost its index entry:", err)
+	}
+
+	if b.Index != 2 || index != 0 {
+		t.Fatalf("repeated transaction indexed at block %d position %d, want block 2 position 0", b.Index, index)
+	}
+
+	if _, err := db.Rollback(2); err != nil {
+		t.Fatal(err)
+	}
+
+	if b, _, err := db.GetTransactionByHash(repeated.DataHash); err != nil || b.Index != 2 {
+		t.Fatalf("repeated transaction is not indexed at block 2 (%v)", err)
+	}
+}
+
+func TestGenesisDocument(t *testing.T) {
+
+	db, _ := openTestChain(t, 1)
+
+	doc, hash, err := db.GenesisDocument()
+	if err != nil || doc != nil || hash == nil {
+		t.Fatalf("unexpected genesis before saving: %q %x %v", doc, hash, err)
+	}
+
+	if err := db.SaveGenesisDocument([]byte(`{"chain_id":"a"}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first document is kept
+	if err := db.SaveGenesisDocument([]byte(`{"chain_id":"b"}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, _, err = db.GenesisDocument()
+	if err != nil || string(doc) != `{"chain_id":"a"}` {
+		t.Fatalf("stored genesis is %q (%v)", doc, err)
+	}
+}
@@ -0,0 +1,255 @@
+package storage
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/block"
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/state"
+	"go.etcd.io/bbolt"
+)
+
+// ErrSnapshotNotFound is returned for a snapshot or chunk not stored.
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+func chunkKey(height uint64, index int) []byte {
+	k := make([]byte, 12)
+	binary.BigEndian.PutUint64(k, height)
+	binary.BigEndian.PutUint32(k[8:], uint32(index))
+	return k
+}
+
+//
+// ==============================
+// SNAPSHOTS
+// ==============================
+//
+
+// SaveSnapshot stores the snapshot m and its chunks. A snapshot of the
+// same height is replaced.
+func (db *DB) SaveSnapshot(m *state.Manifest, chunks [][]byte) error {
+
+	return db.conn.Update(func(tx *bbolt.Tx) error {
+
+		if err := deleteSnapshot(tx, m.Height); err != nil {
+			return err
+		}
+
+		return putSnapshot(tx, m, chunks)
+	})
+}
+
+func putSnapshot(tx *bbolt.Tx, m *state.Manifest, chunks [][]byte) error {
+
+	if len(chunks) != len(m.Chunks) {
+		return fmt.Errorf("snapshot %d: %d chunks for %d hashes", m.Height, len(chunks), len(m.Chunks))
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Bucket(SnapshotsBucket).Put(uint64ToBytes(m.Height), data); err != nil {
+		return err
+	}
+
+	bucket := tx.Bucket(SnapshotChunksBucket)
+
+	for i, chunk := range chunks {
+		if err := bucket.Put(chunkKey(m.Height, i), chunk); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func deleteSnapshot(tx *bbolt.Tx, height uint64) error {
+
+	if err := tx.Bucket(SnapshotsBucket).Delete(uint64ToBytes(height)); err != nil {
+		return err
+	}
+
+	// Collect first: deleting while a cursor advances skips keys
+	var keys [][]byte
+
+	prefix := uint64ToBytes(height)
+	c := tx.Bucket(SnapshotChunksBucket).Cursor()
+
+	for k, _ := c.Seek(prefix); k != nil && string(k[:8]) == string(prefix); k, _ = c.Next() {
+		keys = append(keys, append([]byte(nil), k...))
+	}
+
+	for _, k := range keys {
+		if err := tx.Bucket(SnapshotChunksBucket).Delete(k); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Snapshots returns the manifests of the stored snapshots, lowest
+// height first.
+func (db *DB) Snapshots() ([]*state.Manifest, error) {
+
+	var list []*state.Manifest
+
+	err := db.conn.View(func(tx *bbolt.Tx) error {
+
+		return tx.Bucket(SnapshotsBucket).ForEach(func(k, v []byte) error {
+
+			var m state.Manifest
+			if err := json.Unmarshal(v, &m); err != nil {
+				return fmt.Errorf("snapshot %d: %w", bytesToUint64(k), err)
+			}
+
+			list = append(list, &m)
+			return nil
+		})
+	})
+
+	return list, err
+}
+
+// Snapshot returns the manifest of the snapshot at height.
+func (db *DB) Snapshot(height uint64) (*state.Manifest, error) {
+
+	var m state.Manifest
+
+	err := db.conn.View(func(tx *bbolt.Tx) error {
+
+		data := tx.Bucket(SnapshotsBucket).Get(uint64ToBytes(height))
+		if data == nil {
+			return ErrSnapshotNotFound
+		}
+
+		return json.Unmarshal(data, &m)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+// SnapshotChunk returns chunk index of the snapshot at height.
+func (db *DB) SnapshotChunk(height uint64, index int) ([]byte, error) {
+
+	var chunk []byte
+
+	err := db.conn.View(func(tx *bbolt.Tx) error {
+
+		data := tx.Bucket(SnapshotChunksBucket).Get(chunkKey(height, index))
+		if data == nil {
+			return ErrSnapshotNotFound
+		}
+
+		// Values are only valid inside the transaction
+		chunk = append([]byte(nil), data...)
+		return nil
+	})
+
+	return chunk, err
+}
+
+// PruneSnapshots keeps the keep most recent snapshots, and the one a
+// state-synced database starts from, and deletes the others. It
+// returns the number deleted.
+func (db *DB) PruneSnapshots(keep int) (int, error) {
+
+	deleted := 0
+
+	err := db.conn.Update(func(tx *bbolt.Tx) error {
+
+		base := baseHeight(tx)
+
+		var heights []uint64
+		err := tx.Bucket(SnapshotsBucket).ForEach(func(k, _ []byte) error {
+			heights = append(heights, bytesToUint64(k))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for i, h := range heights {
+
+			if i >= len(heights)-keep || h == base {
+				continue
+			}
+
+			if err := deleteSnapshot(tx, h); err != nil {
+				return err
+			}
+			deleted++
+		}
+
+		return nil
+	})
+
+	return deleted, err
+}
+
+//
+// ==============================
+// STATE SYNC
+// ==============================
+//
+
+// Base returns the height a state-synced database starts at: blocks
+// below it are not stored. It is 0 for a database holding the whole
+// chain.
+func (db *DB) Base() (uint64, error) {
+
+	var base uint64
+
+	err := db.conn.View(func(tx *bbolt.Tx) error {
+		base = baseHeight(tx)
+		return nil
+	})
+
+	return base, err
+}
+
+func baseHeight(tx *bbolt.Tx) uint64 {
+
+	if v := tx.Bucket(MetaBucket).Get([]byte("base_height")); v != nil {
+		return bytesToUint64(v)
+	}
+
+	return 0
+}
+
+// RestoreSnapshot starts an empty database from a verified snapshot:
+// it stores the snapshot and b, the block it was taken at, as the tip
+// and base of the chain.
+func (db *DB) RestoreSnapshot(m *state.Manifest, chunks [][]byte, b *block.Block) error {
+
+	if uint64(b.Index) != m.Height || string(b.Hash) != string(m.BlockHash) {
+		return fmt.Errorf("snapshot %d was not taken at block %d", m.Height, b.Index)
+	}
+
+	return db.conn.Update(func(tx *bbolt.Tx) error {
+
+		meta := tx.Bucket(MetaBucket)
+
+		if meta.Get([]byte("latest_height")) != nil {
+			return errors.New("state sync needs an empty database")
+		}
+
+		if err := putSnapshot(tx, m, chunks); err != nil {
+			return err
+		}
+
+		if err := db.putBlock(tx, b); err != nil {
+			return err
+		}
+
+		return meta.Put([]byte("base_height"), uint64ToBytes(m.Height))
+	})
+}
@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/state"
+)
+
+// testSnapshot returns a snapshot at height, taken at blockHash, with
+// the given chunks. Storage does not check chunk hashes.
+func testSnapshot(height uint64, blockHash []byte, chunks ...string) (*state.Manifest, [][]byte) {
+
+	m := &state.Manifest{Format: state.SnapshotFormat, Height: height, BlockHash: blockHash}
+	var data [][]byte
+
+	for _, c := range chunks {
+		data = append(data, []byte(c))
+		m.Chunks = append(m.Chunks, []byte(c))
+	}
+
+	return m, data
+}
+
+func TestSnapshotsSaveAndPrune(t *testing.T) {
+
+	db, _ := openTestChain(t, 1)
+
+	for _, h := range []uint64{10, 20, 30} {
+		m, chunks := testSnapshot(h, nil, "a", "b")
+		if err := db.SaveSnapshot(m, chunks); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	chunk, err := db.SnapshotChunk(20, 1)
+	if err != nil || string(chunk) != "b" {
+		t.Fatalf("chunk is %q (%v)", chunk, err)
+	}
+
+	deleted, err := db.PruneSnapshots(2)
+	if err != nil || deleted != 1 {
+		t.Fatalf("pruned %d snapshots (%v)", deleted, err)
+	}
+
+	list, err := db.Snapshots()
+	if err != nil || len(list) != 2 || list[0].Height != 20 || list[1].Height != 30 {
+		t.Fatalf("unexpected snapshots after pruning: %v", list)
+	}
+
+	if _, err := db.Snapshot(10); !errors.Is(err, ErrSnapshotNotFound) {
+		t.Fatal("pruned snapshot should be gone:", err)
+	}
+
+	if _, err := db.SnapshotChunk(10, 0); !errors.Is(err, ErrSnapshotNotFound) {
+		t.Fatal("chunks of a pruned snapshot should be gone:", err)
+	}
+
+	// Rolling back below a snapshot drops it
+	if _, err := db.Rollback(0); err != nil {
+		t.Fatal(err)
+	}
+
+	if list, _ := db.Snapshots(); len(list) != 0 {
+		t.Fatalf("%d snapshots above the tip survived a rollback", len(list))
+	}
+}
+
+func TestRestoreSnapshot(t *testing.T) {
+
+	_, blocks := openTestChain(t, 3)
+	tip := blocks[2]
+
+	db, err := Open(filepath.Join(t.TempDir(), "synced.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	m, chunks := testSnapshot(3, []byte("other"), "a")
+	if err := db.RestoreSnapshot(m, chunks, tip); err == nil {
+		t.Fatal("a snapshot of another block should be rejected")
+	}
+
+	m, chunks = testSnapshot(3, tip.Hash, "a")
+	if err := db.RestoreSnapshot(m, chunks, tip); err != nil {
+		t.Fatal(err)
+	}
+
+	height, _ := db.GetLatestHeight()
+	base, _ := db.Base()
+
+	if height != 3 || base != 3 {
+		t.Fatalf("restored database at height %d, base %d", height, base)
+	}
+
+	if err := db.RestoreSnapshot(m, chunks, tip); err == nil {
+		t.Fatal("restoring into a non-empty database should fail")
+	}
+
+	// The base snapshot is kept, and blocks below it are unreachable
+	m, chunks = testSnapshot(4, nil, "b")
+	if err := db.SaveSnapshot(m, chunks); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.PruneSnapshots(1); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.Snapshot(3); err != nil {
+		t.Fatal("the base snapshot should survive pruning:", err)
+	}
+
+	if _, err := db.Rollback(2); err == nil {
+		t.Fatal("rollback below the base should fail")
+	}
+}
@@ -3,20 +3,36 @@ package transaction
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/chain"
 	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
 	"github.com/Sai-shashank-2005/aegisq-protocol/core/identity"
 )
 
 type Transaction struct {
-	SenderID  string `json:"sender_id"`
-	PublicKey []byte `json:"public_key"`
-	Algorithm string `json:"algorithm"`
-	DataHash  string `json:"data_hash"`
-	Metadata  string `json:"metadata"`
-	Timestamp int64  `json:"timestamp"`
-	Signature []byte `json:"signature"`
+	SenderID  string       `json:"sender_id"`
+	PublicKey []byte       `json:"public_key"`
+	Algorithm string       `json:"algorithm"`
+	DataHash  string       `json:"data_hash"`
+	Metadata  string       `json:"metadata"`
+	Timestamp int64        `json:"timestamp"`
+	Rotation  *KeyRotation `json:"rotation,omitempty"`
+	Signature []byte       `json:"signature"`
+}
+
+// KeyRotation hands the sender's validator identity to NewPublicKey
+// from ActivationHeight on. The transaction Signature is made with the
+// current key; NewKeySignature proves possession of the new key over
+// the same payload digest. NewAlgorithm is set only when the new key
+// uses a different algorithm than the current one (see
+// chain.AlgorithmEpoch).
+type KeyRotation struct {
+	NewPublicKey     []byte `json:"new_public_key"`
+	NewAlgorithm     string `json:"new_algorithm,omitempty"`
+	ActivationHeight int    `json:"activation_height"`
+	NewKeySignature  []byte `json:"new_key_signature"`
 }
 
 func NewTransaction(node *identity.NodeIdentity, dataHash, metadata string) *Transaction {
@@ -30,18 +46,56 @@ func NewTransaction(node *identity.NodeIdentity, dataHash, metadata string) *Tra
 	}
 }
 
-func (tx *Transaction) computePayloadHash() ([]byte, error) {
+// NewKeyRotation builds a transaction by which node schedules its
+// validator key to change to newPublicKey, an algorithm key, at
+// activationHeight.
+func NewKeyRotation(node *identity.NodeIdentity, algorithm string, newPublicKey []byte, activationHeight int) *Transaction {
+	tx := NewTransaction(node, fmt.Sprintf("%x", crypto.Hash(newPublicKey)), "key-rotation")
+	tx.Rotation = &KeyRotation{
+		NewPublicKey:     newPublicKey,
+		ActivationHeight: activationHeight,
+	}
+	if algorithm != node.Algorithm() {
+		tx.Rotation.NewAlgorithm = algorithm
+	}
+	return tx
+}
+
+// IsKeyRotation reports whether tx is a key rotation.
+func (tx *Transaction) IsKeyRotation() bool {
+	return tx.Rotation != nil
+}
+
+type rotationPayload struct {
+	NewPublicKey     []byte `json:"new_public_key"`
+	NewAlgorithm     string `json:"new_algorithm,omitempty"`
+	ActivationHeight int    `json:"activation_height"`
+}
+
+func (tx *Transaction) computePayloadHash(h crypto.Hasher) ([]byte, error) {
 	if tx.SenderID == "" || tx.DataHash == "" {
 		return nil, errors.New("invalid transaction fields")
 	}
 
+	// Plain transactions omit the rotation field, so their digests are
+	// unchanged by its introduction.
+	var rotation *rotationPayload
+	if tx.Rotation != nil {
+		rotation = &rotationPayload{
+			NewPublicKey:     tx.Rotation.NewPublicKey,
+			NewAlgorithm:     tx.Rotation.NewAlgorithm,
+			ActivationHeight: tx.Rotation.ActivationHeight,
+		}
+	}
+
 	payload := struct {
-		SenderID  string `json:"sender_id"`
-		PublicKey []byte `json:"public_key"`
-		Algorithm string `json:"algorithm"`
-		DataHash  string `json:"data_hash"`
-		Metadata  string `json:"metadata"`
-		Timestamp int64  `json:"timestamp"`
+		SenderID  string           `json:"sender_id"`
+		PublicKey []byte           `json:"public_key"`
+		Algorithm string           `json:"algorithm"`
+		DataHash  string           `json:"data_hash"`
+		Metadata  string           `json:"metadata"`
+		Timestamp int64            `json:"timestamp"`
+		Rotation  *rotationPayload `json:"rotation,omitempty"`
 	}{
 		SenderID:  tx.SenderID,
 		PublicKey: tx.PublicKey,
@@ -49,6 +103,7 @@ func (tx *Transaction) computePayloadHash() ([]byte, error) {
 		DataHash:  tx.DataHash,
 		Metadata:  tx.Metadata,
 		Timestamp: tx.Timestamp,
+		Rotation:  rotation,
 	}
 
 	bytes, err := json.Marshal(payload)
@@ -56,20 +111,32 @@ func (tx *Transaction) computePayloadHash() ([]byte, error) {
 		return nil, err
 	}
 
-	return crypto.Hash(bytes), nil
+	return h.Hash(bytes), nil
 }
 
+// Hash returns the payload digest under the default chain hash.
 func (tx *Transaction) Hash() ([]byte, error) {
-	return tx.computePayloadHash()
+	return tx.HashWith(crypto.DefaultHasher)
+}
+
+// HashWith returns the payload digest under the chain hash h.
+func (tx *Transaction) HashWith(h crypto.Hasher) ([]byte, error) {
+	return tx.computePayloadHash(h)
 }
 
 func (tx *Transaction) SignWithIdentity(node *identity.NodeIdentity) error {
-	hash, err := tx.computePayloadHash()
+	return tx.SignWith(node, chain.Default)
+}
+
+// SignWith signs the payload digest under the chain's hash, in the
+// chain's TX signature domain.
+func (tx *Transaction) SignWith(node *identity.NodeIdentity, p chain.Params) error {
+	hash, err := tx.computePayloadHash(p.Hasher)
 	if err != nil {
 		return err
 	}
 
-	signature, err := node.Sign(hash)
+	signature, err := node.Sign(p.Domain(crypto.MessageTx), hash)
 	if err != nil {
 		return err
 	}
@@ -78,15 +145,109 @@ func (tx *Transaction) SignWithIdentity(node *identity.NodeIdentity) error {
 	return nil
 }
 
+// SignRotation signs a key rotation with the current key and with the
+// incoming key, which must belong to the same node.
+func (tx *Transaction) SignRotation(current, next *identity.NodeIdentity, p chain.Params) error {
+	if tx.Rotation == nil {
+		return errors.New("not a key rotation")
+	}
+
+	if next.NodeID != current.NodeID ||
+		next.Algorithm() != tx.NewKeyAlgorithm() ||
+		string(next.PublicKey) != string(tx.Rotation.NewPublicKey) {
+		return errors.New("incoming key does not match rotation")
+	}
+
+	if err := tx.SignWith(current, p); err != nil {
+		return err
+	}
+
+	hash, err := tx.computePayloadHash(p.Hasher)
+	if err != nil {
+		return err
+	}
+
+	signature, err := next.Sign(p.Domain(crypto.MessageKeyRotation), hash)
+	if err != nil {
+		return err
+	}
+
+	tx.Rotation.NewKeySignature = signature
+	return nil
+}
+
 func (tx *Transaction) Verify(signer crypto.Signer) (bool, error) {
+	return tx.VerifyWith(signer, chain.Default)
+}
+
+// VerifyWith checks the signature over the payload digest under the
+// chain's hash and TX signature domain.
+func (tx *Transaction) VerifyWith(signer crypto.Signer, p chain.Params) (bool, error) {
 	if tx.Algorithm != signer.Algorithm() {
 		return false, errors.New("algorithm mismatch")
 	}
 
-	hash, err := tx.computePayloadHash()
+	hash, err := tx.computePayloadHash(p.Hasher)
+	if err != nil {
+		return false, err
+	}
+
+	ctx, err := p.Domain(crypto.MessageTx).Context()
+	if err != nil {
+		return false, err
+	}
+
+	// A signature already checked (e.g. on pool admission) is not re-verified.
+	if !crypto.DefaultSignatureCache.Verify(signer, crypto.BatchItem{
+		PublicKey: tx.PublicKey,
+		Message:   hash,
+		Signature: tx.Signature,
+		Context:   ctx,
+	}) {
+		return false, nil
+	}
+
+	if tx.Rotation == nil {
+		return true, nil
+	}
+
+	verifier, err := crypto.Verifier(tx.NewKeyAlgorithm())
+	if err != nil {
+		return false, err
+	}
+
+	item, err := tx.RotationItem(p, hash)
 	if err != nil {
 		return false, err
 	}
 
-	return signer.Verify(tx.PublicKey, hash, tx.Signature), nil
-}
\ No newline at end of file
+	return crypto.DefaultSignatureCache.Verify(verifier, item), nil
+}
+
+// NewKeyAlgorithm returns the algorithm of a rotation's incoming key.
+func (tx *Transaction) NewKeyAlgorithm() string {
+	if tx.Rotation == nil || tx.Rotation.NewAlgorithm == "" {
+		return tx.Algorithm
+	}
+	return tx.Rotation.NewAlgorithm
+}
+
+// RotationItem returns the incoming key's signature over the payload
+// digest hash, for verification in the chain's KEYROT domain.
+func (tx *Transaction) RotationItem(p chain.Params, hash []byte) (crypto.BatchItem, error) {
+	if tx.Rotation == nil {
+		return crypto.BatchItem{}, errors.New("not a key rotation")
+	}
+
+	ctx, err := p.Domain(crypto.MessageKeyRotation).Context()
+	if err != nil {
+		return crypto.BatchItem{}, err
+	}
+
+	return crypto.BatchItem{
+		PublicKey: tx.Rotation.NewPublicKey,
+		Message:   hash,
+		Signature: tx.Rotation.NewKeySignature,
+		Context:   ctx,
+	}, nil
+}
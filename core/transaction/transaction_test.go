@@ -3,6 +3,7 @@ package transaction
 import (
 	"testing"
 
+	"github.com/Sai-shashank-2005/aegisq-protocol/core/chain"
 	"github.com/Sai-shashank-2005/aegisq-protocol/core/crypto"
 	"github.com/Sai-shashank-2005/aegisq-protocol/core/identity"
 )
@@ -33,4 +34,63 @@ func TestTransactionTamperFails(t *testing.T) {
 	if valid {
 		t.Fatal("Tampered transaction should fail")
 	}
-}
\ No newline at end of file
+}
+
+func TestKeyRotationSignVerify(t *testing.T) {
+	signer := &crypto.Ed25519Signer{}
+	current, _ := identity.NewNodeIdentity("validator-1", signer)
+	next, _ := identity.NewNodeIdentity("validator-1", signer)
+
+	tx := NewKeyRotation(current, next.Algorithm(), next.PublicKey, 10)
+	if err := tx.SignRotation(current, next, chain.Default); err != nil {
+		t.Fatal(err)
+	}
+
+	valid, err := tx.Verify(signer)
+	if err != nil || !valid {
+		t.Fatal("key rotation verification failed")
+	}
+
+	tx.Rotation.ActivationHeight = 11
+
+	valid, _ = tx.Verify(signer)
+	if valid {
+		t.Fatal("tampered activation height should fail")
+	}
+}
+
+func TestKeyRotationRequiresNewKeySignature(t *testing.T) {
+	signer := &crypto.Ed25519Signer{}
+	current, _ := identity.NewNodeIdentity("validator-1", signer)
+	next, _ := identity.NewNodeIdentity("validator-1", signer)
+
+	// Signed by the current key only
+	tx := NewKeyRotation(current, next.Algorithm(), next.PublicKey, 10)
+	tx.SignWithIdentity(current)
+
+	valid, _ := tx.Verify(signer)
+	if valid {
+		t.Fatal("rotation without the new key's signature should fail")
+	}
+
+	// A signature by the new key in the transaction domain is not consent
+	hash, _ := tx.Hash()
+	tx.Rotation.NewKeySignature, _ = next.Sign(chain.Default.Domain(crypto.MessageTx), hash)
+
+	valid, _ = tx.Verify(signer)
+	if valid {
+		t.Fatal("new key signature must be bound to the rotation domain")
+	}
+}
+
+func TestSignRotationRejectsMismatchedKey(t *testing.T) {
+	signer := &crypto.Ed25519Signer{}
+	current, _ := identity.NewNodeIdentity("validator-1", signer)
+	next, _ := identity.NewNodeIdentity("validator-1", signer)
+	other, _ := identity.NewNodeIdentity("validator-1", signer)
+
+	tx := NewKeyRotation(current, next.Algorithm(), next.PublicKey, 10)
+	if err := tx.SignRotation(current, other, chain.Default); err == nil {
+		t.Fatal("signing with a key other than the announced one should fail")
+	}
+}
@@ -3,8 +3,9 @@ module github.com/Sai-shashank-2005/aegisq-protocol
 go 1.24.13
 
 require (
+	github.com/cloudflare/circl v1.6.3
 	go.etcd.io/bbolt v1.4.3
 	golang.org/x/crypto v0.48.0
+	golang.org/x/sys v0.41.0
+	gopkg.in/yaml.v3 v3.0.1
 )
-
-require golang.org/x/sys v0.41.0 // indirect